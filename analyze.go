@@ -0,0 +1,129 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/disiqueira/gotree"
+
+	"github.com/d6o/Gorganizer/pkg/organizer"
+	"github.com/d6o/Gorganizer/pkg/store"
+)
+
+func runAnalyze(args []string) (err error) {
+	flags := flag.NewFlagSet("analyze", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: %s analyze [options] directory\n", os.Args[0])
+		fmt.Fprintln(flags.Output(), "Suggests rules from the folders the files of an organized directory are in.")
+		flags.PrintDefaults()
+	}
+	lang := flags.String("language", "en", "Specify language: en|tr|pt")
+	profile := flags.String("profile", "", "Rule profile to use instead of the default one")
+	minFiles := flags.Int("min-files", 2, "Fewest files with an extension to suggest a rule for it")
+	threshold := flags.Float64("threshold", 0.75, "Share of an extension's files one folder must hold for its rule not to be ambiguous")
+	accept := flags.Bool("accept", false, "Add the rules that are not ambiguous")
+	preview := flags.Bool("preview", false, "With -accept, only show what the rules would change")
+	asJSON := flags.Bool("json", false, "Print the suggestions as JSON")
+	var logs logFlags
+	logs.register(flags)
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if err := logs.setup(); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return errAnalyzeUsage
+	}
+
+	suggestions, err := organizer.Analyze(flags.Arg(0),
+		organizer.WithMinFiles(*minFiles),
+		organizer.WithAmbiguityThreshold(*threshold),
+	)
+	if err != nil {
+		return err
+	}
+
+	s, err := openStore(*lang, *profile)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := s.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}()
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(suggestions); err != nil {
+			return err
+		}
+	} else {
+		printSuggestions(suggestions, s)
+	}
+
+	if !*accept {
+		if !*asJSON && len(suggestions) > 0 {
+			say("Add the rules that are not ambiguous with -accept")
+		}
+		return nil
+	}
+
+	var rules []store.Rule
+	for _, sg := range suggestions {
+		// Extensions and folders that cannot be written as a rule are
+		// left out.
+		if sg.Ambiguous || strings.ContainsAny(sg.Extension, ":,") || store.CheckFolder(sg.Folder) != nil {
+			continue
+		}
+		rules = append(rules, store.Rule{Extension: sg.Extension, Folder: sg.Folder})
+	}
+	changes, err := s.SetRules(rules, *preview)
+	if err != nil {
+		return err
+	}
+	printChanges(changes, *preview)
+	return nil
+}
+
+// printSuggestions prints the suggested rules by folder, then the ambiguous
+// ones with the folders their files are spread across.
+func printSuggestions(suggestions []organizer.Suggestion, s *store.Store) {
+	tree := gotree.New(fmt.Sprintf("Suggested rules (%d)", len(suggestions)))
+
+	var ambiguous gotree.Tree
+	for _, sg := range suggestions {
+		label := fmt.Sprintf("%s → %s (%.0f%%, %d of %d files)", sg.Extension, sg.Folder, sg.Confidence*100, sg.Files, sg.Total)
+		switch current := s.Lookup(sg.Extension); {
+		case current == "":
+		case strings.EqualFold(current, sg.Folder):
+			label += " [current rule]"
+		default:
+			label += " [now " + current + "]"
+		}
+
+		if !sg.Ambiguous {
+			tree.Add(label)
+			continue
+		}
+		if ambiguous == nil {
+			ambiguous = gotree.New("Ambiguous")
+		}
+		branch := ambiguous.Add(label)
+		for _, c := range sg.Folders {
+			branch.Add(c.Name + ": " + countLabel(c.Files, c.Bytes))
+		}
+	}
+	if ambiguous != nil {
+		tree.AddTree(ambiguous)
+	}
+
+	fmt.Println(tree.Print())
+}
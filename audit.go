@@ -0,0 +1,133 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/d6o/Gorganizer/pkg/organizer"
+)
+
+// auditFlags are the flags of the commands that can write the audit log.
+type auditFlags struct {
+	enabled bool
+	file    string
+	hash    bool
+	maxSize int64
+}
+
+func (a *auditFlags) register(flags *flag.FlagSet) {
+	flags.BoolVar(&a.enabled, "audit", false, "Append every file operation to the audit log")
+	flags.StringVar(&a.file, "audit-file", defaultAuditFile(), "JSON lines file the audit log is written to")
+	flags.BoolVar(&a.hash, "audit-hash", false, "Record the SHA-256 of every file in the audit log")
+	flags.Int64Var(&a.maxSize, "audit-max-size", 10, "Size in MiB past which the audit log is rotated")
+}
+
+// open returns the audit log to write to, or nil when auditing is off.
+func (a *auditFlags) open() (*organizer.AuditLog, error) {
+	if !a.enabled {
+		return nil, nil
+	}
+	return organizer.OpenAuditLog(a.file,
+		organizer.WithAuditHashes(a.hash),
+		organizer.WithAuditMaxSize(a.maxSize<<20),
+	)
+}
+
+func defaultAuditFile() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ".gorganizer-audit.jsonl"
+	}
+	return filepath.Join(home, ".gorganizer-audit.jsonl")
+}
+
+func runLog(args []string) error {
+	flags := flag.NewFlagSet("log", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: %s log [options]\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	file := flags.String("file", defaultAuditFile(), "Audit log to read")
+	since := flags.String("since", "", "Only show operations from this date (2006-01-02) or time (RFC 3339) on")
+	until := flags.String("until", "", "Only show operations up to this date, included, or before this time")
+	path := flags.String("path", "", "Only show operations on this file or on files in this directory")
+	runID := flags.String("run", "", "Only show the operations of this run")
+	asJSON := flags.Bool("json", false, "Print the matching records as JSON lines")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	filter := organizer.AuditFilter{RunID: *runID}
+	var err error
+	if filter.Since, err = parseLogTime(*since, false); err != nil {
+		return err
+	}
+	if filter.Until, err = parseLogTime(*until, true); err != nil {
+		return err
+	}
+	if *path != "" {
+		if filter.Path, err = filepath.Abs(*path); err != nil {
+			return err
+		}
+	}
+
+	records, err := organizer.ReadAuditLog(*file, filter)
+	if err != nil {
+		if os.IsNotExist(err) {
+			fmt.Println("No audit log at", *file)
+			return nil
+		}
+		return err
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		for _, r := range records {
+			if err := enc.Encode(r); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for _, r := range records {
+		line := fmt.Sprintf("%s  %s  %s@%s  %-7s %s", r.Time.Local().Format(time.DateTime), r.RunID, r.User, r.Host, r.Op, r.Source)
+		if r.Destination != "" {
+			line += " → " + r.Destination
+		}
+		if r.Trashed != "" {
+			line += "  (trashed to " + r.Trashed + ")"
+		}
+		if r.Outcome != organizer.AuditOK {
+			line += "  [" + r.Outcome + ": " + r.Error + "]"
+		}
+		fmt.Println(line)
+	}
+	fmt.Printf("%d operations\n", len(records))
+	return nil
+}
+
+// parseLogTime parses a -since or -until value. A date stands for the start
+// of that day, or for the start of the next one when it ends a range, so that
+// the range includes it.
+func parseLogTime(value string, end bool) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.ParseInLocation(time.DateOnly, value, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: %q", errInvalidLogTime, value)
+	}
+	if end {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/disiqueira/gotree"
+
+	"github.com/d6o/Gorganizer/internal/daemon"
+)
+
+func runDaemon(args []string) error {
+	status := len(args) > 0 && args[0] == "status"
+	if status {
+		args = args[1:]
+	}
+
+	flags := flag.NewFlagSet("daemon", flag.ExitOnError)
+	jobsFile := flags.String("jobs", defaultJobsFile(), "Job file listing the directories to organize and their schedules")
+	var logs logFlags
+	logs.register(flags)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: gorganizer daemon [status] [options]")
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if err := logs.setup(); err != nil {
+		return err
+	}
+
+	jobs, err := daemon.LoadJobs(*jobsFile)
+	if err != nil {
+		return err
+	}
+
+	if status {
+		return printDaemonStatus(jobs)
+	}
+
+	d, err := daemon.New(jobs, daemon.WithLogger(logger))
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	say(fmt.Sprintf("Running %d jobs, logging to %s", len(jobs.Jobs), jobs.LogDir))
+	return d.Run(ctx)
+}
+
+func defaultJobsFile() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ".gorganizer-jobs.json"
+	}
+	return filepath.Join(home, ".gorganizer-jobs.json")
+}
+
+func printDaemonStatus(jobs *daemon.JobFile) error {
+	status, err := daemon.ReadStatus(jobs.StatusFile())
+	if err != nil {
+		if os.IsNotExist(err) {
+			fmt.Println("The daemon has not run yet")
+			return nil
+		}
+		return err
+	}
+
+	tree := gotree.New(fmt.Sprintf("Jobs (pid %d, updated %s)", status.PID, formatTime(status.Updated)))
+
+	for _, j := range status.Jobs {
+		job := tree.Add(j.Name + " (" + j.Schedule + ")")
+		if j.Running {
+			job.Add("running since " + formatTime(j.LastStart))
+		}
+		job.Add("next run: " + formatTime(j.Next))
+
+		switch {
+		case j.LastEnd.IsZero():
+			job.Add("last run: never")
+		case j.LastError != "":
+			job.Add("last run: " + formatTime(j.LastEnd) + ", failed: " + j.LastError)
+		default:
+			job.Add(fmt.Sprintf("last run: %s, %d files moved", formatTime(j.LastEnd), j.Moved))
+		}
+
+		job.Add(fmt.Sprintf("%d runs, %d skipped while still running", j.Runs, j.Skipped))
+	}
+
+	fmt.Println(tree.Print())
+	return nil
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "never"
+	}
+	return t.Local().Format(time.DateTime)
+}
@@ -0,0 +1,18 @@
+package main
+
+import "errors"
+
+var (
+	errPlanFileRequired = errors.New("apply requires exactly one plan file")
+	errStalePlan        = errors.New("plan is out of date, files changed since it was made")
+	errEmptyDirectory   = errors.New("directory must not be empty")
+	errUnknownPreset    = errors.New("unknown preset")
+	errProfileUsage     = errors.New("unknown profile command or wrong number of arguments")
+	errRulesUsage       = errors.New("unknown rules command or wrong number of arguments")
+	errAnalyzeUsage     = errors.New("analyze requires exactly one directory")
+	errFolderExists     = errors.New("folder already exists")
+	errInvalidLogTime   = errors.New("invalid date or time, use 2006-01-02 or RFC 3339")
+	errUnknownLogFormat = errors.New("unknown log format, use text or json")
+	errCancelled        = errors.New("cancelled")
+	errEditorRules      = errors.New("-tui needs directories that share their rules")
+)
@@ -4,5 +4,10 @@ go 1.25.6
 
 require (
 	github.com/disiqueira/gotree v1.0.0
+	github.com/ulikunitz/xz v0.5.15
+	golang.org/x/sys v0.47.0
+	golang.org/x/term v0.45.0
+	golang.org/x/text v0.40.0
 	gopkg.in/ini.v1 v1.67.1
+	gopkg.in/yaml.v3 v3.0.1
 )
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"flag"
+	"time"
+
+	"github.com/d6o/Gorganizer/pkg/organizer"
+)
+
+// hookFlags are the flags of the commands that run hooks.
+type hookFlags struct {
+	hooks   []organizer.Hook
+	timeout time.Duration
+}
+
+func (h *hookFlags) register(flags *flag.FlagSet) {
+	flags.Func("hook", "Run a shell command at an event: pre-run|pre-move|post-move|post-run=COMMAND. Repeat for several", func(value string) error {
+		hook, err := organizer.ParseHook(value)
+		if err != nil {
+			return err
+		}
+		h.hooks = append(h.hooks, hook)
+		return nil
+	})
+	flags.DurationVar(&h.timeout, "hook-timeout", organizer.DefaultHookTimeout, "How long a hook may run before it fails")
+}
+
+// list returns the hooks with the timeout applied.
+func (h *hookFlags) list() []organizer.Hook {
+	for i := range h.hooks {
+		h.hooks[i].Timeout = h.timeout
+	}
+	return h.hooks
+}
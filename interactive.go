@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/d6o/Gorganizer/internal/confirm"
+	"github.com/d6o/Gorganizer/internal/tui"
+	"github.com/d6o/Gorganizer/pkg/organizer"
+	"github.com/d6o/Gorganizer/pkg/store"
+)
+
+// interactiveFlag can be given bare (-interactive), meaning one question per
+// destination folder, or with a granularity (-interactive=file).
+type interactiveFlag struct {
+	enabled     bool
+	granularity confirm.Granularity
+}
+
+func (f *interactiveFlag) String() string {
+	if f == nil || !f.enabled {
+		return ""
+	}
+	if f.granularity == confirm.PerFile {
+		return "file"
+	}
+	return "folder"
+}
+
+func (f *interactiveFlag) Set(value string) error {
+	switch value {
+	case "false":
+		f.enabled = false
+		return nil
+	case "true":
+		value = "folder"
+	}
+
+	g, err := confirm.ParseGranularity(value)
+	if err != nil {
+		return err
+	}
+	f.enabled = true
+	f.granularity = g
+	return nil
+}
+
+func (f *interactiveFlag) IsBoolFlag() bool {
+	return true
+}
+
+// runInteractive plans the run, asks the user to approve each group of
+// operations on the terminal, and applies the approved ones.
+func runInteractive(org *organizer.Organizer, g confirm.Granularity) (*organizer.OrganizeResult, error) {
+	plan, err := org.Plan()
+	if err != nil {
+		return nil, err
+	}
+
+	reviewed, err := confirm.NewPrompter(os.Stdin, os.Stdout).Review(plan, g)
+	if err != nil {
+		return nil, err
+	}
+
+	say(fmt.Sprintf("Applying %d of %d operations", len(reviewed.Entries), len(plan.Entries)))
+	return org.Apply(reviewed)
+}
+
+// runEditor opens the full-screen editor on the preview of the run, and
+// applies the edited plan if the user chooses to. It returns errCancelled
+// when the user quits without applying. Rules added in the editor go to
+// rules, the store that classifies the run's inputs.
+func runEditor(org *organizer.Organizer, rules *store.Store) (result *organizer.OrganizeResult, err error) {
+	preview, err := org.Preview()
+	if err != nil {
+		return nil, err
+	}
+
+	console, err := tui.OpenConsole(os.Stdin, os.Stdout)
+	if err != nil {
+		return nil, err
+	}
+
+	actions, apply, err := tui.NewEditor(console, rules, preview).Run()
+	if cerr := console.Close(); cerr != nil && err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if !apply {
+		return nil, errCancelled
+	}
+
+	plan, err := org.PlanFor(actions)
+	if err != nil {
+		return nil, err
+	}
+	return org.Apply(plan)
+}
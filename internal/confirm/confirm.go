@@ -0,0 +1,199 @@
+// Package confirm asks the user to approve the operations of a plan before
+// they are applied, either one destination folder or one file at a time.
+package confirm
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/d6o/Gorganizer/pkg/organizer"
+)
+
+// Granularity selects how planned operations are grouped into questions.
+type Granularity int
+
+const (
+	// PerFolder asks once for every destination folder.
+	PerFolder Granularity = iota
+	// PerFile asks once for every file.
+	PerFile
+)
+
+// ParseGranularity converts "folder" or "file" to a Granularity. Returns
+// ErrUnknownGranularity for any other value.
+func ParseGranularity(name string) (Granularity, error) {
+	switch name {
+	case "folder":
+		return PerFolder, nil
+	case "file":
+		return PerFile, nil
+	}
+	return PerFolder, ErrUnknownGranularity
+}
+
+// Prompter asks questions on an output stream and reads answers from an
+// input stream, one per line.
+type Prompter struct {
+	in  *bufio.Reader
+	out io.Writer
+}
+
+// NewPrompter creates a Prompter reading answers from in and writing
+// questions to out.
+func NewPrompter(in io.Reader, out io.Writer) *Prompter {
+	return &Prompter{
+		in:  bufio.NewReader(in),
+		out: out,
+	}
+}
+
+type answer int
+
+const (
+	answerYes answer = iota
+	answerNo
+	answerAll
+	answerSkipFolder
+	answerQuit
+)
+
+type group struct {
+	label   string
+	entries []int
+}
+
+// Review asks about every entry of plan and returns a copy containing only
+// the accepted entries, in their original order. Answering "all" accepts
+// everything not asked about yet; "quit", or the end of the input, rejects
+// it while keeping the answers already given.
+func (p *Prompter) Review(plan *organizer.Plan, g Granularity) (*organizer.Plan, error) {
+	accepted := make([]bool, len(plan.Entries))
+	groups := groupEntries(plan)
+
+review:
+	for gi, grp := range groups {
+		if g == PerFolder {
+			fmt.Fprintf(p.out, "%s (%d files)\n", grp.label, len(grp.entries))
+			for _, i := range grp.entries {
+				fmt.Fprintf(p.out, "  %s\n", filepath.Base(plan.Entries[i].Source))
+			}
+
+			a, err := p.ask("Apply? [y]es, [n]o, [a]ll, [q]uit: ")
+			if err != nil {
+				return nil, err
+			}
+
+			switch a {
+			case answerYes:
+				accept(accepted, grp.entries)
+			case answerAll:
+				acceptRest(accepted, groups[gi:])
+				break review
+			case answerQuit:
+				break review
+			}
+			continue
+		}
+
+		for fi, i := range grp.entries {
+			fmt.Fprintf(p.out, "%s: %s\n", grp.label, filepath.Base(plan.Entries[i].Source))
+
+			a, err := p.ask("Apply? [y]es, [n]o, [a]ll, [s]kip folder, [q]uit: ")
+			if err != nil {
+				return nil, err
+			}
+
+			switch a {
+			case answerYes:
+				accepted[i] = true
+			case answerAll:
+				accept(accepted, grp.entries[fi:])
+				acceptRest(accepted, groups[gi+1:])
+				break review
+			case answerSkipFolder:
+				continue review
+			case answerQuit:
+				break review
+			}
+		}
+	}
+
+	reviewed := *plan
+	reviewed.Entries = nil
+	for i, e := range plan.Entries {
+		if accepted[i] {
+			reviewed.Entries = append(reviewed.Entries, e)
+		}
+	}
+	return &reviewed, nil
+}
+
+// ask prints question until the answer is understood. The end of the input
+// counts as quitting.
+func (p *Prompter) ask(question string) (answer, error) {
+	for {
+		fmt.Fprint(p.out, question)
+
+		line, err := p.in.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return answerQuit, err
+		}
+
+		switch strings.ToLower(strings.TrimSpace(line)) {
+		case "y", "yes":
+			return answerYes, nil
+		case "n", "no":
+			return answerNo, nil
+		case "a", "all":
+			return answerAll, nil
+		case "s", "skip":
+			return answerSkipFolder, nil
+		case "q", "quit":
+			return answerQuit, nil
+		}
+
+		if err == io.EOF {
+			fmt.Fprintln(p.out)
+			return answerQuit, nil
+		}
+	}
+}
+
+// groupEntries groups plan entries by operation and destination folder, in
+// the order each group first appears.
+func groupEntries(plan *organizer.Plan) []group {
+	var groups []group
+	index := make(map[string]int)
+
+	for i, e := range plan.Entries {
+		label := string(e.Op)
+		if e.Target != "" {
+			label += " to " + filepath.Dir(e.Target)
+		}
+
+		gi, ok := index[label]
+		if !ok {
+			gi = len(groups)
+			index[label] = gi
+			groups = append(groups, group{label: label})
+		}
+		groups[gi].entries = append(groups[gi].entries, i)
+	}
+
+	return groups
+}
+
+func accept(accepted []bool, entries []int) {
+	for _, i := range entries {
+		accepted[i] = true
+	}
+}
+
+func acceptRest(accepted []bool, groups []group) {
+	for _, g := range groups {
+		accept(accepted, g.entries)
+	}
+}
@@ -0,0 +1,97 @@
+package confirm_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/d6o/Gorganizer/internal/confirm"
+	"github.com/d6o/Gorganizer/pkg/organizer"
+)
+
+func testPlan() *organizer.Plan {
+	return &organizer.Plan{
+		Version: organizer.PlanVersion,
+		Entries: []organizer.PlanEntry{
+			{Op: organizer.OpMove, Source: "/in/a.mp3", Target: "/out/Music/a.mp3"},
+			{Op: organizer.OpMove, Source: "/in/a.pdf", Target: "/out/Documents/a.pdf"},
+			{Op: organizer.OpMove, Source: "/in/b.mp3", Target: "/out/Music/b.mp3"},
+			{Op: organizer.OpDelete, Source: "/in/c.pdf"},
+		},
+	}
+}
+
+func sources(p *organizer.Plan) string {
+	var names []string
+	for _, e := range p.Entries {
+		names = append(names, e.Source)
+	}
+	return strings.Join(names, ",")
+}
+
+func TestParseGranularity(t *testing.T) {
+	t.Parallel()
+
+	if g, err := confirm.ParseGranularity("folder"); err != nil || g != confirm.PerFolder {
+		t.Errorf("ParseGranularity(folder) = %d, %v", g, err)
+	}
+	if g, err := confirm.ParseGranularity("file"); err != nil || g != confirm.PerFile {
+		t.Errorf("ParseGranularity(file) = %d, %v", g, err)
+	}
+	if _, err := confirm.ParseGranularity("hunk"); err != confirm.ErrUnknownGranularity {
+		t.Errorf("ParseGranularity(hunk) error = %v, want ErrUnknownGranularity", err)
+	}
+}
+
+func TestReview(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		granularity confirm.Granularity
+		input       string
+		want        string
+	}{
+		{"folder yes and no", confirm.PerFolder, "y\nn\ny\n", "/in/a.mp3,/in/b.mp3,/in/c.pdf"},
+		{"folder all", confirm.PerFolder, "n\na\n", "/in/a.pdf,/in/c.pdf"},
+		{"folder quit", confirm.PerFolder, "y\nq\n", "/in/a.mp3,/in/b.mp3"},
+		{"folder end of input", confirm.PerFolder, "y\n", "/in/a.mp3,/in/b.mp3"},
+		{"folder retries unknown answers", confirm.PerFolder, "maybe\ny\nn\nn\n", "/in/a.mp3,/in/b.mp3"},
+		{"file yes and no", confirm.PerFile, "n\ny\ny\nn\n", "/in/a.pdf,/in/b.mp3"},
+		{"file skip folder", confirm.PerFile, "s\ny\ny\n", "/in/a.pdf,/in/c.pdf"},
+		{"file all", confirm.PerFile, "n\na\n", "/in/a.pdf,/in/b.mp3,/in/c.pdf"},
+		{"file quit", confirm.PerFile, "y\nq\n", "/in/a.mp3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			var out bytes.Buffer
+			p := confirm.NewPrompter(strings.NewReader(tt.input), &out)
+
+			got, err := p.Review(testPlan(), tt.granularity)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if sources(got) != tt.want {
+				t.Errorf("Review() kept %q, want %q", sources(got), tt.want)
+			}
+		})
+	}
+}
+
+func TestReview_FolderPromptListsFiles(t *testing.T) {
+	t.Parallel()
+	var out bytes.Buffer
+	p := confirm.NewPrompter(strings.NewReader("q\n"), &out)
+
+	if _, err := p.Review(testPlan(), confirm.PerFolder); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{"move to /out/Music (2 files)", "  a.mp3", "  b.mp3"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("prompt output missing %q:\n%s", want, out.String())
+		}
+	}
+}
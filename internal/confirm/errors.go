@@ -0,0 +1,7 @@
+package confirm
+
+import "errors"
+
+// ErrUnknownGranularity is returned when a granularity name is not "folder"
+// or "file".
+var ErrUnknownGranularity = errors.New("interactive mode must be folder or file")
@@ -0,0 +1,371 @@
+// Package daemon runs organize jobs on a schedule. Each job has its own
+// input and output directories, rules and options, logs to its own file and
+// never runs twice at the same time. The daemon reports the state of every
+// job in a status file that a separate process can read.
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/d6o/Gorganizer/internal/report"
+	"github.com/d6o/Gorganizer/pkg/organizer"
+	"github.com/d6o/Gorganizer/pkg/store"
+)
+
+// Runner executes one run of a job, calling progress for every file handled.
+type Runner func(job Job, progress func(organizer.FileAction)) (*organizer.OrganizeResult, error)
+
+// Option configures a Daemon during construction.
+type Option func(*Daemon)
+
+// WithClock sets the clock the daemon schedules and timestamps with.
+func WithClock(c Clock) Option {
+	return func(d *Daemon) {
+		d.clock = c
+	}
+}
+
+// WithRunner replaces the function that runs a job, which by default
+// organizes the job's input directory.
+func WithRunner(r Runner) Option {
+	return func(d *Daemon) {
+		d.runner = r
+	}
+}
+
+// WithLogger sets the logger the default Runner passes to the organizer and
+// the rules store of every job, with the job's name attached. By default
+// nothing is logged there; the job logs are written either way.
+func WithLogger(l *slog.Logger) Option {
+	return func(d *Daemon) {
+		if l != nil {
+			d.logger = l
+		}
+	}
+}
+
+// Daemon schedules the jobs of a job file.
+type Daemon struct {
+	clock      Clock
+	runner     Runner
+	logger     *slog.Logger
+	statusFile string
+
+	mu   sync.Mutex
+	jobs []*entry
+	wg   sync.WaitGroup
+
+	// saveMu serializes writes of the status file.
+	saveMu sync.Mutex
+}
+
+type entry struct {
+	job      Job
+	schedule Schedule
+	status   JobStatus
+}
+
+// New creates a Daemon for the jobs of f. Each job is first due at the next
+// time its schedule fires.
+func New(f *JobFile, opts ...Option) (*Daemon, error) {
+	d := &Daemon{
+		clock:      SystemClock,
+		logger:     slog.New(slog.DiscardHandler),
+		statusFile: f.StatusFile(),
+	}
+	d.runner = d.organize
+	for _, opt := range opts {
+		opt(d)
+	}
+
+	if err := os.MkdirAll(f.LogDir, 0o755); err != nil {
+		return nil, err
+	}
+
+	now := d.clock.Now()
+	for _, j := range f.Jobs {
+		s, err := ParseSchedule(j.Schedule)
+		if err != nil {
+			return nil, fmt.Errorf("job %q: %w", j.Name, err)
+		}
+		d.jobs = append(d.jobs, &entry{
+			job:      j,
+			schedule: s,
+			status: JobStatus{
+				Name:     j.Name,
+				Schedule: j.Schedule,
+				Next:     s.Next(now),
+			},
+		})
+	}
+
+	return d, nil
+}
+
+// Run schedules jobs until ctx is cancelled, then waits for running jobs to
+// finish.
+func (d *Daemon) Run(ctx context.Context) error {
+	d.saveStatus()
+	defer d.wg.Wait()
+
+	for {
+		var wait <-chan time.Time
+		if next := d.next(); !next.IsZero() {
+			wait = d.clock.After(next.Sub(d.clock.Now()))
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-wait:
+			d.Tick(d.clock.Now())
+		}
+	}
+}
+
+// next returns the earliest time a job is due, or the zero time if no job
+// will run again.
+func (d *Daemon) next() time.Time {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var next time.Time
+	for _, e := range d.jobs {
+		if n := e.status.Next; !n.IsZero() && (next.IsZero() || n.Before(next)) {
+			next = n
+		}
+	}
+	return next
+}
+
+// Tick starts every job due at now. A job whose previous run is still in
+// progress is skipped until its next scheduled time.
+func (d *Daemon) Tick(now time.Time) {
+	d.mu.Lock()
+	for _, e := range d.jobs {
+		if e.status.Next.IsZero() || e.status.Next.After(now) {
+			continue
+		}
+		e.status.Next = e.schedule.Next(now)
+
+		if e.status.Running {
+			e.status.Skipped++
+			d.logf(e.job, now, "skipped: previous run still in progress")
+			continue
+		}
+
+		e.status.Running = true
+		e.status.LastStart = now
+		d.wg.Add(1)
+		go d.execute(e)
+	}
+	d.mu.Unlock()
+
+	d.saveStatus()
+}
+
+// Wait blocks until every running job has finished.
+func (d *Daemon) Wait() {
+	d.wg.Wait()
+}
+
+func (d *Daemon) execute(e *entry) {
+	defer d.wg.Done()
+
+	j := e.job
+	d.logf(j, d.clock.Now(), "start: organizing %s into %s", j.Input, j.Output)
+
+	result, err := d.runner(j, func(a organizer.FileAction) {
+		if line := describe(a); line != "" {
+			d.logf(j, d.clock.Now(), "%s", line)
+		}
+	})
+
+	moved := 0
+	if result != nil {
+		for _, a := range result.Actions {
+			if a.Moved || a.Linked || a.Removed {
+				moved++
+			}
+		}
+	}
+
+	end := d.clock.Now()
+	if err != nil {
+		d.logf(j, end, "failed: %v", err)
+	} else {
+		d.logf(j, end, "done: %d files moved", moved)
+	}
+
+	d.mu.Lock()
+	e.status.Running = false
+	e.status.LastEnd = end
+	e.status.Runs++
+	e.status.Moved = moved
+	e.status.LastError = ""
+	if err != nil {
+		e.status.LastError = err.Error()
+	}
+	d.mu.Unlock()
+
+	d.saveStatus()
+}
+
+// describe renders the log line for a handled file, or "" for files that
+// were left alone.
+func describe(a organizer.FileAction) string {
+	switch {
+	case a.Moved:
+		return "moved " + a.Path + " to " + a.Destination
+	case a.Linked:
+		return "linked " + a.Path + " to " + a.DuplicateOf
+	case a.Archive != nil && a.Archive.ExtractedTo != "":
+		return "extracted " + a.Path + " to " + a.Archive.ExtractedTo
+	case a.Removed:
+		return "removed " + a.Path + " (duplicate of " + a.DuplicateOf + ")"
+	case a.Reason == organizer.ReasonUnknownExtension:
+		return "unknown extension: " + a.Path
+	}
+	return ""
+}
+
+// logf appends a line to the job's log. The file is reopened for every line
+// so that it can be rotated while the daemon runs.
+func (d *Daemon) logf(j Job, t time.Time, format string, args ...any) {
+	line := t.Format(time.RFC3339) + " " + fmt.Sprintf(format, args...)
+	if !strings.HasSuffix(line, "\n") {
+		line += "\n"
+	}
+
+	if err := os.MkdirAll(filepath.Dir(j.Log), 0o755); err != nil {
+		return
+	}
+	f, err := os.OpenFile(j.Log, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return
+	}
+	_, _ = f.WriteString(line)
+	_ = f.Close()
+}
+
+// Status returns the current state of every job, in job file order.
+func (d *Daemon) Status() []JobStatus {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	jobs := make([]JobStatus, len(d.jobs))
+	for i, e := range d.jobs {
+		jobs[i] = e.status
+	}
+	return jobs
+}
+
+func (d *Daemon) saveStatus() {
+	d.saveMu.Lock()
+	defer d.saveMu.Unlock()
+
+	status := Status{
+		Updated: d.clock.Now(),
+		PID:     os.Getpid(),
+		Jobs:    d.Status(),
+	}
+	data, err := json.MarshalIndent(status, "", "  ")
+	if err != nil {
+		return
+	}
+
+	tmp := d.statusFile + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return
+	}
+	_ = os.Rename(tmp, d.statusFile)
+}
+
+// organize is the default Runner.
+func (d *Daemon) organize(j Job, progress func(organizer.FileAction)) (result *organizer.OrganizeResult, err error) {
+	log := d.logger.With("job", j.Name)
+	// Jobs only read the rules, so the store is saved once when it is created
+	// with the defaults and never rewritten on later runs.
+	created := false
+	opts := []store.Option{store.WithProfile(j.Profile), store.WithLogger(log), store.WithEventHandler(func(evt store.Event) {
+		if evt == store.EventDefaultsInitialized {
+			created = true
+		}
+	})}
+	if j.Rules != "" {
+		opts = append(opts, store.WithConfigFile(j.Rules))
+	}
+	s, err := store.NewStore(j.Language, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if created {
+		if err := s.Save(); err != nil {
+			return nil, err
+		}
+	}
+
+	policy, err := organizer.ParseDuplicatePolicy(j.Duplicates)
+	if err != nil {
+		return nil, err
+	}
+	archives, err := organizer.ParseArchivePolicy(j.Archives)
+	if err != nil {
+		return nil, err
+	}
+	hooks, err := j.hooks()
+	if err != nil {
+		return nil, err
+	}
+	hidden := true
+	if j.Hidden != nil {
+		hidden = *j.Hidden
+	}
+
+	var audit *organizer.AuditLog
+	if j.Audit != "" {
+		if audit, err = organizer.OpenAuditLog(j.Audit, organizer.WithAuditHashes(j.AuditHash)); err != nil {
+			return nil, err
+		}
+		defer func() {
+			if cerr := audit.Close(); err == nil {
+				err = cerr
+			}
+		}()
+	}
+
+	org := organizer.NewOrganizer(s, organizer.Config{
+		InputFolder:       j.Input,
+		OutputFolder:      j.Output,
+		Recursive:         j.Recursive,
+		IgnoreHiddenFiles: hidden,
+		ExcludeList:       organizer.ExcludeList(j.Exclude),
+		Duplicates:        policy,
+		Archives:          archives,
+		Permanent:         j.Permanent,
+		Audit:             audit,
+		Hooks:             hooks,
+		Logger:            log,
+		Progress:          progress,
+	})
+	result, err = org.Run()
+	if j.Report != "" && result != nil {
+		r := report.Report{Result: result, Rules: s.Rules(), Generated: time.Now()}
+		if err != nil {
+			r.Error = err.Error()
+		}
+		if rerr := report.WriteFile(j.Report, r); rerr != nil {
+			return result, errors.Join(err, rerr)
+		}
+	}
+	return result, err
+}
@@ -0,0 +1,448 @@
+package daemon_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/d6o/Gorganizer/internal/daemon"
+	"github.com/d6o/Gorganizer/internal/testutil"
+	"github.com/d6o/Gorganizer/pkg/organizer"
+	"github.com/d6o/Gorganizer/pkg/store"
+)
+
+// fakeClock only moves when the test advances it.
+type fakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []waiter
+	waiting chan struct{}
+}
+
+type waiter struct {
+	at time.Time
+	ch chan time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{
+		now:     time.Date(2026, time.March, 2, 10, 0, 0, 0, time.UTC),
+		waiting: make(chan struct{}, 1),
+	}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ch := make(chan time.Time, 1)
+	c.waiters = append(c.waiters, waiter{at: c.now.Add(d), ch: ch})
+	select {
+	case c.waiting <- struct{}{}:
+	default:
+	}
+	return ch
+}
+
+// Advance moves the clock forward, firing every timer that expires.
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+	pending := c.waiters[:0]
+	for _, w := range c.waiters {
+		if w.at.After(c.now) {
+			pending = append(pending, w)
+			continue
+		}
+		w.ch <- c.now
+	}
+	c.waiters = pending
+}
+
+func writeJobFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "jobs.json")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func loadJobs(t *testing.T, content string) *daemon.JobFile {
+	t.Helper()
+	f, err := daemon.LoadJobs(writeJobFile(t, content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+// countingRunner records the jobs it runs, reported sorted since jobs due at
+// the same time start concurrently, and blocks each run until release
+// is closed.
+type countingRunner struct {
+	mu      sync.Mutex
+	runs    []string
+	release chan struct{}
+}
+
+func (r *countingRunner) run(j daemon.Job, progress func(organizer.FileAction)) (*organizer.OrganizeResult, error) {
+	r.mu.Lock()
+	r.runs = append(r.runs, j.Name)
+	r.mu.Unlock()
+
+	<-r.release
+	progress(organizer.FileAction{FileName: "a.mp3", Path: "in/a.mp3", Destination: "Music", Moved: true})
+	return &organizer.OrganizeResult{Actions: []organizer.FileAction{{Moved: true}}}, nil
+}
+
+func (r *countingRunner) names() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	names := slices.Clone(r.runs)
+	slices.Sort(names)
+	return strings.Join(names, ",")
+}
+
+const twoJobs = `{"jobs": [
+	{"name": "often", "input": "in", "schedule": "*/5 * * * *"},
+	{"name": "hourly", "input": "in", "schedule": "@every 1h"}
+]}`
+
+func TestLoadJobs(t *testing.T) {
+	t.Parallel()
+	path := writeJobFile(t, `{"jobs": [
+		{"name": "downloads", "input": "in", "output": "/srv/out", "schedule": "@daily", "rules": "rules.ini"}
+	]}`)
+	dir := filepath.Dir(path)
+
+	f, err := daemon.LoadJobs(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	j := f.Jobs[0]
+	if j.Input != filepath.Join(dir, "in") {
+		t.Errorf("Input = %q, want it relative to the job file", j.Input)
+	}
+	if j.Output != "/srv/out" {
+		t.Errorf("Output = %q", j.Output)
+	}
+	if j.Rules != filepath.Join(dir, "rules.ini") {
+		t.Errorf("Rules = %q", j.Rules)
+	}
+	if j.Log != filepath.Join(dir, "logs", "downloads.log") {
+		t.Errorf("Log = %q, want default in the log directory", j.Log)
+	}
+	if f.StatusFile() != filepath.Join(dir, "logs", "status.json") {
+		t.Errorf("StatusFile = %q", f.StatusFile())
+	}
+}
+
+func TestLoadJobs_Invalid(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		content string
+		want    error
+	}{
+		{"no jobs", `{"jobs": []}`, daemon.ErrNoJobs},
+		{"no name", `{"jobs": [{"input": "in", "schedule": "1h"}]}`, daemon.ErrJobNameRequired},
+		{"no input", `{"jobs": [{"name": "a", "schedule": "1h"}]}`, daemon.ErrInputRequired},
+		{"bad schedule", `{"jobs": [{"name": "a", "input": "in", "schedule": "often"}]}`, daemon.ErrInvalidSchedule},
+		{"bad duplicates", `{"jobs": [{"name": "a", "input": "in", "schedule": "1h", "duplicates": "x"}]}`, organizer.ErrUnknownDuplicatePolicy},
+		{"bad hook", `{"jobs": [{"name": "a", "input": "in", "schedule": "1h", "hooks": ["later=true"]}]}`, organizer.ErrUnknownHookEvent},
+		{"same name", `{"jobs": [
+			{"name": "a", "input": "in", "schedule": "1h"},
+			{"name": "a", "input": "in2", "schedule": "1h"}
+		]}`, daemon.ErrDuplicateJob},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if _, err := daemon.LoadJobs(writeJobFile(t, tt.content)); !errors.Is(err, tt.want) {
+				t.Errorf("error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestDaemon_TickRunsDueJobs(t *testing.T) {
+	t.Parallel()
+	clock := newFakeClock()
+	runner := &countingRunner{release: make(chan struct{})}
+	close(runner.release)
+
+	d, err := daemon.New(loadJobs(t, twoJobs), daemon.WithClock(clock), daemon.WithRunner(runner.run))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d.Tick(clock.Now().Add(4 * time.Minute))
+	d.Wait()
+	if got := runner.names(); got != "" {
+		t.Errorf("runs before first due time = %q, want none", got)
+	}
+
+	d.Tick(clock.Now().Add(5 * time.Minute))
+	d.Wait()
+	d.Tick(clock.Now().Add(time.Hour))
+	d.Wait()
+	if got := runner.names(); got != "hourly,often,often" {
+		t.Errorf("runs = %q", got)
+	}
+
+	status := d.Status()
+	if status[0].Runs != 2 || status[0].Moved != 1 || status[0].Running {
+		t.Errorf("often status = %+v", status[0])
+	}
+	if want := clock.Now().Add(65 * time.Minute); !status[0].Next.Equal(want) {
+		t.Errorf("often next = %v, want %v", status[0].Next, want)
+	}
+}
+
+func TestDaemon_OverlapProtection(t *testing.T) {
+	t.Parallel()
+	clock := newFakeClock()
+	runner := &countingRunner{release: make(chan struct{})}
+	f := loadJobs(t, twoJobs)
+
+	d, err := daemon.New(f, daemon.WithClock(clock), daemon.WithRunner(runner.run))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d.Tick(clock.Now().Add(5 * time.Minute))
+	d.Tick(clock.Now().Add(10 * time.Minute))
+	d.Tick(clock.Now().Add(15 * time.Minute))
+
+	status := d.Status()
+	if !status[0].Running || status[0].Skipped != 2 {
+		t.Errorf("status while running = %+v, want running with 2 skipped", status[0])
+	}
+
+	close(runner.release)
+	d.Wait()
+
+	if got := runner.names(); got != "often" {
+		t.Errorf("runs = %q, want a single run", got)
+	}
+
+	log, err := os.ReadFile(f.Jobs[0].Log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"2026-03-02T10:10:00Z skipped: previous run still in progress",
+		"start: organizing",
+		"moved in/a.mp3 to Music",
+		"done: 1 files moved",
+	} {
+		if !strings.Contains(string(log), want) {
+			t.Errorf("log missing %q:\n%s", want, log)
+		}
+	}
+
+	saved, err := daemon.ReadStatus(f.StatusFile())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.Jobs[0].Runs != 1 || saved.Jobs[0].Skipped != 2 || saved.Jobs[0].Running {
+		t.Errorf("saved status = %+v", saved.Jobs[0])
+	}
+}
+
+func TestDaemon_RecordsFailures(t *testing.T) {
+	t.Parallel()
+	clock := newFakeClock()
+	f := loadJobs(t, twoJobs)
+
+	d, err := daemon.New(f, daemon.WithClock(clock), daemon.WithRunner(
+		func(daemon.Job, func(organizer.FileAction)) (*organizer.OrganizeResult, error) {
+			return nil, errors.New("disk on fire")
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d.Tick(clock.Now().Add(5 * time.Minute))
+	d.Wait()
+
+	if got := d.Status()[0].LastError; got != "disk on fire" {
+		t.Errorf("LastError = %q", got)
+	}
+	log, err := os.ReadFile(f.Jobs[0].Log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(log), "failed: disk on fire") {
+		t.Errorf("log = %s", log)
+	}
+}
+
+func TestDaemon_Run(t *testing.T) {
+	t.Parallel()
+	clock := newFakeClock()
+	runner := &countingRunner{release: make(chan struct{})}
+	close(runner.release)
+
+	d, err := daemon.New(loadJobs(t, twoJobs), daemon.WithClock(clock), daemon.WithRunner(runner.run))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- d.Run(ctx)
+	}()
+
+	// Each wait for a timer is one turn of the scheduling loop.
+	for i := 0; i < 3; i++ {
+		<-clock.waiting
+		clock.Advance(5 * time.Minute)
+	}
+	<-clock.waiting
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	// A run may still be finishing when the next one is due, in which case
+	// that one is skipped.
+	if status := d.Status()[0]; status.Runs+status.Skipped != 3 || status.Runs < 1 {
+		t.Errorf("status = %+v, want three scheduled runs", status)
+	}
+}
+
+func TestDaemon_Organize(t *testing.T) {
+	t.Parallel()
+	clock := newFakeClock()
+	f := loadJobs(t, `{"jobs": [{"name": "a", "input": "in", "output": "out", "schedule": "1m", "rules": "rules.ini", "audit": "audit.jsonl", "report": "report.html"}]}`)
+	j := f.Jobs[0]
+
+	if err := os.MkdirAll(j.Input, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(j.Input, "song.mp3"), nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var logged bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logged, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	d, err := daemon.New(f, daemon.WithClock(clock), daemon.WithLogger(logger))
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.Tick(clock.Now().Add(time.Minute))
+	d.Wait()
+
+	if status := d.Status()[0]; status.LastError != "" || status.Moved != 1 {
+		t.Fatalf("status = %+v", status)
+	}
+	if _, err := os.Stat(filepath.Join(j.Output, "Music", "song.mp3")); err != nil {
+		t.Error("song.mp3 should be organized into the output directory")
+	}
+	if _, err := os.Stat(j.Rules); err != nil {
+		t.Error("the job's rules file should be created with the defaults")
+	}
+	records, err := organizer.ReadAuditLog(j.Audit, organizer.AuditFilter{})
+	if err != nil || len(records) != 1 || records[0].Op != organizer.OpMove {
+		t.Errorf("audit records = %+v, %v, want the move", records, err)
+	}
+	if page, err := os.ReadFile(j.Report); err != nil || !strings.Contains(string(page), "song.mp3") {
+		t.Errorf("report = %q, %v, want the run's files", page, err)
+	}
+	if !strings.Contains(logged.String(), "job=a") {
+		t.Errorf("log = %q, want the organizer's lines tagged with the job", logged.String())
+	}
+}
+
+func TestDaemon_OrganizeKeepsRules(t *testing.T) {
+	t.Parallel()
+	clock := newFakeClock()
+	f := loadJobs(t, `{"jobs": [{"name": "a", "input": "in", "schedule": "1m", "rules": "rules.ini"}]}`)
+	j := f.Jobs[0]
+	testutil.WriteFile(t, j.Input, "song.mp3", "")
+
+	s, err := store.NewStore("en", store.WithConfigFile(j.Rules))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	modified := time.Now().Add(-time.Hour).Truncate(time.Second)
+	if err := os.Chtimes(j.Rules, modified, modified); err != nil {
+		t.Fatal(err)
+	}
+	before, err := os.ReadFile(j.Rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d, err := daemon.New(f, daemon.WithClock(clock))
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.Tick(clock.Now().Add(time.Minute))
+	d.Wait()
+
+	if status := d.Status()[0]; status.LastError != "" || status.Moved != 1 {
+		t.Fatalf("status = %+v", status)
+	}
+	after, err := os.ReadFile(j.Rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(after, before) {
+		t.Errorf("rules file = %q, want it unchanged", after)
+	}
+	info, err := os.Stat(j.Rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.ModTime().Equal(modified) {
+		t.Errorf("rules file modified at %v, want %v: a job should not rewrite it", info.ModTime(), modified)
+	}
+}
+
+func TestDaemon_Hooks(t *testing.T) {
+	t.Parallel()
+	clock := newFakeClock()
+	f := loadJobs(t, `{"jobs": [{"name": "a", "input": "in", "schedule": "1m", "rules": "rules.ini", "hooks": ["pre-move=exit 1"], "hook_timeout": "10s"}]}`)
+	j := f.Jobs[0]
+	testutil.WriteFile(t, j.Input, "song.mp3", "")
+
+	d, err := daemon.New(f, daemon.WithClock(clock))
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.Tick(clock.Now().Add(time.Minute))
+	d.Wait()
+
+	if status := d.Status()[0]; status.LastError != "" || status.Moved != 0 {
+		t.Fatalf("status = %+v, want the move vetoed", status)
+	}
+	if _, err := os.Stat(filepath.Join(j.Input, "song.mp3")); err != nil {
+		t.Error("the pre-move hook should keep song.mp3 in place")
+	}
+}
@@ -0,0 +1,19 @@
+package daemon
+
+import "errors"
+
+// ErrInvalidSchedule is returned when a job schedule is neither a cron
+// expression nor an interval.
+var ErrInvalidSchedule = errors.New("invalid schedule")
+
+// ErrJobNameRequired is returned when a job in the job file has no name.
+var ErrJobNameRequired = errors.New("job name is required")
+
+// ErrDuplicateJob is returned when two jobs in the job file share a name.
+var ErrDuplicateJob = errors.New("duplicate job name")
+
+// ErrInputRequired is returned when a job has no input directory.
+var ErrInputRequired = errors.New("job input directory is required")
+
+// ErrNoJobs is returned when the job file does not define any job.
+var ErrNoJobs = errors.New("job file defines no jobs")
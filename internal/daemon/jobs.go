@@ -0,0 +1,204 @@
+package daemon
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/d6o/Gorganizer/internal/fsutil"
+	"github.com/d6o/Gorganizer/pkg/organizer"
+)
+
+// JobFile is the configuration of the daemon, usually read from JSON:
+//
+//	{
+//	  "log_dir": "~/.gorganizer/logs",
+//	  "jobs": [
+//	    {
+//	      "name": "downloads",
+//	      "input": "~/Downloads",
+//	      "output": "~/Sorted",
+//	      "schedule": "*/30 * * * *",
+//	      "duplicates": "skip",
+//	      "audit": "/srv/share/.gorganizer-audit.jsonl",
+//	      "report": "~/Sorted/report.html",
+//	      "hooks": ["post-run=notify-send 'Downloads sorted'"]
+//	    },
+//	    {"name": "desktop", "input": "~/Desktop", "schedule": "@every 1h", "language": "pt"}
+//	  ]
+//	}
+type JobFile struct {
+	// LogDir holds the per-job logs and the status file. It defaults to a
+	// "logs" directory next to the job file.
+	LogDir string `json:"log_dir"`
+	Jobs   []Job  `json:"jobs"`
+}
+
+// Job is one scheduled organize run.
+type Job struct {
+	Name string `json:"name"`
+	// Input is the directory to organize and Output the directory to put
+	// the organized folders in. Output defaults to Input.
+	Input  string `json:"input"`
+	Output string `json:"output"`
+	// Schedule is a cron expression or an interval, see ParseSchedule.
+	Schedule string `json:"schedule"`
+	// Language selects the default rules, Rules an explicit rules file to
+	// use instead of the one in the user's home directory, and Profile a
+	// rule profile within it.
+	Language string `json:"language"`
+	Rules    string `json:"rules"`
+	Profile  string `json:"profile"`
+	// Log is the file the job logs to. It defaults to <name>.log in the
+	// log directory.
+	Log string `json:"log"`
+	// Audit is the audit log every file operation of the job is appended
+	// to, none by default. AuditHash also records the files' SHA-256.
+	Audit     string `json:"audit"`
+	AuditHash bool   `json:"audit_hash"`
+	// Report is an HTML report of the job's last run, rewritten after
+	// every run.
+	Report string `json:"report"`
+	// Hooks run at the events of the job's runs, each written as
+	// EVENT=COMMAND like the -hook flag. HookTimeout is how long a hook may
+	// run, such as "10s", organizer.DefaultHookTimeout by default.
+	Hooks       []string `json:"hooks"`
+	HookTimeout string   `json:"hook_timeout"`
+
+	Recursive  bool     `json:"recursive"`
+	Hidden     *bool    `json:"hidden"`
+	Exclude    []string `json:"exclude"`
+	Duplicates string   `json:"duplicates"`
+	Archives   string   `json:"archives"`
+	Permanent  bool     `json:"permanent"`
+}
+
+// LoadJobs reads and validates a job file. Relative and "~" paths in the
+// file are resolved against the job file's directory and the user's home
+// directory.
+func LoadJobs(path string) (*JobFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var f JobFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	base, err := filepath.Abs(filepath.Dir(path))
+	if err != nil {
+		return nil, err
+	}
+	if f.LogDir == "" {
+		f.LogDir = "logs"
+	}
+	if f.LogDir, err = resolve(base, f.LogDir); err != nil {
+		return nil, err
+	}
+
+	if err := f.validate(base); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &f, nil
+}
+
+// StatusFile returns the path of the file the daemon reports its status in.
+func (f *JobFile) StatusFile() string {
+	return filepath.Join(f.LogDir, "status.json")
+}
+
+func (f *JobFile) validate(base string) error {
+	if len(f.Jobs) == 0 {
+		return ErrNoJobs
+	}
+
+	seen := make(map[string]bool)
+	for i := range f.Jobs {
+		j := &f.Jobs[i]
+		if j.Name == "" {
+			return fmt.Errorf("job %d: %w", i+1, ErrJobNameRequired)
+		}
+		if seen[j.Name] {
+			return fmt.Errorf("%w: %q", ErrDuplicateJob, j.Name)
+		}
+		seen[j.Name] = true
+
+		if err := j.resolve(base, f.LogDir); err != nil {
+			return fmt.Errorf("job %q: %w", j.Name, err)
+		}
+	}
+	return nil
+}
+
+// resolve checks the job and fills in its defaults.
+func (j *Job) resolve(base, logDir string) error {
+	if j.Input == "" {
+		return ErrInputRequired
+	}
+	if _, err := ParseSchedule(j.Schedule); err != nil {
+		return err
+	}
+	if _, err := organizer.ParseDuplicatePolicy(j.Duplicates); err != nil {
+		return err
+	}
+	if _, err := organizer.ParseArchivePolicy(j.Archives); err != nil {
+		return err
+	}
+	if _, err := j.hooks(); err != nil {
+		return err
+	}
+
+	if j.Output == "" {
+		j.Output = j.Input
+	}
+	if j.Log == "" {
+		j.Log = filepath.Join(logDir, j.Name+".log")
+	}
+
+	for _, p := range []*string{&j.Input, &j.Output, &j.Rules, &j.Log, &j.Audit, &j.Report} {
+		if *p == "" {
+			continue
+		}
+		var err error
+		if *p, err = resolve(base, *p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// hooks parses the job's hooks.
+func (j *Job) hooks() ([]organizer.Hook, error) {
+	var timeout time.Duration
+	if j.HookTimeout != "" {
+		var err error
+		if timeout, err = time.ParseDuration(j.HookTimeout); err != nil {
+			return nil, fmt.Errorf("hook timeout: %w", err)
+		}
+	}
+
+	hooks := make([]organizer.Hook, len(j.Hooks))
+	for i, spec := range j.Hooks {
+		h, err := organizer.ParseHook(spec)
+		if err != nil {
+			return nil, err
+		}
+		h.Timeout = timeout
+		hooks[i] = h
+	}
+	return hooks, nil
+}
+
+func resolve(base, path string) (string, error) {
+	if fsutil.HomeRelative(path) {
+		return fsutil.ExpandHome(path)
+	}
+	if filepath.IsAbs(path) {
+		return filepath.Clean(path), nil
+	}
+	return filepath.Join(base, path), nil
+}
@@ -0,0 +1,45 @@
+package daemon
+
+import (
+	"encoding/json"
+	"os"
+	"time"
+)
+
+// Status is the content of the status file written by a running daemon.
+type Status struct {
+	Updated time.Time   `json:"updated"`
+	PID     int         `json:"pid"`
+	Jobs    []JobStatus `json:"jobs"`
+}
+
+// JobStatus is the state of one job.
+type JobStatus struct {
+	Name     string    `json:"name"`
+	Schedule string    `json:"schedule"`
+	Running  bool      `json:"running"`
+	Next     time.Time `json:"next,omitzero"`
+	// LastStart, LastEnd, LastError and Moved describe the last run.
+	LastStart time.Time `json:"last_start,omitzero"`
+	LastEnd   time.Time `json:"last_end,omitzero"`
+	LastError string    `json:"last_error,omitempty"`
+	Moved     int       `json:"moved"`
+	// Runs counts finished runs and Skipped the runs that did not start
+	// because the previous one was still in progress.
+	Runs    int `json:"runs"`
+	Skipped int `json:"skipped"`
+}
+
+// ReadStatus reads the status file written by a daemon.
+func ReadStatus(path string) (*Status, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var s Status
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
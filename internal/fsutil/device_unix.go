@@ -0,0 +1,14 @@
+//go:build !windows
+
+package fsutil
+
+import (
+	"errors"
+	"syscall"
+)
+
+// CrossDevice reports whether a rename or a hard link failed because its
+// paths are on different file systems.
+func CrossDevice(err error) bool {
+	return errors.Is(err, syscall.EXDEV)
+}
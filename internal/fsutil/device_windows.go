@@ -0,0 +1,18 @@
+//go:build windows
+
+package fsutil
+
+import (
+	"errors"
+	"syscall"
+)
+
+// errorNotSameDevice is ERROR_NOT_SAME_DEVICE, returned when a file is
+// renamed to another volume.
+const errorNotSameDevice = syscall.Errno(17)
+
+// CrossDevice reports whether a rename or a hard link failed because its
+// paths are on different volumes.
+func CrossDevice(err error) bool {
+	return errors.Is(err, errorNotSameDevice)
+}
@@ -0,0 +1,128 @@
+// Package fsutil moves files and directories across file systems, where
+// os.Rename cannot, and expands ~ in paths.
+package fsutil
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Move renames src to dst. When they are on different file systems, it
+// copies src next to dst, keeping modes and modification times, syncs the
+// copy, renames it to dst and removes src. A failed copy leaves src and dst
+// as they were.
+func Move(src, dst string) error {
+	err := os.Rename(src, dst)
+	if err == nil || !CrossDevice(err) {
+		return err
+	}
+
+	tmp, err := os.MkdirTemp(filepath.Dir(dst), "."+filepath.Base(dst)+".*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = os.RemoveAll(tmp)
+	}()
+
+	copied := filepath.Join(tmp, filepath.Base(dst))
+	if err := Copy(src, copied); err != nil {
+		return err
+	}
+	if err := os.Rename(copied, dst); err != nil {
+		return err
+	}
+	return os.RemoveAll(src)
+}
+
+// Copy copies the file, symbolic link or directory tree at src to dst,
+// which must not exist, keeping modes and modification times. Files are
+// synced to disk before Copy returns.
+func Copy(src, dst string) error {
+	info, err := os.Lstat(src)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case info.Mode()&fs.ModeSymlink != 0:
+		target, err := os.Readlink(src)
+		if err != nil {
+			return err
+		}
+		return os.Symlink(target, dst)
+
+	case info.IsDir():
+		if err := os.Mkdir(dst, info.Mode().Perm()|0o700); err != nil {
+			return err
+		}
+		entries, err := os.ReadDir(src)
+		if err != nil {
+			return err
+		}
+		for _, e := range entries {
+			if err := Copy(filepath.Join(src, e.Name()), filepath.Join(dst, e.Name())); err != nil {
+				return err
+			}
+		}
+		if err := os.Chmod(dst, info.Mode().Perm()); err != nil {
+			return err
+		}
+
+	default:
+		if err := copyFile(src, dst, info.Mode().Perm()); err != nil {
+			return err
+		}
+	}
+	return os.Chtimes(dst, info.ModTime(), info.ModTime())
+}
+
+func copyFile(src, dst string, perm fs.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = in.Close()
+	}()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		return errors.Join(err, out.Close())
+	}
+	if err := out.Sync(); err != nil {
+		return errors.Join(err, out.Close())
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	// The umask may have narrowed the mode at creation.
+	return os.Chmod(dst, perm)
+}
+
+// HomeRelative reports whether path is ~ or starts with ~ and a separator,
+// such as ~/Documents. Paths such as ~user/Documents are not.
+func HomeRelative(path string) bool {
+	rest, ok := strings.CutPrefix(path, "~")
+	return ok && (rest == "" || os.IsPathSeparator(rest[0]))
+}
+
+// ExpandHome replaces the ~ of a home relative path with the user's home
+// directory. Other paths are returned as they are.
+func ExpandHome(path string) (string, error) {
+	if !HomeRelative(path) {
+		return path, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, path[1:]), nil
+}
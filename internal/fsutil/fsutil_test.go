@@ -0,0 +1,99 @@
+package fsutil_test
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/d6o/Gorganizer/internal/fsutil"
+)
+
+var modTime = time.Date(2026, 10, 1, 9, 31, 0, 0, time.UTC)
+
+func writeFile(t *testing.T, path, content string, perm os.FileMode) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), perm); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(path, perm); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func checkFile(t *testing.T, path, content string, perm os.FileMode) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != content {
+		t.Errorf("%s = %q, want %q", path, data, content)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm() != perm {
+		t.Errorf("%s mode = %v, want %v", path, info.Mode().Perm(), perm)
+	}
+	if !info.ModTime().Equal(modTime) {
+		t.Errorf("%s modified %v, want %v", path, info.ModTime(), modTime)
+	}
+}
+
+func TestCopy(t *testing.T) {
+	t.Parallel()
+	src := t.TempDir()
+	dst := filepath.Join(t.TempDir(), "copy")
+
+	if err := os.Mkdir(filepath.Join(src, "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, filepath.Join(src, "run.sh"), "#!/bin/sh\n", 0o750)
+	writeFile(t, filepath.Join(src, "sub", "notes.txt"), "notes", 0o640)
+
+	if err := fsutil.Copy(src, dst); err != nil {
+		t.Fatal(err)
+	}
+	checkFile(t, filepath.Join(dst, "run.sh"), "#!/bin/sh\n", 0o750)
+	checkFile(t, filepath.Join(dst, "sub", "notes.txt"), "notes", 0o640)
+	if _, err := os.Stat(filepath.Join(src, "run.sh")); err != nil {
+		t.Errorf("Copy removed the source: %v", err)
+	}
+
+	if err := fsutil.Copy(filepath.Join(src, "run.sh"), filepath.Join(dst, "run.sh")); !os.IsExist(err) {
+		t.Errorf("Copy() over an existing file = %v, want exist", err)
+	}
+}
+
+func TestExpandHome(t *testing.T) {
+	t.Parallel()
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Skip(err)
+	}
+
+	tests := []struct {
+		path, want string
+		relative   bool
+	}{
+		{"~", home, true},
+		{"~/Documents/Scans", filepath.Join(home, "Documents", "Scans"), true},
+		{"~bob/Documents", "~bob/Documents", false},
+		{"Documents/~", "Documents/~", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		if got := fsutil.HomeRelative(tt.path); got != tt.relative {
+			t.Errorf("HomeRelative(%q) = %v, want %v", tt.path, got, tt.relative)
+		}
+		if got, err := fsutil.ExpandHome(tt.path); err != nil || got != tt.want {
+			t.Errorf("ExpandHome(%q) = %q, %v, want %q", tt.path, got, err, tt.want)
+		}
+	}
+}
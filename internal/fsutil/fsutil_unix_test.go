@@ -0,0 +1,72 @@
+//go:build !windows
+
+package fsutil_test
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/d6o/Gorganizer/internal/fsutil"
+)
+
+func device(t *testing.T, path string) uint64 {
+	t.Helper()
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return uint64(info.Sys().(*syscall.Stat_t).Dev) //nolint:unconvert // Dev is not uint64 on every platform
+}
+
+// otherDevice returns a directory on another file system than dir, or
+// skips the test when there is none.
+func otherDevice(t *testing.T, dir string) string {
+	t.Helper()
+	other, err := os.MkdirTemp("/dev/shm", "fsutil")
+	if err != nil {
+		t.Skip("no tmpfs to move files to:", err)
+	}
+	t.Cleanup(func() {
+		_ = os.RemoveAll(other)
+	})
+	if device(t, other) == device(t, dir) {
+		t.Skip("/dev/shm is on the same file system")
+	}
+	return other
+}
+
+func TestMove_AcrossFileSystems(t *testing.T) {
+	t.Parallel()
+	src := t.TempDir()
+	dst := otherDevice(t, src)
+
+	file := filepath.Join(src, "movie.mkv")
+	writeFile(t, file, "frames", 0o640)
+	if err := fsutil.Move(file, filepath.Join(dst, "movie.mkv")); err != nil {
+		t.Fatal(err)
+	}
+	checkFile(t, filepath.Join(dst, "movie.mkv"), "frames", 0o640)
+	if _, err := os.Stat(file); !os.IsNotExist(err) {
+		t.Error("source file should be gone")
+	}
+
+	dir := filepath.Join(src, "album")
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, filepath.Join(dir, "01.mp3"), "song", 0o600)
+	if err := fsutil.Move(dir, filepath.Join(dst, "album")); err != nil {
+		t.Fatal(err)
+	}
+	checkFile(t, filepath.Join(dst, "album", "01.mp3"), "song", 0o600)
+
+	entries, err := os.ReadDir(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Errorf("%s holds %d entries, want no leftovers", dst, len(entries))
+	}
+}
@@ -0,0 +1,191 @@
+// Package report renders the result of an organize run as a self-contained
+// HTML page, and groups its files the way the command line prints them.
+package report
+
+import (
+	_ "embed"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"time"
+
+	"github.com/d6o/Gorganizer/pkg/organizer"
+	"github.com/d6o/Gorganizer/pkg/store"
+)
+
+// Report is what a report page shows.
+type Report struct {
+	Result *organizer.OrganizeResult
+	// Rules are the rules the run used.
+	Rules     []store.Rule
+	Preview   bool
+	Generated time.Time
+	// Error is why the run stopped, when it failed. Its result then holds
+	// the files handled before the failure and the one it failed on.
+	Error string
+}
+
+// Group is the files of a run shown under one label: their destination
+// folder, or why they are not organized.
+type Group struct {
+	Label   string
+	Actions []organizer.FileAction
+}
+
+// Bytes returns the total size of the group's files.
+func (g Group) Bytes() int64 {
+	var n int64
+	for _, a := range g.Actions {
+		n += a.Size
+	}
+	return n
+}
+
+// Groups groups actions by Label, in the order the labels first appear.
+func Groups(actions []organizer.FileAction) []Group {
+	var groups []Group
+	index := make(map[string]int)
+	for _, a := range actions {
+		label := Label(a)
+		i, ok := index[label]
+		if !ok {
+			i = len(groups)
+			index[label] = i
+			groups = append(groups, Group{Label: label})
+		}
+		groups[i].Actions = append(groups[i].Actions, a)
+	}
+	return groups
+}
+
+// Label returns the label of the group a file is shown in.
+func Label(a organizer.FileAction) string {
+	switch a.Reason {
+	case organizer.ReasonHidden:
+		return "Hidden Files"
+	case organizer.ReasonExcluded:
+		return "Excluded Files"
+	case organizer.ReasonUnknownExtension:
+		return "Unknown extension (will not be moved)"
+	case organizer.ReasonDuplicate:
+		return "Duplicate Files"
+	case organizer.ReasonStale:
+		return "Changed since planned (skipped)"
+	case organizer.ReasonVetoed:
+		return "Vetoed by a hook (skipped)"
+	}
+	return a.Destination
+}
+
+// Name returns how a file is shown: with its new name when it is renamed,
+// or with the number of files extracted from it.
+func Name(a organizer.FileAction) string {
+	switch {
+	case a.Archive != nil && a.Archive.ExtractedTo != "":
+		return fmt.Sprintf("%s (extracted %d files)", a.FileName, len(a.Archive.Extracted))
+	case a.NewName != "":
+		return a.FileName + " → " + a.NewName
+	}
+	return a.FileName
+}
+
+// FormatBytes formats a size with a decimal unit, as in 38.2 GB.
+func FormatBytes(n int64) string {
+	const unit = 1000
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "kMGTPE"[exp])
+}
+
+//go:embed report.html.tmpl
+var pageTemplate string
+
+var page = template.Must(template.New("report").Funcs(template.FuncMap{
+	"bytes": FormatBytes,
+	"name":  Name,
+	"time": func(t time.Time) string {
+		return t.Format(time.DateTime)
+	},
+	"duration": func(d time.Duration) time.Duration {
+		return d.Round(time.Millisecond)
+	},
+}).Parse(pageTemplate))
+
+type root struct {
+	Name   string
+	Groups []Group
+}
+
+type folderRules struct {
+	Folder     string
+	Extensions []string
+}
+
+type pageData struct {
+	Report
+	Summary *organizer.Summary
+	Roots   []root
+	Skipped []organizer.FileAction
+	Failed  []organizer.FileAction
+	Folders []folderRules
+}
+
+// Write renders r as an HTML page to w.
+func Write(w io.Writer, r Report) error {
+	data := pageData{Report: r, Summary: r.Result.Summary}
+	if data.Summary == nil {
+		data.Summary = r.Result.Summarize()
+	}
+
+	byRoot := r.Result.ByRoot()
+	if len(byRoot) <= 1 {
+		data.Roots = []root{{Groups: Groups(r.Result.Actions)}}
+	} else {
+		for _, g := range byRoot {
+			data.Roots = append(data.Roots, root{Name: g.Root, Groups: Groups(g.Actions)})
+		}
+	}
+
+	for _, a := range r.Result.Actions {
+		switch {
+		case a.Error != "" || (a.HookError != "" && a.Reason != organizer.ReasonVetoed):
+			data.Failed = append(data.Failed, a)
+		case a.Reason != organizer.ReasonOrganized && !a.Moved && !a.Removed && !a.Linked:
+			data.Skipped = append(data.Skipped, a)
+		}
+	}
+
+	index := make(map[string]int)
+	for _, rule := range r.Rules {
+		i, ok := index[rule.Folder]
+		if !ok {
+			i = len(data.Folders)
+			index[rule.Folder] = i
+			data.Folders = append(data.Folders, folderRules{Folder: rule.Folder})
+		}
+		data.Folders[i].Extensions = append(data.Folders[i].Extensions, rule.Extension)
+	}
+
+	return page.Execute(w, data)
+}
+
+// WriteFile renders r as an HTML page to the file at path.
+func WriteFile(path string, r Report) (err error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := f.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}()
+	return Write(f, r)
+}
@@ -0,0 +1,169 @@
+package report_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/d6o/Gorganizer/internal/report"
+	"github.com/d6o/Gorganizer/internal/testutil"
+	"github.com/d6o/Gorganizer/internal/trash"
+	"github.com/d6o/Gorganizer/pkg/organizer"
+	"github.com/d6o/Gorganizer/pkg/store"
+)
+
+func testResult() *organizer.OrganizeResult {
+	return &organizer.OrganizeResult{
+		RunID: "343b915dd970",
+		Actions: []organizer.FileAction{
+			{FileName: "a.mp3", Path: "/in/a.mp3", Size: 3000, Destination: "Music", Reason: organizer.ReasonOrganized, Moved: true},
+			{FileName: "b.pdf", NewName: "B.pdf", Path: "/in/b.pdf", Size: 10, Destination: "Documents", Reason: organizer.ReasonOrganized, Moved: true},
+			{FileName: "c.mp3", Path: "/in/c.mp3", Size: 2000, Destination: "Music", Reason: organizer.ReasonOrganized, Error: "permission denied"},
+			{FileName: "<d>.xyz", Path: "/in/<d>.xyz", Reason: organizer.ReasonUnknownExtension},
+			{FileName: "e.mp3", Path: "/in/e.mp3", Reason: organizer.ReasonDuplicate, DuplicateOf: "/in/a.mp3", Removed: true},
+		},
+	}
+}
+
+func TestGroups(t *testing.T) {
+	t.Parallel()
+	groups := report.Groups(testResult().Actions)
+
+	var got []string
+	for _, g := range groups {
+		var names []string
+		for _, a := range g.Actions {
+			names = append(names, report.Name(a))
+		}
+		got = append(got, g.Label+": "+strings.Join(names, ", "))
+	}
+	want := []string{
+		"Music: a.mp3, c.mp3",
+		"Documents: b.pdf → B.pdf",
+		"Unknown extension (will not be moved): <d>.xyz",
+		"Duplicate Files: e.mp3",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("groups =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if groups[0].Bytes() != 5000 {
+		t.Errorf("Music holds %d bytes, want 5000", groups[0].Bytes())
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		n    int64
+		want string
+	}{
+		{0, "0 B"},
+		{999, "999 B"},
+		{1000, "1.0 kB"},
+		{38_200_000_000, "38.2 GB"},
+	}
+	for _, tt := range tests {
+		if got := report.FormatBytes(tt.n); got != tt.want {
+			t.Errorf("FormatBytes(%d) = %q, want %q", tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestWrite(t *testing.T) {
+	t.Parallel()
+	var buf bytes.Buffer
+	err := report.Write(&buf, report.Report{
+		Result:    testResult(),
+		Rules:     []store.Rule{{Extension: "mp3", Folder: "Music"}, {Extension: "pdf", Folder: "Documents"}, {Extension: "flac", Folder: "Music"}},
+		Generated: time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	page := buf.String()
+
+	for _, want := range []string{
+		"Run 343b915dd970.",
+		"<summary>Music <span class=\"total\">(2 files, 5.0 kB)</span></summary>",
+		"b.pdf → B.pdf",
+		"Failed files (1)",
+		"permission denied",
+		"Skipped files (1)",
+		"&lt;d&gt;.xyz</td><td>unknown</td>",
+		"<summary>Music <span class=\"total\">(2 extensions)</span></summary>\n<p>mp3, flac</p>",
+	} {
+		if !strings.Contains(page, want) {
+			t.Errorf("report lacks %q", want)
+		}
+	}
+	if strings.Contains(page, "<d>") {
+		t.Error("file names are not escaped")
+	}
+	for _, external := range []string{"<link", "<script", "src="} {
+		if strings.Contains(page, external) {
+			t.Errorf("report is not self-contained, it has %q", external)
+		}
+	}
+}
+
+func TestWrite_FailedRun(t *testing.T) {
+	t.Parallel()
+	in := t.TempDir()
+	out := t.TempDir()
+	testutil.WriteFile(t, in, "a.mp3", "")
+	testutil.WriteFile(t, in, "b.pdf", "")
+	testutil.WriteFile(t, out, "Music", "")
+
+	s, err := store.NewStore("en", store.WithConfigDir(t.TempDir()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr, err := trash.New(trash.WithHomeTrash(filepath.Join(t.TempDir(), "Trash")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, runErr := organizer.NewOrganizer(s, organizer.Config{InputFolder: in, OutputFolder: out, Trash: tr}).Run()
+	if runErr == nil {
+		t.Fatal("Run() should fail")
+	}
+
+	var buf bytes.Buffer
+	if err := report.Write(&buf, report.Report{Result: result, Error: runErr.Error()}); err != nil {
+		t.Fatal(err)
+	}
+	page := buf.String()
+	for _, want := range []string{"The run stopped: ", "Failed files (1)"} {
+		if !strings.Contains(page, want) {
+			t.Errorf("report lacks %q", want)
+		}
+	}
+	if strings.Contains(page, "b.pdf") {
+		t.Error("report lists b.pdf, which the failed run never reached")
+	}
+}
+
+func TestWriteFile_Roots(t *testing.T) {
+	t.Parallel()
+	result := &organizer.OrganizeResult{Actions: []organizer.FileAction{
+		{FileName: "a.mp3", Root: "/downloads", Destination: "Music", Reason: organizer.ReasonOrganized},
+		{FileName: "b.mp3", Root: "/desktop", Destination: "Music", Reason: organizer.ReasonOrganized},
+	}}
+	file := filepath.Join(t.TempDir(), "report.html")
+
+	if err := report.WriteFile(file, report.Report{Result: result, Preview: true}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	page := string(data)
+	for _, want := range []string{"Preview, nothing was moved.", "<summary>/downloads</summary>", "<summary>/desktop</summary>"} {
+		if !strings.Contains(page, want) {
+			t.Errorf("report lacks %q", want)
+		}
+	}
+}
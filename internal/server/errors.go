@@ -0,0 +1,24 @@
+package server
+
+import "errors"
+
+// ErrUnauthorized is returned when a request does not carry the server token.
+var ErrUnauthorized = errors.New("missing or invalid token")
+
+// ErrRuleNotFound is returned when deleting a rule for an extension that has
+// no rule.
+var ErrRuleNotFound = errors.New("no rule for extension")
+
+// ErrRunNotFound is returned when a run id is unknown.
+var ErrRunNotFound = errors.New("run not found")
+
+// ErrDirectoryRequired is returned when an organize request has no directory.
+var ErrDirectoryRequired = errors.New("directory is required")
+
+// ErrStreamingUnsupported is returned when the connection cannot stream
+// Server-Sent Events.
+var ErrStreamingUnsupported = errors.New("streaming unsupported")
+
+// ErrNotLoopback is returned when asked to listen on an address that is not
+// on the loopback interface.
+var ErrNotLoopback = errors.New("server must listen on localhost or a unix socket")
@@ -0,0 +1,264 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/d6o/Gorganizer/pkg/organizer"
+)
+
+// Run states reported by the API.
+const (
+	StatusRunning = "running"
+	StatusDone    = "done"
+	StatusFailed  = "failed"
+)
+
+// OrganizeRequest is the body of POST /organize. Output defaults to
+// Directory and Hidden defaults to true, as on the command line.
+type OrganizeRequest struct {
+	Directory  string   `json:"directory"`
+	Output     string   `json:"output"`
+	Preview    bool     `json:"preview"`
+	Recursive  bool     `json:"recursive"`
+	Hidden     *bool    `json:"hidden"`
+	Exclude    []string `json:"exclude"`
+	Duplicates string   `json:"duplicates"`
+	Archives   string   `json:"archives"`
+	Permanent  bool     `json:"permanent"`
+}
+
+// RunStatus is the body of GET /runs/{id}.
+type RunStatus struct {
+	ID     string                    `json:"id"`
+	Status string                    `json:"status"`
+	Error  string                    `json:"error,omitempty"`
+	Result *organizer.OrganizeResult `json:"result,omitempty"`
+}
+
+// run tracks one organize run and the progress events it produced.
+type run struct {
+	mu      sync.Mutex
+	status  RunStatus
+	events  []organizer.FileAction
+	changed chan struct{}
+}
+
+func (r *run) add(a organizer.FileAction) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, a)
+	r.notify()
+}
+
+func (r *run) finish(result *organizer.OrganizeResult, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	// A failed run still reports what it did before failing.
+	r.status.Result = result
+	if err != nil {
+		r.status.Status = StatusFailed
+		r.status.Error = err.Error()
+	} else {
+		r.status.Status = StatusDone
+	}
+	r.notify()
+}
+
+// notify wakes every waiter. It must be called with r.mu held.
+func (r *run) notify() {
+	close(r.changed)
+	r.changed = make(chan struct{})
+}
+
+// since returns the events after the first n, the current status, and a
+// channel closed on the next change.
+func (r *run) since(n int) ([]organizer.FileAction, RunStatus, <-chan struct{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.events[n:], r.status, r.changed
+}
+
+func (r *run) snapshot() RunStatus {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.status
+}
+
+// runs holds the runs in progress and the last limit finished ones.
+type runs struct {
+	mu       sync.Mutex
+	byID     map[string]*run
+	finished []string
+	limit    int
+}
+
+func newRuns(limit int) *runs {
+	return &runs{byID: make(map[string]*run), limit: limit}
+}
+
+func (rs *runs) start() (*run, error) {
+	id, err := NewToken()
+	if err != nil {
+		return nil, err
+	}
+
+	r := &run{
+		status:  RunStatus{ID: id[:12], Status: StatusRunning},
+		changed: make(chan struct{}),
+	}
+
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	rs.byID[r.status.ID] = r
+	return r, nil
+}
+
+// finish records the outcome of r and forgets the oldest finished runs
+// beyond the limit.
+func (rs *runs) finish(r *run, result *organizer.OrganizeResult, err error) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	r.finish(result, err)
+	rs.finished = append(rs.finished, r.snapshot().ID)
+	for len(rs.finished) > max(rs.limit, 0) {
+		delete(rs.byID, rs.finished[0])
+		rs.finished = rs.finished[1:]
+	}
+}
+
+func (rs *runs) get(id string) (*run, bool) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	r, ok := rs.byID[id]
+	return r, ok
+}
+
+func (s *Server) organize(w http.ResponseWriter, r *http.Request) {
+	var req OrganizeRequest
+	if !readJSON(w, r, &req) {
+		return
+	}
+	if req.Directory == "" {
+		writeError(w, http.StatusBadRequest, ErrDirectoryRequired)
+		return
+	}
+
+	policy, err := organizer.ParseDuplicatePolicy(req.Duplicates)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	archives, err := organizer.ParseArchivePolicy(req.Archives)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	output := req.Output
+	if output == "" {
+		output = req.Directory
+	}
+	hidden := true
+	if req.Hidden != nil {
+		hidden = *req.Hidden
+	}
+
+	rn, err := s.runs.start()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	org := organizer.NewOrganizer(s, organizer.Config{
+		InputFolder:       req.Directory,
+		OutputFolder:      output,
+		Preview:           req.Preview,
+		Recursive:         req.Recursive,
+		IgnoreHiddenFiles: hidden,
+		ExcludeList:       organizer.ExcludeList(req.Exclude),
+		Duplicates:        policy,
+		Archives:          archives,
+		Permanent:         req.Permanent,
+		Trash:             s.trash,
+		Audit:             s.audit,
+		Hooks:             s.hooks,
+		Logger:            s.logger,
+		RunID:             rn.snapshot().ID,
+		Progress:          rn.add,
+	})
+
+	status := rn.snapshot()
+	s.active.Add(1)
+	go func() {
+		defer s.active.Done()
+		result, err := org.Run()
+		s.runs.finish(rn, result, err)
+	}()
+
+	w.Header().Set("Location", "/runs/"+status.ID)
+	writeJSON(w, http.StatusAccepted, status)
+}
+
+func (s *Server) getRun(w http.ResponseWriter, r *http.Request) {
+	rn, ok := s.runs.get(r.PathValue("id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, ErrRunNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, rn.snapshot())
+}
+
+// runEvents streams an "action" event for every file handled by the run,
+// including those handled before the client connected, followed by a single
+// "done" event carrying the final status.
+func (s *Server) runEvents(w http.ResponseWriter, r *http.Request) {
+	rn, ok := s.runs.get(r.PathValue("id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, ErrRunNotFound)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, ErrStreamingUnsupported)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	sent := 0
+	for {
+		events, status, changed := rn.since(sent)
+		for _, a := range events {
+			writeEvent(w, "action", a)
+		}
+		sent += len(events)
+
+		if status.Status != StatusRunning {
+			status.Result = nil
+			writeEvent(w, "done", status)
+			flusher.Flush()
+			return
+		}
+		flusher.Flush()
+
+		select {
+		case <-changed:
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+func writeEvent(w http.ResponseWriter, event string, v any) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		data = []byte(fmt.Sprintf("%q", err.Error()))
+	}
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+}
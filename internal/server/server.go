@@ -0,0 +1,306 @@
+// Package server exposes rule management and organize runs over a local
+// HTTP/JSON API, with progress streamed as Server-Sent Events.
+//
+// Endpoints:
+//
+//	GET    /rules               list all rules
+//	POST   /rules               add a rule: {"extension": "mp3", "folder": "Music"}
+//	DELETE /rules/{ext}         delete the rule for an extension
+//	POST   /organize            start a run, see OrganizeRequest
+//	GET    /runs/{id}           status and result of a run
+//	GET    /runs/{id}/events    progress of a run as Server-Sent Events
+//
+// Every request must carry the server token, either as an
+// "Authorization: Bearer <token>" header or, for clients such as EventSource
+// that cannot set headers, as a "token" query parameter. Request bodies are
+// limited to MaxRequestBytes, and only the last DefaultRunHistory finished
+// runs are kept, see WithRunHistory.
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/d6o/Gorganizer/pkg/organizer"
+	"github.com/d6o/Gorganizer/pkg/store"
+)
+
+// RuleStore is the rule database served by the API. *store.Store satisfies
+// it. The server serializes every call, so implementations need not be safe
+// for concurrent use.
+type RuleStore interface {
+	Lookup(ext string) string
+	Rules() []store.Rule
+	InsertRule(rule string) error
+	DeleteRule(ext string) error
+	Save() error
+}
+
+// MaxRequestBytes is the largest request body the server reads.
+const MaxRequestBytes = 1 << 20
+
+// DefaultRunHistory is how many finished runs the server keeps by default.
+const DefaultRunHistory = 100
+
+// Option configures a Server during construction.
+type Option func(*Server)
+
+// WithTrash sets where organize runs send the files they remove, instead of
+// the user's trash.
+func WithTrash(t organizer.Trash) Option {
+	return func(s *Server) {
+		s.trash = t
+	}
+}
+
+// WithAudit appends every file operation of the organize runs to log.
+func WithAudit(log *organizer.AuditLog) Option {
+	return func(s *Server) {
+		s.audit = log
+	}
+}
+
+// WithHooks runs hooks at the events of every organize run. Clients cannot
+// add their own, so the API never runs commands the server was not started
+// with.
+func WithHooks(hooks []organizer.Hook) Option {
+	return func(s *Server) {
+		s.hooks = hooks
+	}
+}
+
+// WithLogger sets the logger organize runs report to. By default nothing is
+// logged.
+func WithLogger(l *slog.Logger) Option {
+	return func(s *Server) {
+		s.logger = l
+	}
+}
+
+// WithRunHistory sets how many finished runs the server keeps. Older ones
+// are forgotten, and asking for them returns ErrRunNotFound. Runs in
+// progress are always kept.
+func WithRunHistory(n int) Option {
+	return func(s *Server) {
+		s.runs.limit = n
+	}
+}
+
+// Server is an http.Handler serving the Gorganizer API.
+type Server struct {
+	mu     sync.Mutex
+	store  RuleStore
+	token  string
+	trash  organizer.Trash
+	audit  *organizer.AuditLog
+	hooks  []organizer.Hook
+	logger *slog.Logger
+	runs   *runs
+	mux    *http.ServeMux
+
+	// active counts the organize runs still in progress.
+	active sync.WaitGroup
+}
+
+// New creates a Server for the given rules, requiring token on every request.
+func New(rules RuleStore, token string, opts ...Option) *Server {
+	s := &Server{
+		store: rules,
+		token: token,
+		runs:  newRuns(DefaultRunHistory),
+		mux:   http.NewServeMux(),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	s.mux.HandleFunc("GET /rules", s.listRules)
+	s.mux.HandleFunc("POST /rules", s.addRule)
+	s.mux.HandleFunc("DELETE /rules/{ext}", s.deleteRule)
+	s.mux.HandleFunc("POST /organize", s.organize)
+	s.mux.HandleFunc("GET /runs/{id}", s.getRun)
+	s.mux.HandleFunc("GET /runs/{id}/events", s.runEvents)
+
+	return s
+}
+
+// ServeHTTP authenticates the request and dispatches it.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !s.authorized(r) {
+		writeError(w, http.StatusUnauthorized, ErrUnauthorized)
+		return
+	}
+	s.mux.ServeHTTP(w, r)
+}
+
+// Wait blocks until the organize runs started by requests have finished.
+// It returns ctx's error if ctx is done first. Call it once the HTTP server
+// has shut down, so that no request starts another run.
+func (s *Server) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		s.active.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *Server) authorized(r *http.Request) bool {
+	token := r.URL.Query().Get("token")
+	if auth := r.Header.Get("Authorization"); auth != "" {
+		token = strings.TrimPrefix(auth, "Bearer ")
+	}
+	return s.token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) == 1
+}
+
+// Lookup resolves an extension while holding the store lock, so organize
+// runs can share the store with rule edits.
+func (s *Server) Lookup(ext string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.store.Lookup(ext)
+}
+
+type ruleRequest struct {
+	Extension string `json:"extension"`
+	Folder    string `json:"folder"`
+}
+
+func (s *Server) listRules(w http.ResponseWriter, _ *http.Request) {
+	s.mu.Lock()
+	rules := s.store.Rules()
+	s.mu.Unlock()
+
+	if rules == nil {
+		rules = []store.Rule{}
+	}
+	writeJSON(w, http.StatusOK, rules)
+}
+
+func (s *Server) addRule(w http.ResponseWriter, r *http.Request) {
+	var req ruleRequest
+	if !readJSON(w, r, &req) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.store.InsertRule(req.Extension + ":" + req.Folder); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := s.store.Save(); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, store.Rule{
+		Extension: strings.ToLower(req.Extension),
+		Folder:    s.store.Lookup(req.Extension),
+	})
+}
+
+func (s *Server) deleteRule(w http.ResponseWriter, r *http.Request) {
+	ext := r.PathValue("ext")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.store.Lookup(ext) == "" {
+		writeError(w, http.StatusNotFound, ErrRuleNotFound)
+		return
+	}
+
+	if err := s.store.DeleteRule(ext); err != nil {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+	if err := s.store.Save(); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// readJSON decodes the request body into v, reading at most
+// MaxRequestBytes. It writes the error response and returns false when the
+// body cannot be decoded.
+func readJSON(w http.ResponseWriter, r *http.Request, v any) bool {
+	err := json.NewDecoder(http.MaxBytesReader(w, r.Body, MaxRequestBytes)).Decode(v)
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooLarge):
+		writeError(w, http.StatusRequestEntityTooLarge, err)
+	case err != nil:
+		writeError(w, http.StatusBadRequest, err)
+	}
+	return err == nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+// Listen opens the listener for the server: a Unix socket when socket is set,
+// otherwise a TCP address that must be on the loopback interface.
+func Listen(addr, socket string) (net.Listener, error) {
+	if socket != "" {
+		if info, err := os.Lstat(socket); err == nil && info.Mode()&os.ModeSocket != 0 {
+			if err := os.Remove(socket); err != nil {
+				return nil, err
+			}
+		}
+
+		l, err := net.Listen("unix", socket)
+		if err != nil {
+			return nil, err
+		}
+		if err := os.Chmod(socket, 0o600); err != nil {
+			return nil, errors.Join(err, l.Close())
+		}
+		return l, nil
+	}
+
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+		return nil, ErrNotLoopback
+	}
+
+	return net.Listen("tcp", addr)
+}
+
+// NewToken returns a random token suitable for authenticating clients.
+func NewToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,395 @@
+package server_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/d6o/Gorganizer/internal/server"
+	"github.com/d6o/Gorganizer/internal/testutil"
+	"github.com/d6o/Gorganizer/internal/trash"
+	"github.com/d6o/Gorganizer/pkg/organizer"
+	"github.com/d6o/Gorganizer/pkg/store"
+)
+
+const token = "secret"
+
+func newTestAPI(t *testing.T, opts ...server.Option) *server.Server {
+	t.Helper()
+	s, err := store.NewStore("en", store.WithConfigDir(t.TempDir()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr, err := trash.New(trash.WithHomeTrash(filepath.Join(t.TempDir(), "Trash")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return server.New(s, token, append([]server.Option{server.WithTrash(tr)}, opts...)...)
+}
+
+func newTestServer(t *testing.T, opts ...server.Option) *httptest.Server {
+	t.Helper()
+	ts := httptest.NewServer(newTestAPI(t, opts...))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func do(t *testing.T, ts *httptest.Server, method, path, body string) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = resp.Body.Close()
+	})
+	return resp
+}
+
+func decode(t *testing.T, resp *http.Response, v any) {
+	t.Helper()
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func waitForRun(t *testing.T, ts *httptest.Server, id string) server.RunStatus {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		var status server.RunStatus
+		decode(t, do(t, ts, http.MethodGet, "/runs/"+id, ""), &status)
+		if status.Status != server.StatusRunning {
+			return status
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("run did not finish")
+	return server.RunStatus{}
+}
+
+func TestServer_RequiresToken(t *testing.T) {
+	t.Parallel()
+	ts := newTestServer(t)
+
+	resp, err := ts.Client().Get(ts.URL + "/rules")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("status = %d, want 401", resp.StatusCode)
+	}
+
+	resp2, err := ts.Client().Get(ts.URL + "/rules?token=" + token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = resp2.Body.Close()
+	}()
+	if resp2.StatusCode != http.StatusOK {
+		t.Errorf("status with query token = %d, want 200", resp2.StatusCode)
+	}
+}
+
+func TestServer_Rules(t *testing.T) {
+	t.Parallel()
+	ts := newTestServer(t)
+
+	resp := do(t, ts, http.MethodPost, "/rules", `{"extension": "PY", "folder": "python"}`)
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("add status = %d, want 201", resp.StatusCode)
+	}
+	var added store.Rule
+	decode(t, resp, &added)
+	if added.Extension != "py" || added.Folder != "Python" {
+		t.Errorf("added = %+v, want py:Python", added)
+	}
+
+	var rules []store.Rule
+	decode(t, do(t, ts, http.MethodGet, "/rules", ""), &rules)
+	if !containsRule(rules, "py", "Python") {
+		t.Error("new rule missing from list")
+	}
+
+	if resp := do(t, ts, http.MethodDelete, "/rules/py", ""); resp.StatusCode != http.StatusNoContent {
+		t.Errorf("delete status = %d, want 204", resp.StatusCode)
+	}
+	if resp := do(t, ts, http.MethodDelete, "/rules/py", ""); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("second delete status = %d, want 404", resp.StatusCode)
+	}
+
+	decode(t, do(t, ts, http.MethodGet, "/rules", ""), &rules)
+	if containsRule(rules, "py", "Python") {
+		t.Error("deleted rule still listed")
+	}
+}
+
+func TestServer_InvalidRule(t *testing.T) {
+	t.Parallel()
+	ts := newTestServer(t)
+
+	if resp := do(t, ts, http.MethodPost, "/rules", `{"extension": "py"}`); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400", resp.StatusCode)
+	}
+}
+
+func TestServer_Organize(t *testing.T) {
+	t.Parallel()
+	ts := newTestServer(t)
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "song.mp3"), nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	resp := do(t, ts, http.MethodPost, "/organize", `{"directory": "`+filepath.ToSlash(dir)+`"}`)
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("status = %d, want 202", resp.StatusCode)
+	}
+	var started server.RunStatus
+	decode(t, resp, &started)
+
+	status := waitForRun(t, ts, started.ID)
+	if status.Status != server.StatusDone {
+		t.Fatalf("status = %+v, want done", status)
+	}
+	if len(status.Result.Actions) != 1 || !status.Result.Actions[0].Moved {
+		t.Errorf("result = %+v, want song.mp3 moved", status.Result)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "Music", "song.mp3")); err != nil {
+		t.Error("song.mp3 should be organized")
+	}
+}
+
+func TestServer_Audit(t *testing.T) {
+	t.Parallel()
+	file := filepath.Join(t.TempDir(), "audit.jsonl")
+	log, err := organizer.OpenAuditLog(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = log.Close()
+	})
+	ts := newTestServer(t, server.WithAudit(log))
+	dir := t.TempDir()
+	testutil.WriteFile(t, dir, "song.mp3", "")
+
+	var started server.RunStatus
+	decode(t, do(t, ts, http.MethodPost, "/organize", `{"directory": "`+filepath.ToSlash(dir)+`"}`), &started)
+	waitForRun(t, ts, started.ID)
+
+	records, err := organizer.ReadAuditLog(file, organizer.AuditFilter{RunID: started.ID})
+	if err != nil || len(records) != 1 || records[0].Op != organizer.OpMove {
+		t.Errorf("audit records of run %s = %+v, %v, want the move", started.ID, records, err)
+	}
+}
+
+func TestServer_Hooks(t *testing.T) {
+	t.Parallel()
+	ts := newTestServer(t, server.WithHooks([]organizer.Hook{{Event: organizer.HookPreMove, Command: "exit 1"}}))
+	dir := t.TempDir()
+	testutil.WriteFile(t, dir, "song.mp3", "")
+
+	var started server.RunStatus
+	decode(t, do(t, ts, http.MethodPost, "/organize", `{"directory": "`+filepath.ToSlash(dir)+`"}`), &started)
+
+	status := waitForRun(t, ts, started.ID)
+	if len(status.Result.Actions) != 1 || status.Result.Actions[0].Reason != organizer.ReasonVetoed {
+		t.Errorf("result = %+v, want song.mp3 vetoed by the hook", status.Result)
+	}
+}
+
+func TestServer_Wait(t *testing.T) {
+	t.Parallel()
+	api := newTestAPI(t, server.WithHooks([]organizer.Hook{{Event: organizer.HookPreMove, Command: "sleep 0.2"}}))
+	ts := httptest.NewServer(api)
+	t.Cleanup(ts.Close)
+	dir := t.TempDir()
+	testutil.WriteFile(t, dir, "song.mp3", "")
+
+	do(t, ts, http.MethodPost, "/organize", `{"directory": "`+filepath.ToSlash(dir)+`"}`)
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := api.Wait(cancelled); !errors.Is(err, context.Canceled) {
+		t.Errorf("Wait() with a done context = %v, want context.Canceled while the run is in progress", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := api.Wait(ctx); err != nil {
+		t.Fatalf("Wait() = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "Music", "song.mp3")); err != nil {
+		t.Error("Wait returned before the run moved song.mp3")
+	}
+}
+
+func TestServer_OrganizeFailure(t *testing.T) {
+	t.Parallel()
+	ts := newTestServer(t)
+
+	var started server.RunStatus
+	decode(t, do(t, ts, http.MethodPost, "/organize", `{"directory": "/does/not/exist", "preview": true}`), &started)
+
+	status := waitForRun(t, ts, started.ID)
+	if status.Status != server.StatusFailed || status.Error == "" {
+		t.Errorf("status = %+v, want failed with error", status)
+	}
+}
+
+func TestServer_OrganizeBadRequest(t *testing.T) {
+	t.Parallel()
+	ts := newTestServer(t)
+
+	for _, body := range []string{`{}`, `{"directory": ".", "duplicates": "shred"}`, `not json`} {
+		if resp := do(t, ts, http.MethodPost, "/organize", body); resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("body %s: status = %d, want 400", body, resp.StatusCode)
+		}
+	}
+}
+
+func TestServer_RequestTooLarge(t *testing.T) {
+	t.Parallel()
+	ts := newTestServer(t)
+
+	body := `{"directory": "` + strings.Repeat("a", server.MaxRequestBytes) + `"}`
+	if resp := do(t, ts, http.MethodPost, "/organize", body); resp.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Errorf("status = %d, want 413", resp.StatusCode)
+	}
+}
+
+func TestServer_RunHistory(t *testing.T) {
+	t.Parallel()
+	ts := newTestServer(t, server.WithRunHistory(2))
+	dir := t.TempDir()
+
+	var ids []string
+	for i := 0; i < 3; i++ {
+		var status server.RunStatus
+		decode(t, do(t, ts, http.MethodPost, "/organize", `{"directory": "`+filepath.ToSlash(dir)+`", "preview": true}`), &status)
+		waitForRun(t, ts, status.ID)
+		ids = append(ids, status.ID)
+	}
+
+	if resp := do(t, ts, http.MethodGet, "/runs/"+ids[0], ""); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("oldest run status = %d, want 404", resp.StatusCode)
+	}
+	for _, id := range ids[1:] {
+		if resp := do(t, ts, http.MethodGet, "/runs/"+id, ""); resp.StatusCode != http.StatusOK {
+			t.Errorf("run %s status = %d, want 200", id, resp.StatusCode)
+		}
+	}
+}
+
+func TestServer_Events(t *testing.T) {
+	t.Parallel()
+	ts := newTestServer(t)
+	dir := t.TempDir()
+	for _, name := range []string{"a.mp3", "b.pdf"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var started server.RunStatus
+	decode(t, do(t, ts, http.MethodPost, "/organize", `{"directory": "`+filepath.ToSlash(dir)+`", "preview": true}`), &started)
+
+	resp := do(t, ts, http.MethodGet, "/runs/"+started.ID+"/events", "")
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Content-Type = %q", ct)
+	}
+
+	var events []string
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		if name, ok := strings.CutPrefix(scanner.Text(), "event: "); ok {
+			events = append(events, name)
+		}
+	}
+
+	if strings.Join(events, ",") != "action,action,done" {
+		t.Errorf("events = %v, want two actions then done", events)
+	}
+}
+
+func TestServer_ConcurrentRuleEdits(t *testing.T) {
+	t.Parallel()
+	ts := newTestServer(t)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			ext := string(rune('a'+i)) + "x"
+			req, err := http.NewRequest(http.MethodPost, ts.URL+"/rules",
+				strings.NewReader(`{"extension": "`+ext+`", "folder": "F"}`))
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			req.Header.Set("Authorization", "Bearer "+token)
+
+			resp, err := ts.Client().Do(req)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			_ = resp.Body.Close()
+		}(i)
+	}
+	wg.Wait()
+
+	var rules []store.Rule
+	decode(t, do(t, ts, http.MethodGet, "/rules", ""), &rules)
+	for i := 0; i < 10; i++ {
+		if !containsRule(rules, string(rune('a'+i))+"x", "F") {
+			t.Errorf("rule %d missing", i)
+		}
+	}
+}
+
+func TestListen_RejectsPublicAddress(t *testing.T) {
+	t.Parallel()
+
+	if _, err := server.Listen("0.0.0.0:0", ""); err != server.ErrNotLoopback {
+		t.Errorf("Listen(0.0.0.0) error = %v, want ErrNotLoopback", err)
+	}
+
+	l, err := server.Listen("127.0.0.1:0", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Close(); err != nil {
+		t.Error(err)
+	}
+}
+
+func containsRule(rules []store.Rule, ext, folder string) bool {
+	for _, r := range rules {
+		if r.Extension == ext && r.Folder == folder {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,11 @@
+package trash
+
+import "errors"
+
+// ErrNoMountPoint is returned when the mount point containing a file cannot
+// be determined.
+var ErrNoMountPoint = errors.New("no mount point found")
+
+// ErrInvalidTrash is returned when a trash directory exists but is not a
+// directory the current user can safely use.
+var ErrInvalidTrash = errors.New("invalid trash directory")
@@ -0,0 +1,52 @@
+//go:build !windows
+
+package trash
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// mountPoint walks up from path until the device changes; the last directory
+// on the same device is the top of its mount.
+func mountPoint(path string) (string, error) {
+	dev, err := device(path)
+	if err != nil {
+		return "", err
+	}
+
+	dir := path
+	if info, err := os.Lstat(path); err == nil && !info.IsDir() {
+		dir = filepath.Dir(path)
+	}
+
+	for {
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return dir, nil
+		}
+
+		parentDev, err := device(parent)
+		if err != nil {
+			return "", err
+		}
+		if parentDev != dev {
+			return dir, nil
+		}
+		dir = parent
+	}
+}
+
+func device(path string) (uint64, error) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return 0, err
+	}
+
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, ErrNoMountPoint
+	}
+	return uint64(st.Dev), nil //nolint:unconvert // Dev is not uint64 on every platform
+}
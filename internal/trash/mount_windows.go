@@ -0,0 +1,11 @@
+//go:build windows
+
+package trash
+
+import "errors"
+
+// mountPoint is not supported on Windows, which has no freedesktop.org trash;
+// trashDir moves every file to the home trash instead.
+func mountPoint(string) (string, error) {
+	return "", errors.ErrUnsupported
+}
@@ -0,0 +1,276 @@
+// Package trash moves files to the trash following the freedesktop.org Trash
+// specification, so that files removed by Gorganizer can be restored with any
+// compliant desktop file manager.
+//
+// Files on the same filesystem as the home trash are moved to
+// $XDG_DATA_HOME/Trash (by default ~/.local/share/Trash). Files on other
+// filesystems are moved to the trash directory at the top of their mount:
+// $topdir/.Trash/$uid when an administrator created a sticky $topdir/.Trash,
+// or $topdir/.Trash-$uid otherwise. When neither can be used, such as on a
+// read-only top directory, they are copied to the home trash and removed.
+// On platforms without mount points, such as Windows, every file goes to the
+// home trash.
+package trash
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/d6o/Gorganizer/internal/fsutil"
+)
+
+const (
+	filesDir      = "files"
+	infoDir       = "info"
+	infoExtension = ".trashinfo"
+	dateLayout    = "2006-01-02T15:04:05"
+)
+
+// Option configures a Trash during construction.
+type Option func(*Trash)
+
+// WithHomeTrash overrides the home trash directory, which defaults to
+// $XDG_DATA_HOME/Trash or ~/.local/share/Trash.
+func WithHomeTrash(dir string) Option {
+	return func(t *Trash) {
+		t.home = dir
+	}
+}
+
+// WithMountPoints replaces mount point detection with a fixed list. The top
+// directory of a file is the longest mount point containing it. This is
+// mostly useful in tests, where temporary directories stand in for mounts.
+func WithMountPoints(points ...string) Option {
+	return func(t *Trash) {
+		t.mounts = points
+	}
+}
+
+// WithUID overrides the user id used to name per-mount trash directories.
+func WithUID(uid int) Option {
+	return func(t *Trash) {
+		t.uid = uid
+	}
+}
+
+// WithClock overrides the function used to timestamp deletions.
+func WithClock(now func() time.Time) Option {
+	return func(t *Trash) {
+		t.now = now
+	}
+}
+
+// Trash moves files into freedesktop.org trash directories.
+type Trash struct {
+	home   string
+	mounts []string
+	uid    int
+	now    func() time.Time
+}
+
+// New creates a Trash for the current user.
+func New(opts ...Option) (*Trash, error) {
+	t := &Trash{
+		uid: os.Getuid(),
+		now: time.Now,
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+
+	if t.home == "" {
+		home, err := homeTrash()
+		if err != nil {
+			return nil, err
+		}
+		t.home = home
+	}
+
+	return t, nil
+}
+
+func homeTrash() (string, error) {
+	if dataHome := os.Getenv("XDG_DATA_HOME"); dataHome != "" {
+		return filepath.Join(dataHome, "Trash"), nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".local", "share", "Trash"), nil
+}
+
+// Put moves the file or directory at path into the trash and returns its new
+// location. A matching .trashinfo file records the original path and the
+// deletion date so the file can be restored.
+func (t *Trash) Put(path string) (string, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := os.Lstat(path); err != nil {
+		return "", err
+	}
+
+	dir, infoPath, err := t.trashDir(path)
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(filepath.Join(dir, filesDir), 0o700); err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Join(dir, infoDir), 0o700); err != nil {
+		return "", err
+	}
+
+	name, info, err := t.reserve(dir, filepath.Base(path))
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := fmt.Fprintf(info, "[Trash Info]\nPath=%s\nDeletionDate=%s\n",
+		escapePath(infoPath), t.now().Format(dateLayout)); err != nil {
+		return "", errors.Join(err, info.Close(), os.Remove(info.Name()))
+	}
+	if err := info.Close(); err != nil {
+		return "", errors.Join(err, os.Remove(info.Name()))
+	}
+
+	dest := filepath.Join(dir, filesDir, name)
+	if err := fsutil.Move(path, dest); err != nil {
+		return "", errors.Join(err, os.Remove(info.Name()))
+	}
+
+	return dest, nil
+}
+
+// trashDir returns the trash directory for path, and the path to record in
+// its .trashinfo file: absolute for the home trash, relative to the mount's
+// top directory otherwise. Files whose mount has no usable trash directory,
+// or whose mount cannot be determined on this platform, go to the home trash.
+func (t *Trash) trashDir(path string) (dir, infoPath string, err error) {
+	if err := os.MkdirAll(t.home, 0o700); err != nil {
+		return "", "", err
+	}
+
+	homeTop, err := t.topDir(t.home)
+	if errors.Is(err, errors.ErrUnsupported) {
+		return t.home, path, nil
+	}
+	if err != nil {
+		return "", "", err
+	}
+	top, err := t.topDir(path)
+	if err != nil {
+		return "", "", err
+	}
+
+	if top == homeTop {
+		return t.home, path, nil
+	}
+
+	rel, err := filepath.Rel(top, path)
+	if err != nil {
+		return "", "", err
+	}
+
+	dir, err = t.topTrash(top)
+	if err != nil {
+		return t.home, path, nil
+	}
+	return dir, rel, nil
+}
+
+// topTrash returns the trash directory at the top of a mount, preferring an
+// administrator provided $topdir/.Trash/$uid over $topdir/.Trash-$uid. The
+// shared directory is only used when it is a real, sticky directory.
+func (t *Trash) topTrash(top string) (string, error) {
+	uid := strconv.Itoa(t.uid)
+
+	shared := filepath.Join(top, ".Trash")
+	if info, err := os.Lstat(shared); err == nil &&
+		info.IsDir() && info.Mode()&os.ModeSticky != 0 {
+		dir := filepath.Join(shared, uid)
+		if err := os.MkdirAll(dir, 0o700); err == nil {
+			return dir, nil
+		}
+	}
+
+	dir := filepath.Join(top, ".Trash-"+uid)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", err
+	}
+
+	info, err := os.Lstat(dir)
+	if err != nil {
+		return "", err
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("%w: %s", ErrInvalidTrash, dir)
+	}
+	return dir, nil
+}
+
+// topDir returns the mount point containing path.
+func (t *Trash) topDir(path string) (string, error) {
+	if t.mounts == nil {
+		return mountPoint(path)
+	}
+
+	best := ""
+	for _, m := range t.mounts {
+		if (path == m || strings.HasPrefix(path, m+string(filepath.Separator))) && len(m) > len(best) {
+			best = m
+		}
+	}
+	if best == "" {
+		return "", fmt.Errorf("%w: %s", ErrNoMountPoint, path)
+	}
+	return best, nil
+}
+
+// reserve atomically creates the .trashinfo file for a trashed item, adding a
+// numeric suffix to the name until it is unique within the trash.
+func (t *Trash) reserve(dir, base string) (string, *os.File, error) {
+	ext := filepath.Ext(base)
+	stem := strings.TrimSuffix(base, ext)
+
+	for i := 1; ; i++ {
+		name := base
+		if i > 1 {
+			name = fmt.Sprintf("%s.%d%s", stem, i, ext)
+		}
+
+		if _, err := os.Lstat(filepath.Join(dir, filesDir, name)); err == nil {
+			continue
+		}
+
+		f, err := os.OpenFile(filepath.Join(dir, infoDir, name+infoExtension),
+			os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+		if err == nil {
+			return name, f, nil
+		}
+		if !os.IsExist(err) {
+			return "", nil, err
+		}
+	}
+}
+
+// escapePath percent-encodes path as required for the Path key, keeping the
+// separators readable.
+func escapePath(path string) string {
+	segments := strings.Split(filepath.ToSlash(path), "/")
+	for i, s := range segments {
+		segments[i] = url.PathEscape(s)
+	}
+	return strings.Join(segments, "/")
+}
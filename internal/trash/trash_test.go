@@ -0,0 +1,250 @@
+package trash_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/d6o/Gorganizer/internal/testutil"
+	"github.com/d6o/Gorganizer/internal/trash"
+)
+
+var deletedAt = time.Date(2026, 10, 1, 9, 31, 0, 0, time.Local)
+
+func clock() time.Time {
+	return deletedAt
+}
+
+func readInfo(t *testing.T, path string) string {
+	t.Helper()
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestPut_HomeTrash(t *testing.T) {
+	t.Parallel()
+	root := t.TempDir()
+	home := filepath.Join(root, "home", ".local", "share", "Trash")
+	docs := filepath.Join(root, "docs")
+	if err := os.Mkdir(docs, 0o700); err != nil {
+		t.Fatal(err)
+	}
+
+	tr, err := trash.New(trash.WithHomeTrash(home), trash.WithMountPoints(root), trash.WithClock(clock))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	file := testutil.WriteFile(t, docs, "my report.pdf", "my report.pdf")
+	dest, err := tr.Put(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if dest != filepath.Join(home, "files", "my report.pdf") {
+		t.Errorf("Put() = %q, want file in home trash", dest)
+	}
+	if _, err := os.Stat(file); !os.IsNotExist(err) {
+		t.Error("original file should be gone")
+	}
+
+	info := readInfo(t, filepath.Join(home, "info", "my report.pdf.trashinfo"))
+	want := "[Trash Info]\nPath=" + filepath.ToSlash(docs) + "/my%20report.pdf\nDeletionDate=2026-10-01T09:31:00\n"
+	if info != want {
+		t.Errorf("trashinfo = %q, want %q", info, want)
+	}
+}
+
+func TestPut_NameCollision(t *testing.T) {
+	t.Parallel()
+	root := t.TempDir()
+	home := filepath.Join(root, "Trash")
+
+	tr, err := trash.New(trash.WithHomeTrash(home), trash.WithMountPoints(root))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, err := tr.Put(testutil.WriteFile(t, root, "a.txt", "a.txt")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, name := range []string{"a.txt", "a.2.txt", "a.3.txt"} {
+		if _, err := os.Stat(filepath.Join(home, "files", name)); err != nil {
+			t.Errorf("expected %s in trash: %v", name, err)
+		}
+		if _, err := os.Stat(filepath.Join(home, "info", name+".trashinfo")); err != nil {
+			t.Errorf("expected %s.trashinfo: %v", name, err)
+		}
+	}
+}
+
+func TestPut_TopDirTrash(t *testing.T) {
+	t.Parallel()
+	homeMount := t.TempDir()
+	otherMount := t.TempDir()
+	home := filepath.Join(homeMount, "Trash")
+
+	tr, err := trash.New(
+		trash.WithHomeTrash(home),
+		trash.WithMountPoints(homeMount, otherMount),
+		trash.WithUID(1000),
+		trash.WithClock(clock),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sub := filepath.Join(otherMount, "scans")
+	if err := os.Mkdir(sub, 0o700); err != nil {
+		t.Fatal(err)
+	}
+
+	dest, err := tr.Put(testutil.WriteFile(t, sub, "scan.pdf", "scan.pdf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := filepath.Join(otherMount, ".Trash-1000")
+	if dest != filepath.Join(dir, "files", "scan.pdf") {
+		t.Errorf("Put() = %q, want file in %s", dest, dir)
+	}
+
+	info := readInfo(t, filepath.Join(dir, "info", "scan.pdf.trashinfo"))
+	if !strings.Contains(info, "\nPath=scans/scan.pdf\n") {
+		t.Errorf("trashinfo should hold a path relative to the mount, got %q", info)
+	}
+}
+
+func TestPut_SharedTopDirTrash(t *testing.T) {
+	t.Parallel()
+	homeMount := t.TempDir()
+	otherMount := t.TempDir()
+
+	shared := filepath.Join(otherMount, ".Trash")
+	if err := os.Mkdir(shared, 0o777); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(shared, 0o777|os.ModeSticky); err != nil {
+		t.Fatal(err)
+	}
+
+	tr, err := trash.New(
+		trash.WithHomeTrash(filepath.Join(homeMount, "Trash")),
+		trash.WithMountPoints(homeMount, otherMount),
+		trash.WithUID(1000),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dest, err := tr.Put(testutil.WriteFile(t, otherMount, "a.txt", "a.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if dest != filepath.Join(shared, "1000", "files", "a.txt") {
+		t.Errorf("Put() = %q, want file in shared .Trash", dest)
+	}
+}
+
+func TestPut_SharedTopDirTrashWithoutStickyBit(t *testing.T) {
+	t.Parallel()
+	homeMount := t.TempDir()
+	otherMount := t.TempDir()
+
+	if err := os.Mkdir(filepath.Join(otherMount, ".Trash"), 0o777); err != nil {
+		t.Fatal(err)
+	}
+
+	tr, err := trash.New(
+		trash.WithHomeTrash(filepath.Join(homeMount, "Trash")),
+		trash.WithMountPoints(homeMount, otherMount),
+		trash.WithUID(1000),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dest, err := tr.Put(testutil.WriteFile(t, otherMount, "a.txt", "a.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if dest != filepath.Join(otherMount, ".Trash-1000", "files", "a.txt") {
+		t.Errorf("Put() = %q, want file in .Trash-1000", dest)
+	}
+}
+
+func TestPut_UnusableTopDirFallsBackToHome(t *testing.T) {
+	t.Parallel()
+	homeMount := t.TempDir()
+	otherMount := t.TempDir()
+	home := filepath.Join(homeMount, "Trash")
+
+	// A file in the way of .Trash-1000 makes the mount's trash unusable.
+	testutil.WriteFile(t, otherMount, ".Trash-1000", ".Trash-1000")
+
+	tr, err := trash.New(
+		trash.WithHomeTrash(home),
+		trash.WithMountPoints(homeMount, otherMount),
+		trash.WithUID(1000),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	file := testutil.WriteFile(t, otherMount, "a.txt", "a.txt")
+	dest, err := tr.Put(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if dest != filepath.Join(home, "files", "a.txt") {
+		t.Errorf("Put() = %q, want file in home trash", dest)
+	}
+	if _, err := os.Stat(file); !os.IsNotExist(err) {
+		t.Error("original file should be gone")
+	}
+	info := readInfo(t, filepath.Join(home, "info", "a.txt.trashinfo"))
+	if !strings.Contains(info, "\nPath="+filepath.ToSlash(file)+"\n") {
+		t.Errorf("trashinfo should hold the absolute path, got %q", info)
+	}
+}
+
+func TestPut_MissingFile(t *testing.T) {
+	t.Parallel()
+	root := t.TempDir()
+
+	tr, err := trash.New(trash.WithHomeTrash(filepath.Join(root, "Trash")), trash.WithMountPoints(root))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := tr.Put(filepath.Join(root, "missing")); !os.IsNotExist(err) {
+		t.Errorf("Put(missing) error = %v, want not exist", err)
+	}
+}
+
+func TestPut_NoMountPoint(t *testing.T) {
+	t.Parallel()
+	root := t.TempDir()
+	other := t.TempDir()
+
+	tr, err := trash.New(trash.WithHomeTrash(filepath.Join(root, "Trash")), trash.WithMountPoints(root))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := tr.Put(testutil.WriteFile(t, other, "a.txt", "a.txt")); !errors.Is(err, trash.ErrNoMountPoint) {
+		t.Errorf("Put() error = %v, want ErrNoMountPoint", err)
+	}
+}
@@ -0,0 +1,49 @@
+package tui
+
+import (
+	"os"
+
+	"golang.org/x/term"
+)
+
+// Console is a Terminal backed by the process's controlling terminal, put in
+// raw mode for the lifetime of the Console.
+type Console struct {
+	in    *os.File
+	out   *os.File
+	state *term.State
+}
+
+// OpenConsole switches in to raw mode and returns a Console reading from in
+// and drawing on out. It returns ErrNotTerminal if in is not a terminal.
+func OpenConsole(in, out *os.File) (*Console, error) {
+	fd := int(in.Fd())
+	if !term.IsTerminal(fd) {
+		return nil, ErrNotTerminal
+	}
+
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Console{in: in, out: out, state: state}, nil
+}
+
+func (c *Console) Read(p []byte) (int, error) {
+	return c.in.Read(p)
+}
+
+func (c *Console) Write(p []byte) (int, error) {
+	return c.out.Write(p)
+}
+
+// Size returns the size of the terminal in columns and rows.
+func (c *Console) Size() (width, height int, err error) {
+	return term.GetSize(int(c.out.Fd()))
+}
+
+// Close restores the terminal to the mode it was in before OpenConsole.
+func (c *Console) Close() error {
+	return term.Restore(int(c.in.Fd()), c.state)
+}
@@ -0,0 +1,7 @@
+package tui
+
+import "errors"
+
+// ErrNotTerminal is returned when the editor is started without a terminal
+// attached to standard input.
+var ErrNotTerminal = errors.New("standard input is not a terminal")
@@ -0,0 +1,67 @@
+package tui
+
+import (
+	"bufio"
+	"unicode"
+)
+
+const (
+	keyCtrlC     = 0x03
+	keyBackspace = 0x7f
+	keyEnter     = '\r'
+	keyEscape    = 0x1b
+
+	// Arrow keys have no single byte; they are given values outside the
+	// Unicode range.
+	keyUp = unicode.MaxRune + 1 + iota
+	keyDown
+	keyRight
+	keyLeft
+)
+
+type key struct {
+	r rune
+}
+
+func (k key) is(r rune) bool {
+	switch r {
+	case keyEnter:
+		return k.r == '\r' || k.r == '\n'
+	case keyBackspace:
+		return k.r == keyBackspace || k.r == '\b'
+	}
+	return k.r == r
+}
+
+func (k key) printable() bool {
+	return k.r <= unicode.MaxRune && unicode.IsPrint(k.r)
+}
+
+// readKey reads one key press. Escape sequences for the arrow keys arrive in
+// a single read from a real terminal, so an escape byte with nothing buffered
+// after it is the Escape key itself.
+func readKey(in *bufio.Reader) (key, error) {
+	r, _, err := in.ReadRune()
+	if err != nil {
+		return key{}, err
+	}
+	if r != keyEscape || in.Buffered() < 2 {
+		return key{r: r}, nil
+	}
+
+	next, err := in.Peek(2)
+	if err != nil || (next[0] != '[' && next[0] != 'O') {
+		return key{r: r}, nil
+	}
+
+	arrows := map[byte]rune{'A': keyUp, 'B': keyDown, 'C': keyRight, 'D': keyLeft}
+	arrow, ok := arrows[next[1]]
+	if !ok {
+		return key{r: r}, nil
+	}
+
+	if _, err := in.Discard(2); err != nil {
+		return key{}, err
+	}
+	return key{r: arrow}, nil
+}
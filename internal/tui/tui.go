@@ -0,0 +1,397 @@
+// Package tui implements a full-screen terminal editor for reviewing an
+// organize run before it is executed. It lists every file with its planned
+// destination and lets the user send a file to another rules folder, exclude
+// it, or add a new rule, using only ANSI escape sequences so it works in any
+// plain terminal.
+package tui
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/d6o/Gorganizer/pkg/organizer"
+	"github.com/d6o/Gorganizer/pkg/store"
+)
+
+// Terminal is the screen the editor draws on and reads keys from. Input is
+// expected in raw mode, one key press at a time.
+type Terminal interface {
+	io.Reader
+	io.Writer
+	Size() (width, height int, err error)
+}
+
+// Rules is the rule set the editor offers folders from and adds rules to.
+// *store.Store satisfies it.
+type Rules interface {
+	Rules() []store.Rule
+	Lookup(ext string) string
+	InsertRule(rule string) error
+}
+
+const (
+	escClear      = "\x1b[H\x1b[2J"
+	escReverse    = "\x1b[7m"
+	escReset      = "\x1b[0m"
+	escHideCursor = "\x1b[?25l"
+	escShowCursor = "\x1b[?25h"
+	escAltScreen  = "\x1b[?1049h"
+	escMainScreen = "\x1b[?1049l"
+
+	helpLine = "[↑/↓] move  [t] retarget  [x] exclude  [r] new rule  [a] apply  [q] quit"
+)
+
+// Editor is an interactive review of the actions of a preview result.
+type Editor struct {
+	term     Terminal
+	in       *bufio.Reader
+	rules    Rules
+	actions  []organizer.FileAction
+	original []organizer.FileAction
+	cursor   int
+	status   string
+}
+
+// NewEditor creates an Editor for the actions of result. The result is not
+// modified; edits are made on a copy returned by Run.
+func NewEditor(term Terminal, rules Rules, result *organizer.OrganizeResult) *Editor {
+	actions := make([]organizer.FileAction, len(result.Actions))
+	copy(actions, result.Actions)
+	original := make([]organizer.FileAction, len(result.Actions))
+	copy(original, result.Actions)
+
+	return &Editor{
+		term:     term,
+		in:       bufio.NewReader(term),
+		rules:    rules,
+		actions:  actions,
+		original: original,
+	}
+}
+
+// Run shows the editor until the user applies or quits. It returns the
+// edited actions and whether the user chose to apply them.
+func (e *Editor) Run() (actions []organizer.FileAction, apply bool, err error) {
+	fmt.Fprint(e.term, escAltScreen+escHideCursor)
+	defer fmt.Fprint(e.term, escShowCursor+escMainScreen)
+
+	for {
+		if err := e.draw(); err != nil {
+			return nil, false, err
+		}
+
+		k, err := readKey(e.in)
+		if err != nil {
+			if err == io.EOF {
+				return e.actions, false, nil
+			}
+			return nil, false, err
+		}
+
+		switch {
+		case k.is(keyUp) || k.is('k'):
+			e.move(-1)
+		case k.is(keyDown) || k.is('j'):
+			e.move(1)
+		case k.is('x'):
+			e.toggleExclude()
+		case k.is('t'):
+			if err := e.retarget(); err != nil {
+				return nil, false, err
+			}
+		case k.is('r'):
+			if err := e.newRule(); err != nil {
+				return nil, false, err
+			}
+		case k.is('a'):
+			return e.actions, true, nil
+		case k.is('q'), k.is(keyEscape), k.is(keyCtrlC):
+			return e.actions, false, nil
+		}
+	}
+}
+
+func (e *Editor) move(delta int) {
+	e.cursor += delta
+	if e.cursor < 0 {
+		e.cursor = 0
+	}
+	if e.cursor >= len(e.actions) {
+		e.cursor = len(e.actions) - 1
+	}
+	e.status = ""
+}
+
+func (e *Editor) selected() *organizer.FileAction {
+	if e.cursor < 0 || e.cursor >= len(e.actions) {
+		return nil
+	}
+	return &e.actions[e.cursor]
+}
+
+// toggleExclude excludes the selected file, or restores its original action
+// if it is already excluded.
+func (e *Editor) toggleExclude() {
+	a := e.selected()
+	if a == nil {
+		return
+	}
+
+	if a.Reason == organizer.ReasonExcluded {
+		*a = e.original[e.cursor]
+		if a.Reason == organizer.ReasonExcluded {
+			a.Reason = organizer.ReasonUnknownExtension
+		}
+		e.status = "Restored " + a.FileName
+		return
+	}
+
+	a.Reason = organizer.ReasonExcluded
+	a.Destination = ""
+	e.status = "Excluded " + a.FileName
+}
+
+// retarget lets the user pick one of the rules folders for the selected file.
+func (e *Editor) retarget() error {
+	a := e.selected()
+	if a == nil {
+		return nil
+	}
+
+	folder, ok, err := e.choose("Move "+a.FileName+" to:", e.folders())
+	if err != nil || !ok {
+		return err
+	}
+
+	a.Reason = organizer.ReasonOrganized
+	a.Destination = folder
+	e.status = a.FileName + " → " + folder
+	return nil
+}
+
+// newRule asks for an ext[,ext...]:folder rule, stores it and sends every
+// file with one of those extensions to the folder the rule was stored with.
+func (e *Editor) newRule() error {
+	prefill := ""
+	if a := e.selected(); a != nil {
+		prefill = extension(a.FileName) + ":"
+	}
+
+	rule, ok, err := e.prompt("New rule (ext:folder): ", prefill)
+	if err != nil || !ok {
+		return err
+	}
+
+	exts, _, err := store.ParseRule(rule)
+	if err == nil {
+		err = e.rules.InsertRule(rule)
+	}
+	if err != nil {
+		e.status = "Invalid rule: " + err.Error()
+		return nil
+	}
+
+	for i := range e.actions {
+		a := &e.actions[i]
+		if a.Reason != organizer.ReasonOrganized && a.Reason != organizer.ReasonUnknownExtension {
+			continue
+		}
+		ext := extension(a.FileName)
+		if !slices.ContainsFunc(exts, func(x string) bool { return strings.EqualFold(strings.TrimSpace(x), ext) }) {
+			continue
+		}
+		if folder := e.rules.Lookup(ext); folder != "" {
+			a.Reason = organizer.ReasonOrganized
+			a.Destination = folder
+		}
+	}
+
+	e.status = "Added rule " + rule
+	return nil
+}
+
+// folders returns the distinct rules folders, sorted.
+func (e *Editor) folders() []string {
+	seen := make(map[string]bool)
+	var folders []string
+	for _, r := range e.rules.Rules() {
+		if !seen[r.Folder] {
+			seen[r.Folder] = true
+			folders = append(folders, r.Folder)
+		}
+	}
+	sort.Strings(folders)
+	return folders
+}
+
+// choose shows a list of options and returns the one picked with Enter.
+func (e *Editor) choose(title string, options []string) (string, bool, error) {
+	if len(options) == 0 {
+		e.status = "No rules folders to choose from"
+		return "", false, nil
+	}
+
+	cursor := 0
+	for {
+		_, height, err := e.term.Size()
+		if err != nil {
+			return "", false, err
+		}
+
+		var b strings.Builder
+		b.WriteString(escClear)
+		b.WriteString(title + "\r\n\r\n")
+		top, rows := window(cursor, len(options), height-4)
+		for i := top; i < top+rows; i++ {
+			line := "  " + options[i]
+			if i == cursor {
+				line = escReverse + "> " + options[i] + escReset
+			}
+			b.WriteString(line + "\r\n")
+		}
+		b.WriteString("\r\n[↑/↓] move  [enter] choose  [esc] cancel")
+		fmt.Fprint(e.term, b.String())
+
+		k, err := readKey(e.in)
+		if err != nil {
+			if err == io.EOF {
+				return "", false, nil
+			}
+			return "", false, err
+		}
+
+		switch {
+		case k.is(keyUp) || k.is('k'):
+			if cursor > 0 {
+				cursor--
+			}
+		case k.is(keyDown) || k.is('j'):
+			if cursor < len(options)-1 {
+				cursor++
+			}
+		case k.is(keyEnter):
+			return options[cursor], true, nil
+		case k.is(keyEscape), k.is('q'), k.is(keyCtrlC):
+			return "", false, nil
+		}
+	}
+}
+
+// prompt reads a line of text on the status line.
+func (e *Editor) prompt(label, value string) (string, bool, error) {
+	buf := []rune(value)
+	for {
+		e.status = label + string(buf) + "_"
+		if err := e.draw(); err != nil {
+			return "", false, err
+		}
+
+		k, err := readKey(e.in)
+		if err != nil {
+			if err == io.EOF {
+				return "", false, nil
+			}
+			return "", false, err
+		}
+
+		switch {
+		case k.is(keyEnter):
+			e.status = ""
+			return string(buf), true, nil
+		case k.is(keyEscape), k.is(keyCtrlC):
+			e.status = ""
+			return "", false, nil
+		case k.is(keyBackspace):
+			if len(buf) > 0 {
+				buf = buf[:len(buf)-1]
+			}
+		case k.printable():
+			buf = append(buf, k.r)
+		}
+	}
+}
+
+func (e *Editor) draw() error {
+	width, height, err := e.term.Size()
+	if err != nil {
+		return err
+	}
+
+	var b strings.Builder
+	b.WriteString(escClear)
+	b.WriteString(truncate(fmt.Sprintf("Gorganizer: %d files", len(e.actions)), width) + "\r\n")
+
+	top, rows := window(e.cursor, len(e.actions), height-3)
+	for i := top; i < top+rows; i++ {
+		line := truncate(describe(e.actions[i]), width-2)
+		if i == e.cursor {
+			b.WriteString(escReverse + "> " + line + escReset + "\r\n")
+		} else {
+			b.WriteString("  " + line + "\r\n")
+		}
+	}
+	for i := rows; i < height-3; i++ {
+		b.WriteString("\r\n")
+	}
+
+	b.WriteString(truncate(e.status, width) + "\r\n")
+	b.WriteString(truncate(helpLine, width))
+
+	_, err = fmt.Fprint(e.term, b.String())
+	return err
+}
+
+// describe renders one action as a row of the list.
+func describe(a organizer.FileAction) string {
+	switch a.Reason {
+	case organizer.ReasonOrganized:
+		if a.NewName != "" {
+			return a.FileName + " → " + filepath.Join(a.Destination, a.NewName)
+		}
+		return a.FileName + " → " + a.Destination
+	case organizer.ReasonDuplicate:
+		return a.FileName + " (duplicate of " + filepath.Base(a.DuplicateOf) + ")"
+	case organizer.ReasonExcluded:
+		return a.FileName + " (excluded)"
+	case organizer.ReasonHidden:
+		return a.FileName + " (hidden)"
+	default:
+		return a.FileName + " (no rule)"
+	}
+}
+
+// window returns the first row and number of rows to show so that cursor is
+// visible in a list of n rows on a screen of height rows.
+func window(cursor, n, height int) (top, rows int) {
+	if height < 1 {
+		height = 1
+	}
+	if n <= height {
+		return 0, n
+	}
+	top = cursor - height/2
+	if top < 0 {
+		top = 0
+	}
+	if top > n-height {
+		top = n - height
+	}
+	return top, height
+}
+
+func truncate(s string, width int) string {
+	r := []rune(s)
+	if width <= 0 || len(r) <= width {
+		return s
+	}
+	return string(r[:width])
+}
+
+func extension(name string) string {
+	return strings.TrimPrefix(filepath.Ext(name), ".")
+}
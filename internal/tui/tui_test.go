@@ -0,0 +1,232 @@
+package tui_test
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/d6o/Gorganizer/internal/tui"
+	"github.com/d6o/Gorganizer/pkg/organizer"
+	"github.com/d6o/Gorganizer/pkg/store"
+)
+
+// fakeTerminal replays scripted key presses and records everything drawn.
+type fakeTerminal struct {
+	keys   *strings.Reader
+	screen bytes.Buffer
+}
+
+func newFakeTerminal(keys string) *fakeTerminal {
+	return &fakeTerminal{keys: strings.NewReader(keys)}
+}
+
+func (f *fakeTerminal) Read(p []byte) (int, error) {
+	// Deliver one key press per read, like a terminal in raw mode. Escape
+	// sequences are delivered whole.
+	if f.keys.Len() == 0 {
+		return f.keys.Read(p)
+	}
+	n := 1
+	rest := f.keys.Len()
+	if b, _ := f.keys.ReadByte(); b == 0x1b && rest >= 3 {
+		n = 3
+	}
+	if err := f.keys.UnreadByte(); err != nil {
+		return 0, err
+	}
+	return f.keys.Read(p[:n])
+}
+
+func (f *fakeTerminal) Write(p []byte) (int, error) {
+	return f.screen.Write(p)
+}
+
+func (f *fakeTerminal) Size() (width, height int, err error) {
+	return 80, 24, nil
+}
+
+type fakeRules struct {
+	rules    []store.Rule
+	inserted []string
+}
+
+func (f *fakeRules) Rules() []store.Rule {
+	return f.rules
+}
+
+func (f *fakeRules) Lookup(ext string) string {
+	for _, r := range f.rules {
+		if r.Extension == strings.ToLower(ext) {
+			return r.Folder
+		}
+	}
+	return ""
+}
+
+// InsertRule stores rules like store.Store, lowercasing extensions and
+// capitalizing folders.
+func (f *fakeRules) InsertRule(rule string) error {
+	exts, folder, err := store.ParseRule(rule)
+	if err != nil {
+		return err
+	}
+	folder = strings.ToUpper(folder[:1]) + folder[1:]
+	for _, ext := range exts {
+		f.rules = slices.DeleteFunc(f.rules, func(r store.Rule) bool { return r.Extension == strings.ToLower(ext) })
+		f.rules = append(f.rules, store.Rule{Extension: strings.ToLower(ext), Folder: folder})
+	}
+	f.inserted = append(f.inserted, rule)
+	return nil
+}
+
+func newRules() *fakeRules {
+	return &fakeRules{rules: []store.Rule{
+		{Extension: "mp3", Folder: "Music"},
+		{Extension: "pdf", Folder: "Documents"},
+		{Extension: "jpg", Folder: "Pictures"},
+	}}
+}
+
+func testResult() *organizer.OrganizeResult {
+	return &organizer.OrganizeResult{Actions: []organizer.FileAction{
+		{FileName: "song.mp3", Destination: "Music", Reason: organizer.ReasonOrganized},
+		{FileName: "notes.pdf", Destination: "Documents", Reason: organizer.ReasonOrganized},
+		{FileName: "photo.heic", Reason: organizer.ReasonUnknownExtension},
+		{FileName: "other.heic", Reason: organizer.ReasonUnknownExtension},
+	}}
+}
+
+const (
+	down  = "\x1b[B"
+	up    = "\x1b[A"
+	enter = "\r"
+)
+
+func run(t *testing.T, keys string, rules *fakeRules) ([]organizer.FileAction, bool, *fakeTerminal) {
+	t.Helper()
+	term := newFakeTerminal(keys)
+	actions, apply, err := tui.NewEditor(term, rules, testResult()).Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return actions, apply, term
+}
+
+func TestEditor_ListsFiles(t *testing.T) {
+	t.Parallel()
+	_, apply, term := run(t, "q", newRules())
+
+	if apply {
+		t.Error("quitting should not apply")
+	}
+	for _, want := range []string{"song.mp3 → Music", "notes.pdf → Documents", "photo.heic (no rule)"} {
+		if !strings.Contains(term.screen.String(), want) {
+			t.Errorf("screen missing %q", want)
+		}
+	}
+}
+
+func TestEditor_Exclude(t *testing.T) {
+	t.Parallel()
+	actions, apply, _ := run(t, "jxa", newRules())
+
+	if !apply {
+		t.Fatal("expected apply")
+	}
+	if actions[1].Reason != organizer.ReasonExcluded {
+		t.Errorf("notes.pdf reason = %s, want excluded", actions[1].Reason)
+	}
+	if actions[0].Reason != organizer.ReasonOrganized {
+		t.Errorf("song.mp3 should be untouched, got %s", actions[0].Reason)
+	}
+}
+
+func TestEditor_ExcludeToggle(t *testing.T) {
+	t.Parallel()
+	actions, _, _ := run(t, "xxa", newRules())
+
+	if actions[0].Reason != organizer.ReasonOrganized || actions[0].Destination != "Music" {
+		t.Errorf("song.mp3 should be restored, got %+v", actions[0])
+	}
+}
+
+func TestEditor_Retarget(t *testing.T) {
+	t.Parallel()
+	// Folders are offered sorted: Documents, Music, Pictures.
+	actions, _, _ := run(t, down+down+"t"+down+down+enter+"a", newRules())
+
+	if actions[2].Reason != organizer.ReasonOrganized || actions[2].Destination != "Pictures" {
+		t.Errorf("photo.heic = %+v, want organized into Pictures", actions[2])
+	}
+	if actions[3].Reason != organizer.ReasonUnknownExtension {
+		t.Error("retarget should only change the selected file")
+	}
+}
+
+func TestEditor_RetargetCancel(t *testing.T) {
+	t.Parallel()
+	actions, _, _ := run(t, "t"+down+"\x1ba", newRules())
+
+	if actions[0].Destination != "Music" {
+		t.Errorf("cancelled retarget changed destination to %q", actions[0].Destination)
+	}
+}
+
+func TestEditor_NewRule(t *testing.T) {
+	t.Parallel()
+	rules := newRules()
+	actions, _, _ := run(t, down+down+"rPhotos"+enter+"a", rules)
+
+	if len(rules.inserted) != 1 || rules.inserted[0] != "heic:Photos" {
+		t.Fatalf("inserted rules = %v, want [heic:Photos]", rules.inserted)
+	}
+	for _, a := range actions[2:] {
+		if a.Reason != organizer.ReasonOrganized || a.Destination != "Photos" {
+			t.Errorf("%s = %+v, want organized into Photos", a.FileName, a)
+		}
+	}
+}
+
+func TestEditor_NewBulkRule(t *testing.T) {
+	t.Parallel()
+	rules := newRules()
+	actions, _, _ := run(t, "r\x7f\x7f\x7f\x7fHEIC,mp3:photos"+enter+"a", rules)
+
+	if len(rules.inserted) != 1 || rules.inserted[0] != "HEIC,mp3:photos" {
+		t.Fatalf("inserted rules = %v, want [HEIC,mp3:photos]", rules.inserted)
+	}
+	for _, i := range []int{0, 2, 3} {
+		if a := actions[i]; a.Reason != organizer.ReasonOrganized || a.Destination != "Photos" {
+			t.Errorf("%s = %+v, want organized into Photos", a.FileName, a)
+		}
+	}
+	if actions[1].Destination != "Documents" {
+		t.Errorf("notes.pdf = %+v, want it left in Documents", actions[1])
+	}
+}
+
+func TestEditor_NewRuleInvalid(t *testing.T) {
+	t.Parallel()
+	rules := newRules()
+	_, _, term := run(t, "r\x7f\x7f\x7f\x7fbogus"+enter+"q", rules)
+
+	if len(rules.inserted) != 0 {
+		t.Errorf("invalid rule should not be inserted, got %v", rules.inserted)
+	}
+	if !strings.Contains(term.screen.String(), "Invalid rule") {
+		t.Error("expected an error on the status line")
+	}
+}
+
+func TestEditor_CursorBounds(t *testing.T) {
+	t.Parallel()
+	actions, _, _ := run(t, up+up+"x"+strings.Repeat(down, 10)+"xa", newRules())
+
+	if actions[0].Reason != organizer.ReasonExcluded {
+		t.Error("cursor should stop at the first file")
+	}
+	if actions[3].Reason != organizer.ReasonExcluded {
+		t.Error("cursor should stop at the last file")
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+)
+
+// logger reports problems and, with -v, what the organizer and the rules
+// store decide, on stderr. Results and messages meant for the user go to
+// stdout.
+var logger = newLogger(os.Stderr, slog.LevelWarn, "text")
+
+// quiet suppresses the messages printed with say.
+var quiet bool
+
+// logFlags are the verbosity flags every command accepts.
+type logFlags struct {
+	verbose bool
+	quiet   bool
+	format  string
+}
+
+func (l *logFlags) register(flags *flag.FlagSet) {
+	flags.BoolVar(&l.verbose, "v", false, "Log every decision on stderr")
+	flags.BoolVar(&l.quiet, "q", false, "Only print results and errors")
+	flags.StringVar(&l.format, "log-format", "text", "Format of the log on stderr: text|json")
+}
+
+// setup applies the flags to logger and quiet. It must be called once the
+// flags are parsed.
+func (l *logFlags) setup() error {
+	if l.format != "text" && l.format != "json" {
+		return fmt.Errorf("%w: %q", errUnknownLogFormat, l.format)
+	}
+
+	level := slog.LevelWarn
+	switch {
+	case l.quiet:
+		level = slog.LevelError
+	case l.verbose:
+		level = slog.LevelDebug
+	}
+
+	logger = newLogger(os.Stderr, level, l.format)
+	quiet = l.quiet
+	return nil
+}
+
+func newLogger(w *os.File, level slog.Level, format string) *slog.Logger {
+	opts := &slog.HandlerOptions{Level: level}
+	if format == "json" {
+		return slog.New(slog.NewJSONHandler(w, opts))
+	}
+	// The time is noise on a terminal.
+	opts.ReplaceAttr = func(groups []string, a slog.Attr) slog.Attr {
+		if len(groups) == 0 && a.Key == slog.TimeKey {
+			return slog.Attr{}
+		}
+		return a
+	}
+	return slog.New(slog.NewTextHandler(w, opts))
+}
+
+// say prints a progress message for the user on stdout, unless -q is set.
+func say(a ...any) {
+	if !quiet {
+		fmt.Println(a...)
+	}
+}
+
+// logClose closes c and logs the error, for deferred calls.
+func logClose(what string, c interface{ Close() error }) {
+	if err := c.Close(); err != nil {
+		logger.Error("closing "+what, "error", err)
+	}
+}
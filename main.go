@@ -1,13 +1,18 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"os"
+	"path/filepath"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/disiqueira/gotree"
 
+	"github.com/d6o/Gorganizer/internal/report"
 	"github.com/d6o/Gorganizer/pkg/organizer"
 	"github.com/d6o/Gorganizer/pkg/store"
 )
@@ -16,94 +21,385 @@ var version = "dev"
 
 func main() {
 	if err := run(); err != nil {
-		fmt.Println(err)
+		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
 }
 
 func run() error {
-	showVersion := flag.Bool("version", false, "Print version and exit")
-	outputFolder := flag.String("output", ".", "Main directory to put organized folders")
-	inputFolder := flag.String("directory", ".", "The directory whose files to classify")
-	newRule := flag.String("newrule", "", "Insert a new rule. Format ext:folder Example: mp3:Music")
-	delRule := flag.String("delrule", "", "Delete a rule. Format ext Example: mp3")
-	printRules := flag.Bool("allrules", false, "Print all rules")
-	preview := flag.Bool("preview", false, "Only preview, do not move files")
-	recursive := flag.Bool("recursive", false, "Search over all directories.")
-	ignoreHiddenFiles := flag.Bool("hidden", true, "Ignore hidden files")
-	excludeExtensions := flag.String("exclude", "", "Exclude files will ignore files for organizer. Format pdf,odt")
-	lang := flag.String("language", "en", "Specify language: en|tr|pt")
-
-	flag.Parse()
+	args := os.Args[1:]
+	if len(args) > 0 {
+		switch args[0] {
+		case "organize":
+			return runOrganize(args[1:])
+		case "apply":
+			return runApply(args[1:])
+		case "serve":
+			return runServe(args[1:])
+		case "daemon":
+			return runDaemon(args[1:])
+		case "profile":
+			return runProfile(args[1:])
+		case "rules":
+			return runRules(args[1:])
+		case "log":
+			return runLog(args[1:])
+		case "analyze":
+			return runAnalyze(args[1:])
+		}
+	}
+	return runOrganize(args)
+}
+
+func runOrganize(args []string) error {
+	flags := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	showVersion := flags.Bool("version", false, "Print version and exit")
+	outputFolder := flags.String("output", ".", "Main directory to put organized folders")
+	var inputs inputsFlag
+	flags.Var(&inputs, "directory", "The directory whose files to classify. Repeat for several, and bind one to a preset with dir=preset, writing = in dir as == (default \".\")")
+	presetsFile := flags.String("presets", defaultPresetsFile(), "JSON file of named presets for -directory dir=preset")
+	newRule := flags.String("newrule", "", "Insert a new rule. Format ext[,ext...]:folder Example: mp3,flac,ogg:Music")
+	delRule := flags.String("delrule", "", "Delete a rule. Format ext Example: mp3")
+	printRules := flags.Bool("allrules", false, "Print all rules")
+	preview := flags.Bool("preview", false, "Only preview, do not move files")
+	recursive := flags.Bool("recursive", false, "Search over all directories.")
+	ignoreHiddenFiles := flags.Bool("hidden", true, "Ignore hidden files")
+	excludeExtensions := flags.String("exclude", "", "Exclude files will ignore files for organizer. Format pdf,odt")
+	lang := flags.String("language", "en", "Specify language: en|tr|pt")
+	ruleProfile := flags.String("profile", "", "Rule profile to use instead of the default one")
+	permanent := flags.Bool("permanent", false, "Delete files permanently instead of moving them to the trash")
+	duplicates := flags.String("duplicates", "", "Detect duplicate files by content: report|skip|delete|move|hardlink")
+	archives := flags.String("archives", "", "Organize zip and tar archives by their content: off|inspect|extract")
+	archiveMaxSize := flags.Int64("archive-max-size", 1024, "Largest total size in MiB of an archive to extract")
+	normalize := flags.String("normalize", "", "Normalize names of moved files: all, or a list of url-decode,transliterate,safe,whitespace,nfc,lower-ext")
+	renameTemplate := flags.String("rename", "", "Rename moved files from a template of {name}, {date[:layout]} and {counter[:width]}, keeping the extension")
+	var audit auditFlags
+	audit.register(flags)
+	var hooks hookFlags
+	hooks.register(flags)
+	var logs logFlags
+	logs.register(flags)
+	summaryOnly := flags.Bool("summary", false, "Print a summary of the run instead of every file")
+	ruleUnknown := flags.String("rule-unknown", "", "Add a rule sending every extension without one to this folder before organizing")
+	reportFile := flags.String("report", "", "Write an HTML report of the run to this file")
+	planFile := flags.String("plan", "", "Write the planned operations to a JSON file instead of moving files")
+	var interactive interactiveFlag
+	editor := flags.Bool("tui", false, "Review and edit the run in a full-screen editor before moving files")
+	flags.Var(&interactive, "interactive", "Ask before moving, per destination folder or per file: -interactive[=folder|file]")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if err := logs.setup(); err != nil {
+		return err
+	}
 
 	if *showVersion {
 		fmt.Println(version)
 		return nil
 	}
 
-	s, err := store.NewStore(*lang, store.WithEventHandler(func(evt store.Event) {
-		switch evt {
-		case store.EventDatabaseNotFound:
-			fmt.Println("No database found")
-		case store.EventCreatingDefaults:
-			fmt.Println("Creating default database")
-		case store.EventDefaultsInitialized:
-			fmt.Println("Default database initialized")
-		}
-	}))
+	s, err := openStore(*lang, *ruleProfile)
 	if err != nil {
 		return err
 	}
-	defer func() {
-		if err := s.Close(); err != nil {
-			fmt.Println(err)
-		}
-	}()
+	defer logClose("rules", s)
 
 	if *newRule != "" {
-		fmt.Println("Creating new rule")
-		if err := s.InsertRule(*newRule); err != nil {
+		exts, folder, err := store.ParseRule(*newRule)
+		if err != nil {
 			return err
 		}
-		printRulesTree(s)
+		if *preview {
+			changes, err := s.AddRules(exts, folder, true)
+			if err != nil {
+				return err
+			}
+			printChanges(changes, true)
+			return nil
+		}
+		say("Creating new rule")
+		if _, err := s.AddRules(exts, folder, false); err != nil {
+			return err
+		}
+		printRulesTree(s, *outputFolder)
 		return nil
 	}
 
 	if *delRule != "" {
-		fmt.Println("Deleting rule")
-		s.DeleteRule(*delRule)
-		printRulesTree(s)
+		say("Deleting rule")
+		if err := s.DeleteRule(*delRule); err != nil {
+			return err
+		}
+		printRulesTree(s, *outputFolder)
 		return nil
 	}
 
 	if *printRules {
-		printRulesTree(s)
+		printRulesTree(s, *outputFolder)
 		return nil
 	}
 
+	duplicatePolicy, err := organizer.ParseDuplicatePolicy(*duplicates)
+	if err != nil {
+		return err
+	}
+	archivePolicy, err := organizer.ParseArchivePolicy(*archives)
+	if err != nil {
+		return err
+	}
+
+	renamer, err := newRenamer(*normalize, *renameTemplate)
+	if err != nil {
+		return err
+	}
+
+	if len(inputs) == 0 {
+		inputs = inputsFlag{{dir: "."}}
+	}
+
+	presets, err := readPresets(*presetsFile)
+	if err != nil {
+		return err
+	}
+
+	orgInputs, presetStores, err := buildInputs(inputs, presets, inputDefaults{
+		output:      *outputFolder,
+		language:    *lang,
+		ruleProfile: *ruleProfile,
+		recursive:   *recursive,
+		hidden:      *ignoreHiddenFiles,
+		exclude:     organizer.ExcludeList(strings.Split(*excludeExtensions, ",")),
+	})
+	defer func() {
+		for _, ps := range presetStores {
+			logClose("rules", ps)
+		}
+	}()
+	if err != nil {
+		return err
+	}
+
+	auditLog, err := audit.open()
+	if err != nil {
+		return err
+	}
+	if auditLog != nil {
+		defer logClose("audit log", auditLog)
+	}
+
 	org := organizer.NewOrganizer(s, organizer.Config{
-		InputFolder:       *inputFolder,
-		OutputFolder:      *outputFolder,
+		InputFolder:       orgInputs[0].Folder,
+		OutputFolder:      orgInputs[0].OutputFolder,
 		Preview:           *preview,
 		Recursive:         *recursive,
 		IgnoreHiddenFiles: *ignoreHiddenFiles,
 		ExcludeList:       organizer.ExcludeList(strings.Split(*excludeExtensions, ",")),
+		Duplicates:        duplicatePolicy,
+		Archives:          archivePolicy,
+		ArchiveLimits:     organizer.ArchiveLimits{MaxSize: *archiveMaxSize << 20},
+		Rename:            renamer,
+		Logger:            logger,
+		Audit:             auditLog,
+		Hooks:             hooks.list(),
+		Permanent:         *permanent,
+		Inputs:            orgInputs,
 	})
 
-	fmt.Println("GOrganizing your Files")
+	if *ruleUnknown != "" {
+		if err := addUnknownRules(org, s, orgInputs, *ruleUnknown, *preview); err != nil {
+			return err
+		}
+	}
+
+	if *planFile != "" {
+		return writePlan(org, *planFile)
+	}
+
+	say("GOrganizing your Files")
+
+	var result *organizer.OrganizeResult
+	switch {
+	case *editor && !*preview:
+		rules, rerr := editorRules(s, orgInputs)
+		if rerr != nil {
+			return rerr
+		}
+		result, err = runEditor(org, rules)
+	case interactive.enabled && !*preview:
+		result, err = runInteractive(org, interactive.granularity)
+	default:
+		result, err = org.Run()
+	}
+	if errors.Is(err, errCancelled) {
+		say("Nothing was changed")
+		return nil
+	}
+	// A failed run still reports the files it handled and the one it
+	// failed on.
+	if *reportFile != "" && result != nil {
+		if rerr := writeReport(*reportFile, result, err, reportRules(s, orgInputs), *preview); rerr != nil {
+			return errors.Join(err, rerr)
+		}
+		say("Report written to", *reportFile)
+	}
+	if err != nil {
+		return err
+	}
+
+	if !*summaryOnly {
+		printResultTree(result)
+		printDuplicatesTree(result)
+	}
+	printSummaryTree(result.Summary, *ruleUnknown == "")
+	if auditLog != nil && result.RunID != "" {
+		say("Run", result.RunID, "recorded in", audit.file)
+	}
+
+	say("All files have been GOrganized!")
+	return nil
+}
 
-	result, err := org.Run()
+// addUnknownRules sends the extensions of the files a run would leave alone
+// to folder. Each rule goes to the store that classifies the input the file
+// was found in: the rules of its preset, or s.
+func addUnknownRules(org *organizer.Organizer, s *store.Store, inputs []organizer.Input, folder string, preview bool) error {
+	result, err := org.Preview()
 	if err != nil {
 		return err
 	}
 
-	printResultTree(result)
+	var stores []*store.Store
+	exts := make(map[*store.Store][]string)
+	roots := make(map[*store.Store][]string)
+	for _, group := range result.ByRoot() {
+		target := s
+		for _, in := range inputs {
+			if rules, ok := in.Resolver.(*store.Store); ok && in.Folder == group.Root {
+				target = rules
+			}
+		}
+		// Extensions that cannot be written as a rule stay unknown.
+		found := slices.DeleteFunc((&organizer.OrganizeResult{Actions: group.Actions}).UnknownExtensions(), func(ext string) bool {
+			return strings.ContainsAny(ext, ":,")
+		})
+		if len(found) == 0 {
+			continue
+		}
+		if _, ok := exts[target]; !ok {
+			stores = append(stores, target)
+		}
+		exts[target] = append(exts[target], found...)
+		roots[target] = append(roots[target], group.Root)
+	}
+	if len(stores) == 0 {
+		say("No unknown extensions")
+		return nil
+	}
 
-	fmt.Println("All files have been GOrganized!")
+	for _, target := range stores {
+		if len(stores) > 1 {
+			say("Rules for", strings.Join(roots[target], ", "))
+		}
+		slices.Sort(exts[target])
+		changes, err := target.AddRules(slices.Compact(exts[target]), folder, preview)
+		if err != nil {
+			return err
+		}
+		printChanges(changes, preview)
+	}
 	return nil
 }
 
-func printRulesTree(s *store.Store) {
+// inputRules returns the store that classifies in: its preset's rules, or s
+// for inputs without them.
+func inputRules(s *store.Store, in organizer.Input) *store.Store {
+	if rules, ok := in.Resolver.(*store.Store); ok {
+		return rules
+	}
+	return s
+}
+
+// editorRules returns the store the editor adds rules to, which must
+// classify every input.
+func editorRules(s *store.Store, inputs []organizer.Input) (*store.Store, error) {
+	rules := inputRules(s, inputs[0])
+	for _, in := range inputs[1:] {
+		if inputRules(s, in) != rules {
+			return nil, errEditorRules
+		}
+	}
+	return rules, nil
+}
+
+// reportRules returns the rules that classify inputs: those of each input's
+// preset, and those of s for the inputs without one.
+func reportRules(s *store.Store, inputs []organizer.Input) []store.Rule {
+	var rules []store.Rule
+	seenStore := make(map[*store.Store]bool)
+	seenRule := make(map[store.Rule]bool)
+	for _, in := range inputs {
+		rs := inputRules(s, in)
+		if seenStore[rs] {
+			continue
+		}
+		seenStore[rs] = true
+		for _, r := range rs.Rules() {
+			if !seenRule[r] {
+				seenRule[r] = true
+				rules = append(rules, r)
+			}
+		}
+	}
+	return rules
+}
+
+// writeReport writes the HTML report of a run that used rules, which failed
+// with runErr when it is not nil.
+func writeReport(file string, result *organizer.OrganizeResult, runErr error, rules []store.Rule, preview bool) error {
+	r := report.Report{
+		Result:    result,
+		Rules:     rules,
+		Preview:   preview,
+		Generated: time.Now(),
+	}
+	if runErr != nil {
+		r.Error = runErr.Error()
+	}
+	return report.WriteFile(file, r)
+}
+
+// newRenamer returns the renamer for the -normalize and -rename flags, or
+// nil when neither is set.
+func newRenamer(normalize, template string) (*organizer.Renamer, error) {
+	if normalize == "" && template == "" {
+		return nil, nil
+	}
+	steps, err := organizer.ParseRenameSteps(normalize)
+	if err != nil {
+		return nil, err
+	}
+	return organizer.NewRenamer(steps, template)
+}
+
+func openStore(lang, profile string) (*store.Store, error) {
+	return store.NewStore(lang, store.WithProfile(profile), store.WithLogger(logger), store.WithEventHandler(func(evt store.Event) {
+		switch evt {
+		case store.EventDatabaseNotFound:
+			say("No database found")
+		case store.EventCreatingDefaults:
+			say("Creating default database")
+		case store.EventDefaultsInitialized:
+			say("Default database initialized")
+		case store.EventDatabaseMigrated:
+			say("Database upgraded to the current format, the old file was kept as a backup")
+		}
+	}))
+}
+
+// printRulesTree prints the rules by folder, with the directory every
+// folder resolves to when organizing into output.
+func printRulesTree(s *store.Store, output string) {
 	rules := s.Rules()
 	tree := gotree.New("Rules")
 	folders := make(map[string]gotree.Tree)
@@ -111,7 +407,11 @@ func printRulesTree(s *store.Store) {
 	for _, r := range rules {
 		ft, ok := folders[r.Folder]
 		if !ok {
-			ft = tree.Add(r.Folder)
+			dir := organizer.DestinationDir(output, r.Folder)
+			if abs, err := filepath.Abs(dir); err == nil {
+				dir = abs
+			}
+			ft = tree.Add(r.Folder + " → " + dir)
 			folders[r.Folder] = ft
 		}
 		ft.Add(r.Extension)
@@ -123,19 +423,87 @@ func printRulesTree(s *store.Store) {
 func printResultTree(result *organizer.OrganizeResult) {
 	tree := gotree.New("Files")
 
-	for _, a := range result.Actions {
-		var label string
-		switch a.Reason {
-		case organizer.ReasonHidden:
-			label = "Hidden Files"
-		case organizer.ReasonExcluded:
-			label = "Excluded Files"
-		case organizer.ReasonUnknownExtension:
-			label = "Unknown extension (will not be moved)"
-		case organizer.ReasonOrganized:
-			label = a.Destination
-		}
-		addToTree(tree, label, a.FileName)
+	groups := result.ByRoot()
+	if len(groups) <= 1 {
+		addActionsToTree(tree, result.Actions)
+	} else {
+		for _, g := range groups {
+			addActionsToTree(tree.Add(g.Root), g.Actions)
+		}
+	}
+
+	fmt.Println(tree.Print())
+}
+
+// printSummaryTree prints the totals of a run. With hint, it also tells how
+// to create rules for the unknown extensions.
+func printSummaryTree(summary *organizer.Summary, hint bool) {
+	if summary == nil {
+		return
+	}
+
+	tree := gotree.New(fmt.Sprintf("Summary: %s in %s", countLabel(summary.Files, summary.Bytes), summary.Elapsed.Round(time.Millisecond)))
+
+	addCounts := func(title string, counts []organizer.Count) gotree.Tree {
+		if len(counts) == 0 {
+			return nil
+		}
+		branch := tree.Add(title)
+		for _, c := range counts {
+			branch.Add(c.Name + ": " + countLabel(c.Files, c.Bytes))
+		}
+		return branch
+	}
+
+	addCounts("Folders", summary.Folders)
+	addCounts("Reasons", summary.Reasons)
+	unknown := addCounts("Unknown extensions", summary.UnknownExtensions)
+	if unknown != nil && hint {
+		unknown.Add("Add rules for them with -rule-unknown=FOLDER")
+	}
+
+	if len(summary.Largest) > 0 {
+		largest := tree.Add("Largest files")
+		for _, a := range summary.Largest {
+			label := a.FileName + ": " + report.FormatBytes(a.Size)
+			if a.Destination != "" {
+				label += " → " + a.Destination
+			}
+			largest.Add(label)
+		}
+	}
+
+	fmt.Println(tree.Print())
+}
+
+func countLabel(files int, bytes int64) string {
+	if files == 1 {
+		return "1 file, " + report.FormatBytes(bytes)
+	}
+	return fmt.Sprintf("%d files, %s", files, report.FormatBytes(bytes))
+}
+
+func addActionsToTree(tree gotree.Tree, actions []organizer.FileAction) {
+	for _, g := range report.Groups(actions) {
+		branch := tree.Add(g.Label)
+		for _, a := range g.Actions {
+			branch.Add(report.Name(a))
+		}
+	}
+}
+
+func printDuplicatesTree(result *organizer.OrganizeResult) {
+	if len(result.Duplicates) == 0 {
+		return
+	}
+
+	tree := gotree.New("Duplicates")
+
+	for _, g := range result.Duplicates {
+		group := tree.Add(g.Original)
+		for _, d := range g.Duplicates {
+			group.Add(d)
+		}
 	}
 
 	fmt.Println(tree.Print())
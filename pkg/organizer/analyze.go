@@ -0,0 +1,145 @@
+package organizer
+
+import (
+	"cmp"
+	"io/fs"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Suggestion is a rule learned from a directory tree that is already
+// organized: the folder most files with an extension live in.
+type Suggestion struct {
+	Extension string `json:"extension"`
+	Folder    string `json:"folder"`
+	// Files is how many of the Total files with the extension are in
+	// Folder, and Confidence their share.
+	Files      int     `json:"files"`
+	Total      int     `json:"total"`
+	Confidence float64 `json:"confidence"`
+	// Ambiguous is set when the confidence is below the analysis
+	// threshold, the files being spread across several folders.
+	Ambiguous bool `json:"ambiguous,omitempty"`
+	// Folders lists every folder holding files with the extension, the
+	// one with the most first, when there is more than one.
+	Folders []Count `json:"folders,omitempty"`
+}
+
+// AnalyzeOption configures Analyze.
+type AnalyzeOption func(*analysis)
+
+type analysis struct {
+	minFiles  int
+	threshold float64
+}
+
+// WithMinFiles sets how many files with an extension the tree must hold for
+// a rule to be suggested, 2 by default.
+func WithMinFiles(n int) AnalyzeOption {
+	return func(a *analysis) {
+		if n > 0 {
+			a.minFiles = n
+		}
+	}
+}
+
+// WithAmbiguityThreshold sets the confidence below which a suggestion is
+// ambiguous, 0.75 by default.
+func WithAmbiguityThreshold(threshold float64) AnalyzeOption {
+	return func(a *analysis) {
+		if threshold > 0 && threshold <= 1 {
+			a.threshold = threshold
+		}
+	}
+}
+
+// Analyze walks the tree under root and suggests a rule for every extension,
+// mapping it to the top-level folder of root that holds most of its files.
+// Files directly in root, hidden files and hidden folders are left out.
+// Suggestions are sorted by extension.
+func Analyze(root string, opts ...AnalyzeOption) ([]Suggestion, error) {
+	a := &analysis{minFiles: 2, threshold: 0.75}
+	for _, opt := range opts {
+		opt(a)
+	}
+
+	// Counts per extension and folder.
+	counts := make(map[string]map[string]*Count)
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == root {
+			return nil
+		}
+		if strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		folder, _, nested := strings.Cut(filepath.ToSlash(rel), "/")
+		ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(d.Name()), "."))
+		if !nested || ext == "" {
+			return nil
+		}
+
+		var size int64
+		if info, err := d.Info(); err == nil {
+			size = info.Size()
+		}
+		if counts[ext] == nil {
+			counts[ext] = make(map[string]*Count)
+		}
+		c, ok := counts[ext][folder]
+		if !ok {
+			c = &Count{Name: folder}
+			counts[ext][folder] = c
+		}
+		c.Files++
+		c.Bytes += size
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var suggestions []Suggestion
+	for ext, folders := range counts {
+		sorted := sortedCounts(folders, 0)
+		total := 0
+		for _, c := range sorted {
+			total += c.Files
+		}
+		if total < a.minFiles {
+			continue
+		}
+
+		s := Suggestion{
+			Extension:  ext,
+			Folder:     sorted[0].Name,
+			Files:      sorted[0].Files,
+			Total:      total,
+			Confidence: float64(sorted[0].Files) / float64(total),
+		}
+		s.Ambiguous = s.Confidence < a.threshold
+		if len(sorted) > 1 {
+			s.Folders = sorted
+		}
+		suggestions = append(suggestions, s)
+	}
+
+	slices.SortFunc(suggestions, func(a, b Suggestion) int {
+		return cmp.Compare(a.Extension, b.Extension)
+	})
+	return suggestions, nil
+}
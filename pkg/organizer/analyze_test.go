@@ -0,0 +1,100 @@
+package organizer_test
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/d6o/Gorganizer/internal/testutil"
+	"github.com/d6o/Gorganizer/pkg/organizer"
+)
+
+// createTestTree creates the files at the given slash separated paths
+// under a new directory.
+func createTestTree(t *testing.T, files ...string) string {
+	t.Helper()
+	root := t.TempDir()
+	for _, f := range files {
+		testutil.WriteFile(t, root, f, "")
+	}
+	return root
+}
+
+func TestAnalyze(t *testing.T) {
+	t.Parallel()
+	root := createTestTree(t,
+		"Music/rock/a.mp3", "Music/b.MP3", "Music/c.mp3",
+		"Docs/a.pdf", "Docs/b.pdf", "Docs/c.pdf", "Work/d.pdf", "Work/e.pdf",
+		"Music/cover.jpg",
+		"Docs/README",
+		"top.mp3", "top.txt", "Docs/.hidden.txt", ".git/objects/x.txt", "Work/.cache/y.txt",
+	)
+
+	suggestions, err := organizer.Analyze(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []organizer.Suggestion{
+		{Extension: "mp3", Folder: "Music", Files: 3, Total: 3, Confidence: 1},
+		{
+			Extension: "pdf", Folder: "Docs", Files: 3, Total: 5, Confidence: 0.6, Ambiguous: true,
+			Folders: []organizer.Count{{Name: "Docs", Files: 3}, {Name: "Work", Files: 2}},
+		},
+	}
+	if len(suggestions) != len(want) {
+		t.Fatalf("got %+v, want %+v", suggestions, want)
+	}
+	for i, got := range suggestions {
+		w := want[i]
+		if got.Extension != w.Extension || got.Folder != w.Folder || got.Files != w.Files ||
+			got.Total != w.Total || got.Confidence != w.Confidence || got.Ambiguous != w.Ambiguous ||
+			!slices.Equal(got.Folders, w.Folders) {
+			t.Errorf("suggestion %d = %+v, want %+v", i, got, w)
+		}
+	}
+}
+
+func TestAnalyze_Options(t *testing.T) {
+	t.Parallel()
+	root := createTestTree(t, "Pictures/a.jpg", "Docs/a.pdf", "Docs/b.pdf", "Work/c.pdf")
+
+	tests := []struct {
+		name string
+		opts []organizer.AnalyzeOption
+		want map[string]bool // extension to ambiguous
+	}{
+		{"defaults", nil, map[string]bool{"pdf": true}},
+		{"min files", []organizer.AnalyzeOption{organizer.WithMinFiles(1)}, map[string]bool{"jpg": false, "pdf": true}},
+		{"threshold", []organizer.AnalyzeOption{organizer.WithAmbiguityThreshold(0.5)}, map[string]bool{"pdf": false}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			suggestions, err := organizer.Analyze(root, tt.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := make(map[string]bool)
+			for _, s := range suggestions {
+				got[s.Extension] = s.Ambiguous
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			for ext, ambiguous := range tt.want {
+				if a, ok := got[ext]; !ok || a != ambiguous {
+					t.Errorf("%s: got ambiguous %v (found %v), want %v", ext, a, ok, ambiguous)
+				}
+			}
+		})
+	}
+}
+
+func TestAnalyze_MissingDirectory(t *testing.T) {
+	t.Parallel()
+	if _, err := organizer.Analyze(filepath.Join(t.TempDir(), "missing")); !os.IsNotExist(err) {
+		t.Errorf("Analyze() error = %v, want not exist", err)
+	}
+}
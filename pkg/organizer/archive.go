@@ -0,0 +1,365 @@
+package organizer
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/bzip2"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/ulikunitz/xz"
+)
+
+// ArchivePolicy controls whether archives are classified by their name or by
+// what they contain.
+type ArchivePolicy int
+
+const (
+	// ArchivesOff organizes archives by their extension, like other files.
+	ArchivesOff ArchivePolicy = iota
+	// ArchivesInspect lists the contents of zip and tar archives and
+	// organizes each archive into the folder most of its content belongs to.
+	ArchivesInspect
+	// ArchivesExtract classifies archives like ArchivesInspect, then extracts
+	// them into a folder named after the archive inside their destination
+	// and removes the archive.
+	ArchivesExtract
+)
+
+var archivePolicyNames = map[string]ArchivePolicy{
+	"":        ArchivesOff,
+	"off":     ArchivesOff,
+	"inspect": ArchivesInspect,
+	"extract": ArchivesExtract,
+}
+
+// ParseArchivePolicy converts a policy name (off, inspect or extract) to an
+// ArchivePolicy. Returns ErrUnknownArchivePolicy for any other value.
+func ParseArchivePolicy(name string) (ArchivePolicy, error) {
+	p, ok := archivePolicyNames[name]
+	if !ok {
+		return ArchivesOff, ErrUnknownArchivePolicy
+	}
+	return p, nil
+}
+
+// ArchiveLimits bound what an archive may contain to be extracted, as a
+// guard against zip bombs. Zero fields use the defaults.
+type ArchiveLimits struct {
+	// MaxFiles is the largest number of entries, 10000 by default.
+	MaxFiles int
+	// MaxSize is the largest total uncompressed size in bytes, 1 GiB by
+	// default.
+	MaxSize int64
+}
+
+const (
+	defaultArchiveMaxFiles = 10000
+	defaultArchiveMaxSize  = 1 << 30
+)
+
+func (l ArchiveLimits) maxFiles() int {
+	if l.MaxFiles > 0 {
+		return l.MaxFiles
+	}
+	return defaultArchiveMaxFiles
+}
+
+func (l ArchiveLimits) maxSize() int64 {
+	if l.MaxSize > 0 {
+		return l.MaxSize
+	}
+	return defaultArchiveMaxSize
+}
+
+// ArchiveInfo describes the contents of an inspected archive.
+type ArchiveInfo struct {
+	// Format is zip, tar, tar.gz, tar.bz2 or tar.xz.
+	Format string `json:"format"`
+	// Files and Size count the regular files in the archive and their
+	// uncompressed size.
+	Files int   `json:"files"`
+	Size  int64 `json:"size"`
+	// Folder is the destination most of the content, by size, resolves to.
+	// It is empty when no file in the archive matches a rule.
+	Folder string `json:"folder,omitempty"`
+	// Error explains why the archive could not be read or will not be
+	// extracted.
+	Error string `json:"error,omitempty"`
+
+	// ExtractedTo is the folder the archive was extracted into, and
+	// Extracted the files written there. The archive itself is removed.
+	ExtractedTo string   `json:"extracted_to,omitempty"`
+	Extracted   []string `json:"extracted,omitempty"`
+}
+
+// archiveFormats maps archive name suffixes to their format, longest first
+// so that "x.tar.gz" is not taken for a plain gzip file.
+var archiveFormats = []struct {
+	suffix string
+	format string
+}{
+	{".tar.gz", "tar.gz"},
+	{".tar.bz2", "tar.bz2"},
+	{".tar.xz", "tar.xz"},
+	{".tgz", "tar.gz"},
+	{".tbz2", "tar.bz2"},
+	{".tbz", "tar.bz2"},
+	{".txz", "tar.xz"},
+	{".tar", "tar"},
+	{".zip", "zip"},
+}
+
+// archiveFormat returns the format of the archive called name and the name
+// without its archive suffix, or "" if it is not an archive that can be
+// inspected.
+func archiveFormat(name string) (format, stem string) {
+	lower := strings.ToLower(name)
+	for _, f := range archiveFormats {
+		if strings.HasSuffix(lower, f.suffix) && len(name) > len(f.suffix) {
+			return f.format, name[:len(name)-len(f.suffix)]
+		}
+	}
+	return "", ""
+}
+
+// archiveEntry is a regular file inside an archive.
+type archiveEntry struct {
+	name string
+	size int64
+	mode fs.FileMode
+}
+
+// inspectArchive lists an archive and classifies it by the folder its
+// content resolves to. It returns nil for files that are not archives.
+func (o *Organizer) inspectArchive(file string, resolver Resolver) *ArchiveInfo {
+	format, _ := archiveFormat(filepath.Base(file))
+	if format == "" {
+		return nil
+	}
+	info := &ArchiveInfo{Format: format}
+
+	limits := o.config.ArchiveLimits
+	sizes := make(map[string]int64)
+	err := walkArchive(file, format, func(e archiveEntry, r io.Reader) error {
+		info.Files++
+		info.Size += e.size
+		if info.Files > limits.maxFiles() {
+			return fmt.Errorf("%w: more than %d files", ErrArchiveTooLarge, limits.maxFiles())
+		}
+		if info.Size > limits.maxSize() {
+			return fmt.Errorf("%w: more than %d bytes", ErrArchiveTooLarge, limits.maxSize())
+		}
+		if _, err := archiveTarget("", e.name); err != nil {
+			return err
+		}
+
+		// As in the scan, a file the resolver fails on is left out
+		// rather than failing the whole archive.
+		res, err := resolver.Resolve(&File{
+			Path: file + "/" + e.name,
+			Name: path.Base(e.name),
+			Size: e.size,
+			Mode: e.mode,
+			open: func() (io.ReadCloser, error) {
+				return io.NopCloser(r), nil
+			},
+		})
+		if err != nil {
+			o.log.Warn("cannot classify file", "path", file+"/"+e.name, "error", err)
+			return nil
+		}
+		if res.Destination != "" {
+			// Empty files still count towards their folder.
+			sizes[res.Destination] += e.size + 1
+		}
+		return nil
+	})
+	if err != nil {
+		info.Error = err.Error()
+	}
+
+	var best int64
+	for folder, size := range sizes {
+		if size > best || (size == best && folder < info.Folder) {
+			info.Folder, best = folder, size
+		}
+	}
+	return info
+}
+
+// walkArchive calls fn for every regular file in the archive, with a reader
+// of its content. Directories, links and other special entries are skipped.
+func walkArchive(file, format string, fn func(archiveEntry, io.Reader) error) error {
+	if format == "zip" {
+		zr, err := zip.OpenReader(file)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			_ = zr.Close()
+		}()
+
+		for _, f := range zr.File {
+			if !f.Mode().IsRegular() {
+				continue
+			}
+			if err := walkZipFile(f, fn); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	var r io.Reader = f
+	switch format {
+	case "tar.gz":
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return err
+		}
+		r = gz
+	case "tar.bz2":
+		r = bzip2.NewReader(f)
+	case "tar.xz":
+		xr, err := xz.NewReader(f)
+		if err != nil {
+			return err
+		}
+		r = xr
+	}
+
+	tr := tar.NewReader(r)
+	for {
+		h, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if h.Typeflag != tar.TypeReg {
+			continue
+		}
+		if err := fn(archiveEntry{name: h.Name, size: h.Size, mode: h.FileInfo().Mode()}, tr); err != nil {
+			return err
+		}
+	}
+}
+
+func walkZipFile(f *zip.File, fn func(archiveEntry, io.Reader) error) error {
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = rc.Close()
+	}()
+
+	return fn(archiveEntry{name: f.Name, size: int64(f.UncompressedSize64), mode: f.Mode()}, rc)
+}
+
+// archiveTarget returns where an entry is extracted inside dir. Names that
+// would escape dir, such as absolute paths or paths through "..", are
+// rejected.
+func archiveTarget(dir, name string) (string, error) {
+	name = filepath.FromSlash(strings.TrimPrefix(name, "./"))
+	if !filepath.IsLocal(name) {
+		return "", fmt.Errorf("%w: %q", ErrUnsafeArchivePath, name)
+	}
+	return filepath.Join(dir, name), nil
+}
+
+// extractArchive extracts the regular files of an archive into dir, which
+// must not exist yet, and returns their paths. Sizes are checked against
+// the limits as the content is read, since archive headers can lie. On
+// error, everything extracted so far is removed.
+func (o *Organizer) extractArchive(file, dir string) (extracted []string, err error) {
+	format, _ := archiveFormat(filepath.Base(file))
+	if format == "" {
+		return nil, fmt.Errorf("%w: %s", ErrNotAnArchive, file)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dir), os.ModePerm); err != nil {
+		return nil, err
+	}
+	if err := os.Mkdir(dir, os.ModePerm); err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			_ = os.RemoveAll(dir)
+			extracted = nil
+		}
+	}()
+
+	limits := o.config.ArchiveLimits
+	budget := limits.maxSize()
+	err = walkArchive(file, format, func(e archiveEntry, r io.Reader) error {
+		if len(extracted) >= limits.maxFiles() {
+			return fmt.Errorf("%w: more than %d files", ErrArchiveTooLarge, limits.maxFiles())
+		}
+		target, err := archiveTarget(dir, e.name)
+		if err != nil {
+			return err
+		}
+
+		n, err := writeEntry(target, e.mode, io.LimitReader(r, budget+1))
+		if err != nil {
+			return err
+		}
+		if budget -= n; budget < 0 {
+			return fmt.Errorf("%w: more than %d bytes", ErrArchiveTooLarge, limits.maxSize())
+		}
+		extracted = append(extracted, target)
+		return nil
+	})
+	return extracted, err
+}
+
+func writeEntry(target string, mode fs.FileMode, r io.Reader) (n int64, err error) {
+	if err := os.MkdirAll(filepath.Dir(target), os.ModePerm); err != nil {
+		return 0, err
+	}
+
+	perm := mode.Perm() | 0o600
+	f, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+	}()
+
+	return io.Copy(f, r)
+}
+
+// extractDir returns the folder an archive is extracted into: its name
+// without the archive suffix, numbered if that name is taken.
+func extractDir(parent, archive string) string {
+	_, stem := archiveFormat(archive)
+	dir := filepath.Join(parent, stem)
+	for i := 2; ; i++ {
+		if _, err := os.Lstat(dir); errors.Is(err, fs.ErrNotExist) {
+			return dir
+		}
+		dir = filepath.Join(parent, fmt.Sprintf("%s (%d)", stem, i))
+	}
+}
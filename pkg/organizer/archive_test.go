@@ -0,0 +1,332 @@
+package organizer_test
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/ulikunitz/xz"
+
+	"github.com/d6o/Gorganizer/pkg/organizer"
+)
+
+// archiveFile is an entry written by the archive helpers.
+type archiveFile struct {
+	name    string
+	content string
+}
+
+func createZip(t *testing.T, path string, files ...archiveFile) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(f)
+	for _, file := range files {
+		w, err := zw.Create(file.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := io.WriteString(w, file.content); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// createTar writes a tar archive compressed according to the extension of
+// path: .tar, .tar.gz or .tar.xz.
+func createTar(t *testing.T, path string, files ...archiveFile) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var w io.WriteCloser = f
+	switch {
+	case strings.HasSuffix(path, ".gz"):
+		w = gzip.NewWriter(f)
+	case strings.HasSuffix(path, ".xz"):
+		if w, err = xz.NewWriter(f); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tw := tar.NewWriter(w)
+	for _, file := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: file.name, Mode: 0o644, Size: int64(len(file.content))}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := io.WriteString(tw, file.content); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if w != f {
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+var album = []archiveFile{
+	{"album/01.mp3", strings.Repeat("a", 1000)},
+	{"album/02.mp3", strings.Repeat("b", 1000)},
+	{"album/cover.jpg", strings.Repeat("c", 100)},
+}
+
+func TestParseArchivePolicy(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		want    organizer.ArchivePolicy
+		wantErr bool
+	}{
+		{"", organizer.ArchivesOff, false},
+		{"off", organizer.ArchivesOff, false},
+		{"inspect", organizer.ArchivesInspect, false},
+		{"extract", organizer.ArchivesExtract, false},
+		{"unzip", organizer.ArchivesOff, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := organizer.ParseArchivePolicy(tt.name)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseArchivePolicy(%q) error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, organizer.ErrUnknownArchivePolicy) {
+				t.Errorf("error = %v, want ErrUnknownArchivePolicy", err)
+			}
+			if got != tt.want {
+				t.Errorf("ParseArchivePolicy(%q) = %v, want %v", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOrganizer_Archives_Inspect(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+
+	createZip(t, filepath.Join(dir, "album.zip"), album...)
+	createTar(t, filepath.Join(dir, "papers.tar.gz"), archiveFile{"a.pdf", "pdf"}, archiveFile{"b.pdf", "pdf"})
+	createTar(t, filepath.Join(dir, "photos.tar.xz"), archiveFile{"x.jpg", "jpg"})
+	createZip(t, filepath.Join(dir, "misc.zip"), archiveFile{"notes.xyz", "?"})
+
+	tests := []struct {
+		policy organizer.ArchivePolicy
+		want   map[string]string
+	}{
+		{organizer.ArchivesOff, map[string]string{
+			"album.zip": "Archives", "papers.tar.gz": "", "photos.tar.xz": "", "misc.zip": "Archives",
+		}},
+		{organizer.ArchivesInspect, map[string]string{
+			"album.zip": "Music", "papers.tar.gz": "Documents", "photos.tar.xz": "Pictures", "misc.zip": "Archives",
+		}},
+	}
+
+	for _, tt := range tests {
+		org := newTestOrganizer(t, organizer.Config{
+			InputFolder:  dir,
+			OutputFolder: dir,
+			Preview:      true,
+			Archives:     tt.policy,
+		})
+		result, err := org.Run()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for name, folder := range tt.want {
+			a := findAction(t, result, name)
+			if a.Destination != folder {
+				t.Errorf("policy %v: %s goes to %q, want %q", tt.policy, name, a.Destination, folder)
+			}
+			if tt.policy == organizer.ArchivesOff && a.Archive != nil {
+				t.Errorf("archive %s inspected with archives off", name)
+			}
+		}
+	}
+
+	result, err := newTestOrganizer(t, organizer.Config{
+		InputFolder:  dir,
+		OutputFolder: dir,
+		Archives:     organizer.ArchivesInspect,
+	}).Preview()
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := findAction(t, result, "album.zip").Archive
+	if info == nil || info.Format != "zip" || info.Files != 3 || info.Size != 2100 {
+		t.Errorf("album.zip info = %+v, want 3 files of 2100 bytes", info)
+	}
+}
+
+func TestOrganizer_Archives_ResolverErrors(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	createZip(t, filepath.Join(dir, "papers.zip"), archiveFile{"a.pdf", "pdf"}, archiveFile{"b.pdf", "pdf"}, archiveFile{"c.bad", "?"})
+
+	errBad := errors.New("cannot read")
+	result, err := newTestOrganizer(t, organizer.Config{
+		InputFolder:  dir,
+		OutputFolder: dir,
+		Archives:     organizer.ArchivesInspect,
+		Resolver: organizer.Chain{
+			organizer.ResolverFunc(func(f *organizer.File) (organizer.Resolution, error) {
+				if f.Ext() == "bad" {
+					return organizer.Resolution{}, errBad
+				}
+				return organizer.Resolution{}, nil
+			}),
+			organizer.ExtensionRules(newMockResolver()),
+		},
+	}).Preview()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a := findAction(t, result, "papers.zip")
+	if a.Destination != "Documents" || a.Archive == nil || a.Archive.Error != "" || a.Archive.Files != 3 {
+		t.Errorf("papers.zip = %+v, archive %+v, want Documents with the unclassified file skipped", a, a.Archive)
+	}
+}
+
+func TestOrganizer_Archives_Extract(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	out := t.TempDir()
+
+	createZip(t, filepath.Join(dir, "album.zip"), album...)
+
+	org := newTestOrganizer(t, organizer.Config{
+		InputFolder:  dir,
+		OutputFolder: out,
+		Archives:     organizer.ArchivesExtract,
+		Trash:        newTestTrash(t),
+	})
+	result, err := org.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a := findAction(t, result, "album.zip")
+	if !a.Removed || a.Trashed == "" {
+		t.Errorf("archive should be moved to the trash after extraction: %+v", a)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "album.zip")); !os.IsNotExist(err) {
+		t.Error("archive still in the input folder")
+	}
+
+	wantDir := filepath.Join(out, "Music", "album")
+	if a.Archive == nil || a.Archive.ExtractedTo != wantDir {
+		t.Fatalf("Archive = %+v, want extracted to %s", a.Archive, wantDir)
+	}
+	var want []string
+	for _, f := range album {
+		want = append(want, filepath.Join(wantDir, filepath.FromSlash(f.name)))
+	}
+	if got := result.Extracted(); !slices.Equal(got, want) {
+		t.Errorf("Extracted() = %v, want %v", got, want)
+	}
+	data, err := os.ReadFile(filepath.Join(wantDir, "album", "cover.jpg"))
+	if err != nil || string(data) != album[2].content {
+		t.Errorf("cover.jpg = %q, %v", data, err)
+	}
+}
+
+func TestOrganizer_Archives_ExtractIntoFreeFolder(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	out := t.TempDir()
+
+	createZip(t, filepath.Join(dir, "album.zip"), album...)
+	if err := os.MkdirAll(filepath.Join(out, "Music", "album"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := newTestOrganizer(t, organizer.Config{
+		InputFolder:  dir,
+		OutputFolder: out,
+		Archives:     organizer.ArchivesExtract,
+		Permanent:    true,
+	}).Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := findAction(t, result, "album.zip").Archive.ExtractedTo, filepath.Join(out, "Music", "album (2)"); got != want {
+		t.Errorf("ExtractedTo = %q, want %q", got, want)
+	}
+}
+
+func TestOrganizer_Archives_Unsafe(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		limits organizer.ArchiveLimits
+		files  []archiveFile
+	}{
+		{"zip slip", organizer.ArchiveLimits{}, []archiveFile{{"song.mp3", "ok"}, {"../evil.mp3", "evil"}}},
+		{"absolute path", organizer.ArchiveLimits{}, []archiveFile{{"song.mp3", "ok"}, {"/tmp/evil.mp3", "evil"}}},
+		{"too many files", organizer.ArchiveLimits{MaxFiles: 2}, album},
+		{"too large", organizer.ArchiveLimits{MaxSize: 1500}, album},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			dir := t.TempDir()
+			out := t.TempDir()
+			createTar(t, filepath.Join(dir, "bad.tar"), tt.files...)
+
+			result, err := newTestOrganizer(t, organizer.Config{
+				InputFolder:   dir,
+				OutputFolder:  out,
+				Archives:      organizer.ArchivesExtract,
+				ArchiveLimits: tt.limits,
+				Permanent:     true,
+			}).Run()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			a := findAction(t, result, "bad.tar")
+			if a.Archive == nil || a.Archive.Error == "" {
+				t.Fatalf("Archive = %+v, want an error", a.Archive)
+			}
+			if a.Archive.ExtractedTo != "" || len(result.Extracted()) != 0 {
+				t.Errorf("unsafe archive was extracted: %+v", a.Archive)
+			}
+			if !a.Moved {
+				t.Error("unsafe archive should be moved like any other file")
+			}
+			if _, err := os.Stat(filepath.Join(filepath.Dir(out), "evil.mp3")); !os.IsNotExist(err) {
+				t.Error("entry escaped the output folder")
+			}
+		})
+	}
+}
@@ -0,0 +1,443 @@
+package organizer
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Audit outcomes.
+const (
+	AuditOK     = "ok"
+	AuditFailed = "failed"
+	// AuditVetoed records an operation that a pre-move hook vetoed, or a
+	// link skipped because the move of its original was vetoed.
+	AuditVetoed = "vetoed"
+)
+
+// AuditRecord is one line of an audit log: a single file operation that was
+// carried out, or attempted, by a run.
+type AuditRecord struct {
+	Time  time.Time `json:"ts"`
+	User  string    `json:"user"`
+	Host  string    `json:"host"`
+	RunID string    `json:"run_id"`
+	Op    Operation `json:"op"`
+	// Source and Destination are absolute paths. Destination is empty for
+	// deletions, and is the folder an archive was extracted into.
+	Source      string `json:"src"`
+	Destination string `json:"dest,omitempty"`
+	Size        int64  `json:"size"`
+	// Hash is the SHA-256 of the source, when the log records hashes.
+	Hash string `json:"hash,omitempty"`
+	// Trashed is where the file the operation removed or replaced was moved
+	// to in the trash. Empty when nothing was trashed.
+	Trashed string `json:"trashed,omitempty"`
+	Outcome string `json:"outcome"`
+	Error   string `json:"error,omitempty"`
+}
+
+// AuditLog appends AuditRecords as JSON lines to a file. When the file
+// would grow past its maximum size it is renamed to file.1, file.1 to
+// file.2 and so on, and the oldest backup is removed. It is safe for
+// concurrent use, and several processes, such as the daemon and the command
+// line, can share a file: they take turns with a lock on file.lock.
+type AuditLog struct {
+	path       string
+	maxSize    int64
+	maxBackups int
+	hashes     bool
+	user       string
+	host       string
+
+	mu   sync.Mutex
+	file *os.File
+	lock *os.File
+	size int64
+}
+
+// AuditOption configures an AuditLog.
+type AuditOption func(*AuditLog)
+
+// WithAuditMaxSize sets the size in bytes past which the log is rotated,
+// 10 MiB by default.
+func WithAuditMaxSize(size int64) AuditOption {
+	return func(l *AuditLog) {
+		if size > 0 {
+			l.maxSize = size
+		}
+	}
+}
+
+// WithAuditBackups sets how many rotated files are kept, 5 by default.
+func WithAuditBackups(n int) AuditOption {
+	return func(l *AuditLog) {
+		if n > 0 {
+			l.maxBackups = n
+		}
+	}
+}
+
+// WithAuditHashes records the SHA-256 of every file before it is moved or
+// removed. Hashing reads every file in full.
+func WithAuditHashes(hashes bool) AuditOption {
+	return func(l *AuditLog) {
+		l.hashes = hashes
+	}
+}
+
+// OpenAuditLog opens the audit log at path for appending, creating it if
+// needed.
+func OpenAuditLog(path string, opts ...AuditOption) (*AuditLog, error) {
+	l := &AuditLog{
+		path:       path,
+		maxSize:    10 << 20,
+		maxBackups: 5,
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+
+	if u, err := user.Current(); err == nil {
+		l.user = u.Username
+	}
+	l.host, _ = os.Hostname()
+
+	if err := l.open(); err != nil {
+		return nil, err
+	}
+	lock, err := os.OpenFile(path+".lock", os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		_ = l.file.Close()
+		return nil, err
+	}
+	l.lock = lock
+	return l, nil
+}
+
+func (l *AuditLog) open() error {
+	if err := os.MkdirAll(filepath.Dir(l.path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(l.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+	l.file, l.size = f, info.Size()
+	return nil
+}
+
+// Write appends r to the log, filling in its time, user and host when they
+// are not set.
+func (l *AuditLog) Write(r AuditRecord) error {
+	if r.Time.IsZero() {
+		r.Time = time.Now()
+	}
+	if r.User == "" {
+		r.User = l.user
+	}
+	if r.Host == "" {
+		r.Host = l.host
+	}
+
+	line, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return os.ErrClosed
+	}
+	if err := lockFile(l.lock); err != nil {
+		return err
+	}
+	defer func() {
+		_ = unlockFile(l.lock)
+	}()
+	if err := l.follow(); err != nil {
+		return err
+	}
+	if l.size > 0 && l.size+int64(len(line)) > l.maxSize {
+		if err := l.rotate(); err != nil {
+			return err
+		}
+	}
+	n, err := l.file.Write(line)
+	l.size += int64(n)
+	return err
+}
+
+// follow reopens the file when another process rotated it, and reads its
+// size, which other processes may have added to. It must be called with
+// the lock held.
+func (l *AuditLog) follow() error {
+	info, err := l.file.Stat()
+	if err != nil {
+		return err
+	}
+	if current, err := os.Stat(l.path); err == nil && os.SameFile(info, current) {
+		l.size = info.Size()
+		return nil
+	}
+	if err := l.file.Close(); err != nil {
+		return err
+	}
+	l.file = nil
+	return l.open()
+}
+
+func (l *AuditLog) rotate() error {
+	if err := l.file.Close(); err != nil {
+		return err
+	}
+	l.file = nil
+
+	for i := l.maxBackups - 1; i >= 1; i-- {
+		err := os.Rename(backupName(l.path, i), backupName(l.path, i+1))
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	if err := os.Rename(l.path, backupName(l.path, 1)); err != nil {
+		return err
+	}
+	return l.open()
+}
+
+func backupName(path string, n int) string {
+	return path + "." + strconv.Itoa(n)
+}
+
+// Close closes the log file.
+func (l *AuditLog) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return nil
+	}
+	err := errors.Join(l.file.Close(), l.lock.Close())
+	l.file = nil
+	return err
+}
+
+// AuditFilter selects records from an audit log. Zero fields match every
+// record.
+type AuditFilter struct {
+	// Since and Until bound the record's time, Until being exclusive.
+	Since time.Time
+	Until time.Time
+	// Path matches records whose source or destination is Path or lies
+	// inside it.
+	Path  string
+	RunID string
+}
+
+func (f AuditFilter) matches(r AuditRecord) bool {
+	if !f.Since.IsZero() && r.Time.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && !r.Time.Before(f.Until) {
+		return false
+	}
+	if f.RunID != "" && r.RunID != f.RunID {
+		return false
+	}
+	if f.Path != "" && !within(r.Source, f.Path) && !within(r.Destination, f.Path) {
+		return false
+	}
+	return true
+}
+
+// within reports whether path is dir or a path inside it.
+func within(path, dir string) bool {
+	if path == "" {
+		return false
+	}
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && filepath.IsLocal(rel)
+}
+
+// ReadAuditLog returns the records of the audit log at path, and of its
+// rotated backups, that match filter, oldest first.
+func ReadAuditLog(path string, filter AuditFilter) ([]AuditRecord, error) {
+	files := []string{path}
+	for i := 1; ; i++ {
+		if _, err := os.Stat(backupName(path, i)); err != nil {
+			break
+		}
+		files = append([]string{backupName(path, i)}, files...)
+	}
+
+	var records []AuditRecord
+	for _, file := range files {
+		var err error
+		records, err = readAuditFile(file, filter, records)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return records, nil
+}
+
+func readAuditFile(file string, filter AuditFilter, records []AuditRecord) ([]AuditRecord, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1<<20)
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var r AuditRecord
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", file, line, err)
+		}
+		if filter.matches(r) {
+			records = append(records, r)
+		}
+	}
+	return records, scanner.Err()
+}
+
+// NewRunID returns a random identifier for a run, as recorded in the audit
+// log.
+func NewRunID() string {
+	b := make([]byte, 6)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// audit records the outcome of entry e, applied to a, in the audit log, if
+// there is one. hash is the SHA-256 of the source taken before e was applied.
+func (o *Organizer) audit(runID string, e PlanEntry, a *FileAction, hash string, opErr error) error {
+	if o.config.Audit == nil {
+		return nil
+	}
+
+	r := AuditRecord{
+		RunID:       runID,
+		Op:          e.Op,
+		Source:      absPath(e.Source),
+		Destination: absPath(e.Target),
+		Size:        e.Size,
+		Hash:        hash,
+		Trashed:     a.Trashed,
+		Outcome:     AuditOK,
+	}
+	switch {
+	case a.Reason == ReasonVetoed:
+		r.Outcome = AuditVetoed
+		r.Error = a.HookError
+	case opErr != nil:
+		r.Outcome = AuditFailed
+		r.Error = opErr.Error()
+	}
+	if err := o.config.Audit.Write(r); err != nil {
+		return fmt.Errorf("writing audit log: %w", err)
+	}
+	return nil
+}
+
+func absPath(path string) string {
+	if path == "" {
+		return ""
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
+
+// sourceHash returns the SHA-256 of file when the audit log records hashes.
+func (o *Organizer) sourceHash(file string) (string, error) {
+	if o.config.Audit == nil || !o.config.Audit.hashes {
+		return "", nil
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// perform runs the pre-move hooks, applies e unless they veto it, records
+// it in the audit log, vetoed or not, and runs the post-move hooks. It returns the error of
+// the operation or of the audit log; hook failures are recorded in a.
+//
+// vetoed holds the targets of the entries vetoed so far in the run. A link
+// to one of them is skipped as vetoed too, since the file it would link to
+// was never put there.
+func (o *Organizer) perform(runID string, e PlanEntry, a *FileAction, vetoed map[string]bool) error {
+	if e.Op == OpLink && vetoed[e.LinkTo] {
+		a.Reason = ReasonVetoed
+		a.HookError = fmt.Sprintf("the move of %s was vetoed", e.DuplicateOf)
+		return o.audit(runID, e, a, "", nil)
+	}
+	if err := o.runHooks(hookPayload(HookPreMove, runID, e, a)); err != nil {
+		a.Reason = ReasonVetoed
+		a.HookError = err.Error()
+		if e.Target != "" {
+			vetoed[e.Target] = true
+		}
+		return o.audit(runID, e, a, "", nil)
+	}
+
+	hash, err := o.sourceHash(e.Source)
+	if err != nil {
+		return errors.Join(err, o.audit(runID, e, a, "", err))
+	}
+	opErr := o.apply(e, a)
+	if opErr != nil {
+		o.log.Error("operation failed", "op", e.Op, "path", e.Source, "target", e.Target, "error", opErr)
+	} else {
+		o.log.Debug("done", "op", e.Op, "path", e.Source, "target", e.Target)
+	}
+	if err := o.audit(runID, e, a, hash, opErr); err != nil {
+		return errors.Join(opErr, err)
+	}
+	if opErr != nil {
+		return opErr
+	}
+
+	if err := o.runHooks(hookPayload(HookPostMove, runID, e, a)); err != nil {
+		a.HookError = err.Error()
+	}
+	return nil
+}
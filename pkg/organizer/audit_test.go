@@ -0,0 +1,290 @@
+package organizer_test
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/d6o/Gorganizer/internal/testutil"
+	"github.com/d6o/Gorganizer/pkg/organizer"
+)
+
+func openTestAuditLog(t *testing.T, file string, opts ...organizer.AuditOption) *organizer.AuditLog {
+	t.Helper()
+	log, err := organizer.OpenAuditLog(file, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := log.Close(); err != nil {
+			t.Error(err)
+		}
+	})
+	return log
+}
+
+func readTestAuditLog(t *testing.T, file string, filter organizer.AuditFilter) []organizer.AuditRecord {
+	t.Helper()
+	records, err := organizer.ReadAuditLog(file, filter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return records
+}
+
+func TestAuditLog_RecordsRun(t *testing.T) {
+	t.Parallel()
+	in := t.TempDir()
+	out := t.TempDir()
+	file := filepath.Join(t.TempDir(), "audit.jsonl")
+
+	testutil.WriteFile(t, in, "song.mp3", "music")
+	testutil.WriteFile(t, in, "notes.xyz", "?")
+
+	config := organizer.Config{
+		InputFolder:  in,
+		OutputFolder: out,
+		Preview:      true,
+		Audit:        openTestAuditLog(t, file, organizer.WithAuditHashes(true)),
+		RunID:        "run-1",
+	}
+	if _, err := newTestOrganizer(t, config).Run(); err != nil {
+		t.Fatal(err)
+	}
+	if records := readTestAuditLog(t, file, organizer.AuditFilter{}); len(records) != 0 {
+		t.Fatalf("preview was audited: %+v", records)
+	}
+
+	config.Preview = false
+	result, err := newTestOrganizer(t, config).Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.RunID != "run-1" {
+		t.Errorf("RunID = %q, want run-1", result.RunID)
+	}
+
+	records := readTestAuditLog(t, file, organizer.AuditFilter{})
+	if len(records) != 1 {
+		t.Fatalf("got %d records, want 1 for the moved file", len(records))
+	}
+	r := records[0]
+	want := organizer.AuditRecord{
+		RunID:       "run-1",
+		Op:          organizer.OpMove,
+		Source:      filepath.Join(in, "song.mp3"),
+		Destination: filepath.Join(out, "Music", "song.mp3"),
+		Size:        5,
+		Hash:        fmt.Sprintf("%x", sha256.Sum256([]byte("music"))),
+		Outcome:     organizer.AuditOK,
+	}
+	if r.Time.IsZero() || r.User == "" || r.Host == "" {
+		t.Errorf("record lacks time, user or host: %+v", r)
+	}
+	r.Time, r.User, r.Host = time.Time{}, "", ""
+	if r != want {
+		t.Errorf("record = %+v, want %+v", r, want)
+	}
+}
+
+func TestAuditLog_RecordsFailures(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	file := filepath.Join(t.TempDir(), "audit.jsonl")
+
+	testutil.WriteFile(t, dir, "song.mp3", "music")
+	info, err := os.Stat(filepath.Join(dir, "song.mp3"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan := &organizer.Plan{
+		Version: organizer.PlanVersion,
+		Entries: []organizer.PlanEntry{{
+			Op:      "shred",
+			Source:  filepath.Join(dir, "song.mp3"),
+			Size:    info.Size(),
+			ModTime: info.ModTime(),
+		}},
+	}
+
+	org := organizer.NewOrganizer(nil, organizer.Config{Audit: openTestAuditLog(t, file)})
+	if _, err := org.Apply(plan); !errors.Is(err, organizer.ErrUnknownOperation) {
+		t.Fatalf("Apply() error = %v, want ErrUnknownOperation", err)
+	}
+
+	records := readTestAuditLog(t, file, organizer.AuditFilter{})
+	if len(records) != 1 || records[0].Outcome != organizer.AuditFailed || records[0].Error == "" {
+		t.Errorf("records = %+v, want one failed operation", records)
+	}
+	if records[0].RunID == "" {
+		t.Error("Apply did not generate a run ID")
+	}
+}
+
+func TestAuditLog_RecordsTrashed(t *testing.T) {
+	t.Parallel()
+	in := t.TempDir()
+	out := t.TempDir()
+	file := filepath.Join(t.TempDir(), "audit.jsonl")
+
+	testutil.WriteFile(t, filepath.Join(out, "Music"), "song.mp3", "old")
+	testutil.WriteFile(t, in, "song.mp3", "new")
+
+	result, err := newTestOrganizer(t, organizer.Config{
+		InputFolder:  in,
+		OutputFolder: out,
+		Audit:        openTestAuditLog(t, file),
+	}).Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	trashed := findAction(t, result, "song.mp3").Trashed
+	if trashed == "" {
+		t.Fatal("the replaced song.mp3 should be trashed")
+	}
+	records := readTestAuditLog(t, file, organizer.AuditFilter{})
+	if len(records) != 1 || records[0].Trashed != trashed {
+		t.Errorf("records = %+v, want the move with the replaced file in %s", records, trashed)
+	}
+}
+
+func TestAuditLog_RecordsVetoes(t *testing.T) {
+	t.Parallel()
+	skipWithoutShell(t)
+	in := t.TempDir()
+	out := t.TempDir()
+	file := filepath.Join(t.TempDir(), "audit.jsonl")
+
+	testutil.WriteFile(t, in, "a.pdf", "same content")
+	testutil.WriteFile(t, in, "b.pdf", "same content")
+
+	_, err := newTestOrganizer(t, organizer.Config{
+		InputFolder:  in,
+		OutputFolder: out,
+		Duplicates:   organizer.DuplicatesHardlink,
+		Audit:        openTestAuditLog(t, file),
+		Hooks: []organizer.Hook{
+			{Event: organizer.HookPreMove, Command: `case "$GORGANIZER_SOURCE" in */a.pdf) exit 1;; esac`},
+		},
+	}).Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	records := readTestAuditLog(t, file, organizer.AuditFilter{})
+	if len(records) != 2 {
+		t.Fatalf("got %d records, want the vetoed move and link", len(records))
+	}
+	for _, r := range records {
+		if r.Outcome != organizer.AuditVetoed || r.Error == "" {
+			t.Errorf("record = %+v, want vetoed with the hook's error", r)
+		}
+	}
+}
+
+func TestAuditLog_RotatesAndFilters(t *testing.T) {
+	t.Parallel()
+	file := filepath.Join(t.TempDir(), "audit.jsonl")
+	log := openTestAuditLog(t, file, organizer.WithAuditMaxSize(400), organizer.WithAuditBackups(2))
+
+	day := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	for i := range 12 {
+		r := organizer.AuditRecord{
+			Time:    day.AddDate(0, 0, i/4),
+			RunID:   []string{"a", "b", "c"}[i/4],
+			Op:      organizer.OpMove,
+			Source:  filepath.Join("/in", []string{"x", "y"}[i%2], "f.pdf"),
+			Outcome: organizer.AuditOK,
+		}
+		if err := log.Write(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, name := range []string{file + ".1", file + ".2"} {
+		if _, err := os.Stat(name); err != nil {
+			t.Errorf("rotated file missing: %v", err)
+		}
+	}
+	if _, err := os.Stat(file + ".3"); !errors.Is(err, os.ErrNotExist) {
+		t.Error("more backups kept than configured")
+	}
+	if info, err := os.Stat(file); err != nil || info.Size() > 400 {
+		t.Errorf("log not rotated: %v %v", info, err)
+	}
+
+	all := readTestAuditLog(t, file, organizer.AuditFilter{})
+	for i := 1; i < len(all); i++ {
+		if all[i].Time.Before(all[i-1].Time) {
+			t.Fatalf("records out of order: %v after %v", all[i].Time, all[i-1].Time)
+		}
+	}
+	if all[len(all)-1].RunID != "c" {
+		t.Errorf("last record from run %q, want c", all[len(all)-1].RunID)
+	}
+
+	tests := []struct {
+		name   string
+		filter organizer.AuditFilter
+		want   int
+	}{
+		{"run", organizer.AuditFilter{RunID: "c"}, 4},
+		{"range", organizer.AuditFilter{Since: day.AddDate(0, 0, 2), Until: day.AddDate(0, 0, 3)}, 4},
+		{"path", organizer.AuditFilter{RunID: "c", Path: filepath.Join("/in", "x")}, 2},
+		{"path prefix is not a folder", organizer.AuditFilter{Path: "/i"}, 0},
+	}
+	for _, tt := range tests {
+		if got := readTestAuditLog(t, file, tt.filter); len(got) != tt.want {
+			t.Errorf("%s: got %d records, want %d", tt.name, len(got), tt.want)
+		}
+	}
+}
+
+func TestAuditLog_SharedFile(t *testing.T) {
+	t.Parallel()
+	file := filepath.Join(t.TempDir(), "audit.jsonl")
+	// Two logs on one file stand for two processes.
+	logs := []*organizer.AuditLog{
+		openTestAuditLog(t, file, organizer.WithAuditMaxSize(1000), organizer.WithAuditBackups(100)),
+		openTestAuditLog(t, file, organizer.WithAuditMaxSize(1000), organizer.WithAuditBackups(100)),
+	}
+
+	var wg sync.WaitGroup
+	for i, log := range logs {
+		wg.Go(func() {
+			for n := range 50 {
+				r := organizer.AuditRecord{RunID: fmt.Sprint(i), Op: organizer.OpMove, Source: fmt.Sprintf("/in/%d.pdf", n), Outcome: organizer.AuditOK}
+				if err := log.Write(r); err != nil {
+					t.Error(err)
+				}
+			}
+		})
+	}
+	wg.Wait()
+
+	if records := readTestAuditLog(t, file, organizer.AuditFilter{}); len(records) != 100 {
+		t.Errorf("read %d records, want 100", len(records))
+	}
+	for i := 0; ; i++ {
+		name := file
+		if i > 0 {
+			name = fmt.Sprintf("%s.%d", file, i)
+		}
+		info, err := os.Stat(name)
+		if err != nil {
+			if i < 2 {
+				t.Errorf("expected rotated files: %v", err)
+			}
+			break
+		}
+		if info.Size() > 1000 {
+			t.Errorf("%s holds %d bytes, past the maximum size", name, info.Size())
+		}
+	}
+}
@@ -0,0 +1,293 @@
+package organizer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// partialHashSize is the number of leading bytes hashed to cheaply tell apart
+// files of equal size before computing a full content hash.
+const partialHashSize = 4096
+
+// DuplicatesFolder is the folder, relative to the output folder, that
+// duplicates are moved to under DuplicatesMove.
+const DuplicatesFolder = "Duplicates"
+
+// DuplicatePolicy controls how files with identical content are handled.
+type DuplicatePolicy int
+
+const (
+	// DuplicatesOff disables duplicate detection.
+	DuplicatesOff DuplicatePolicy = iota
+	// DuplicatesReport detects duplicates but organizes them as usual.
+	DuplicatesReport
+	// DuplicatesSkip leaves duplicates where they are.
+	DuplicatesSkip
+	// DuplicatesDelete removes duplicates, keeping only the original.
+	DuplicatesDelete
+	// DuplicatesMove moves duplicates into DuplicatesFolder.
+	DuplicatesMove
+	// DuplicatesHardlink replaces duplicates with hard links to the original.
+	// A duplicate headed for another file system than its original is
+	// moved instead, since a hard link cannot span them.
+	DuplicatesHardlink
+)
+
+var duplicatePolicyNames = map[string]DuplicatePolicy{
+	"":         DuplicatesOff,
+	"off":      DuplicatesOff,
+	"report":   DuplicatesReport,
+	"skip":     DuplicatesSkip,
+	"delete":   DuplicatesDelete,
+	"move":     DuplicatesMove,
+	"hardlink": DuplicatesHardlink,
+}
+
+// ParseDuplicatePolicy converts a policy name (off, report, skip, delete,
+// move or hardlink) to a DuplicatePolicy. Returns ErrUnknownDuplicatePolicy
+// for any other value.
+func ParseDuplicatePolicy(name string) (DuplicatePolicy, error) {
+	p, ok := duplicatePolicyNames[name]
+	if !ok {
+		return DuplicatesOff, ErrUnknownDuplicatePolicy
+	}
+	return p, nil
+}
+
+type hashCandidate struct {
+	path     string
+	size     int64
+	hash     []byte
+	action   *FileAction
+	existing bool
+}
+
+func (c hashCandidate) hashHex() string {
+	return hex.EncodeToString(c.hash)
+}
+
+// findDuplicates hashes the files that are about to be organized, together
+// with the files already present in their destination folders, and marks
+// every copy after the original according to the configured policy.
+//
+// Files are first grouped by size, then by a hash of their first few
+// kilobytes, and only the remaining candidates get a full SHA-256.
+func (o *Organizer) findDuplicates(actions []FileAction) ([]DuplicateGroup, error) {
+	candidates, err := o.duplicateCandidates(actions)
+	if err != nil {
+		return nil, err
+	}
+
+	bySize := make(map[int64][]hashCandidate)
+	var sizes []int64
+	for _, c := range candidates {
+		if _, ok := bySize[c.size]; !ok {
+			sizes = append(sizes, c.size)
+		}
+		bySize[c.size] = append(bySize[c.size], c)
+	}
+
+	var groups []DuplicateGroup
+	for _, size := range sizes {
+		if len(bySize[size]) < 2 {
+			continue
+		}
+
+		byPartial, err := groupByHash(bySize[size], partialHashSize)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, partial := range byPartial {
+			if len(partial) < 2 {
+				continue
+			}
+
+			byFull := [][]hashCandidate{partial}
+			if size > partialHashSize {
+				byFull, err = groupByHash(partial, -1)
+				if err != nil {
+					return nil, err
+				}
+			}
+
+			for _, full := range byFull {
+				if g, ok := o.markDuplicates(full); ok {
+					groups = append(groups, g)
+				}
+			}
+		}
+	}
+
+	return groups, nil
+}
+
+// duplicateCandidates returns the files that are organized in this run,
+// followed by the regular files already in their destination folders.
+// Symbolic links are never candidates, whether or not their target exists.
+func (o *Organizer) duplicateCandidates(actions []FileAction) ([]hashCandidate, error) {
+	var candidates []hashCandidate
+	seen := make(map[string]bool)
+	var folders []string
+	seenFolder := make(map[string]bool)
+
+	for i := range actions {
+		a := &actions[i]
+		if a.Reason != ReasonOrganized {
+			continue
+		}
+
+		info, err := os.Lstat(a.Path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.Mode().IsRegular() {
+			continue
+		}
+
+		candidates = append(candidates, hashCandidate{path: a.Path, size: info.Size(), action: a})
+		seen[a.Path] = true
+
+		dir := o.destinationDir(*a)
+		if !seenFolder[dir] {
+			seenFolder[dir] = true
+			folders = append(folders, dir)
+		}
+	}
+
+	for _, dir := range folders {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+
+		for _, entry := range entries {
+			path := filepath.Join(dir, entry.Name())
+			if seen[path] || !entry.Type().IsRegular() {
+				continue
+			}
+
+			info, err := entry.Info()
+			if err != nil {
+				return nil, err
+			}
+
+			candidates = append(candidates, hashCandidate{path: path, size: info.Size(), existing: true})
+			seen[path] = true
+		}
+	}
+
+	return candidates, nil
+}
+
+// markDuplicates picks the original of a set of identical files and updates
+// the actions of the remaining copies. It reports false when the set does
+// not contain any file organized in this run.
+func (o *Organizer) markDuplicates(files []hashCandidate) (DuplicateGroup, bool) {
+	if len(files) < 2 {
+		return DuplicateGroup{}, false
+	}
+
+	original := -1
+	for i, f := range files {
+		if f.existing {
+			original = i
+			break
+		}
+	}
+	if original < 0 {
+		original = 0
+	}
+
+	group := DuplicateGroup{
+		Hash:     files[0].hashHex(),
+		Size:     files[0].size,
+		Original: files[original].path,
+	}
+
+	for i, f := range files {
+		if i == original || f.existing {
+			continue
+		}
+
+		group.Duplicates = append(group.Duplicates, f.path)
+
+		a := f.action
+		a.DuplicateOf = group.Original
+		o.log.Debug("duplicate", "path", f.path, "original", group.Original)
+
+		switch o.config.Duplicates {
+		case DuplicatesSkip, DuplicatesDelete:
+			a.Reason = ReasonDuplicate
+			a.Destination = ""
+		case DuplicatesMove:
+			a.Reason = ReasonDuplicate
+			a.Destination = DuplicatesFolder
+		case DuplicatesHardlink:
+			a.Reason = ReasonDuplicate
+		}
+	}
+
+	if len(group.Duplicates) == 0 {
+		return DuplicateGroup{}, false
+	}
+
+	return group, true
+}
+
+// groupByHash splits files by the SHA-256 of their first limit bytes, or of
+// their whole content when limit is negative. Group order follows the order
+// in which each hash was first seen.
+func groupByHash(files []hashCandidate, limit int64) ([][]hashCandidate, error) {
+	var order [][]byte
+	groups := make(map[string][]hashCandidate)
+
+	for _, f := range files {
+		sum, err := hashFile(f.path, limit)
+		if err != nil {
+			return nil, err
+		}
+
+		key := string(sum)
+		if _, ok := groups[key]; !ok {
+			order = append(order, sum)
+		}
+		f.hash = sum
+		groups[key] = append(groups[key], f)
+	}
+
+	result := make([][]hashCandidate, 0, len(order))
+	for _, sum := range order {
+		result = append(result, groups[string(sum)])
+	}
+	return result, nil
+}
+
+func hashFile(path string, limit int64) (hash []byte, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if cerr := f.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}()
+
+	var r io.Reader = f
+	if limit >= 0 {
+		r = io.LimitReader(f, limit)
+	}
+
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
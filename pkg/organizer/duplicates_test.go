@@ -0,0 +1,260 @@
+package organizer_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/d6o/Gorganizer/internal/testutil"
+	"github.com/d6o/Gorganizer/pkg/organizer"
+)
+
+func findAction(t *testing.T, result *organizer.OrganizeResult, name string) organizer.FileAction {
+	t.Helper()
+	for _, a := range result.Actions {
+		if a.FileName == name {
+			return a
+		}
+	}
+	t.Fatalf("%s not found in actions", name)
+	return organizer.FileAction{}
+}
+
+func TestParseDuplicatePolicy(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		want    organizer.DuplicatePolicy
+		wantErr error
+	}{
+		{"", organizer.DuplicatesOff, nil},
+		{"report", organizer.DuplicatesReport, nil},
+		{"skip", organizer.DuplicatesSkip, nil},
+		{"delete", organizer.DuplicatesDelete, nil},
+		{"move", organizer.DuplicatesMove, nil},
+		{"hardlink", organizer.DuplicatesHardlink, nil},
+		{"shred", organizer.DuplicatesOff, organizer.ErrUnknownDuplicatePolicy},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := organizer.ParseDuplicatePolicy(tt.name)
+			if err != tt.wantErr {
+				t.Fatalf("ParseDuplicatePolicy(%q) error = %v, want %v", tt.name, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseDuplicatePolicy(%q) = %d, want %d", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDuplicates_ReportWithinRun(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+
+	testutil.WriteFile(t, dir, "report.pdf", "same content")
+	testutil.WriteFile(t, dir, "report (1).pdf", "same content")
+	testutil.WriteFile(t, dir, "other.pdf", "different content")
+
+	result, err := newTestOrganizer(t, organizer.Config{
+		InputFolder:       dir,
+		OutputFolder:      dir,
+		Preview:           true,
+		IgnoreHiddenFiles: true,
+		Duplicates:        organizer.DuplicatesReport,
+	}).Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(result.Duplicates) != 1 {
+		t.Fatalf("expected 1 duplicate group, got %d", len(result.Duplicates))
+	}
+
+	g := result.Duplicates[0]
+	if filepath.Base(g.Original) != "report (1).pdf" {
+		t.Errorf("Original = %q, want first scanned file", g.Original)
+	}
+	if len(g.Duplicates) != 1 || filepath.Base(g.Duplicates[0]) != "report.pdf" {
+		t.Errorf("Duplicates = %v, want [report.pdf]", g.Duplicates)
+	}
+	if g.Size != int64(len("same content")) {
+		t.Errorf("Size = %d, want %d", g.Size, len("same content"))
+	}
+	if len(g.Hash) != 64 {
+		t.Errorf("Hash = %q, want a hex SHA-256", g.Hash)
+	}
+
+	a := findAction(t, result, "report.pdf")
+	if a.Reason != organizer.ReasonOrganized {
+		t.Errorf("report policy should keep reason organized, got %d", a.Reason)
+	}
+	if a.DuplicateOf != g.Original {
+		t.Errorf("DuplicateOf = %q, want %q", a.DuplicateOf, g.Original)
+	}
+}
+
+func TestDuplicates_SameSizeDifferentContent(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+
+	big := strings.Repeat("a", 10000)
+	testutil.WriteFile(t, dir, "a.pdf", big+"1")
+	testutil.WriteFile(t, dir, "b.pdf", big+"2")
+
+	result, err := newTestOrganizer(t, organizer.Config{
+		InputFolder:       dir,
+		OutputFolder:      dir,
+		Preview:           true,
+		IgnoreHiddenFiles: true,
+		Duplicates:        organizer.DuplicatesReport,
+	}).Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(result.Duplicates) != 0 {
+		t.Errorf("expected no duplicate groups, got %v", result.Duplicates)
+	}
+}
+
+func TestDuplicates_AgainstDestination(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	out := t.TempDir()
+
+	docs := filepath.Join(out, "Documents")
+	if err := os.Mkdir(docs, os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	testutil.WriteFile(t, docs, "kept.pdf", "same content")
+	testutil.WriteFile(t, dir, "report.pdf", "same content")
+
+	result, err := newTestOrganizer(t, organizer.Config{
+		InputFolder:       dir,
+		OutputFolder:      out,
+		IgnoreHiddenFiles: true,
+		Duplicates:        organizer.DuplicatesSkip,
+	}).Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(result.Duplicates) != 1 {
+		t.Fatalf("expected 1 duplicate group, got %d", len(result.Duplicates))
+	}
+	if result.Duplicates[0].Original != filepath.Join(docs, "kept.pdf") {
+		t.Errorf("Original = %q, want existing destination file", result.Duplicates[0].Original)
+	}
+
+	a := findAction(t, result, "report.pdf")
+	if a.Reason != organizer.ReasonDuplicate || a.Moved {
+		t.Errorf("report.pdf should be skipped as duplicate, got reason %d moved %v", a.Reason, a.Moved)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "report.pdf")); err != nil {
+		t.Error("skipped duplicate should stay in place")
+	}
+}
+
+func TestDuplicates_Delete(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	out := t.TempDir()
+
+	testutil.WriteFile(t, dir, "a.pdf", "same content")
+	testutil.WriteFile(t, dir, "b.pdf", "same content")
+
+	result, err := newTestOrganizer(t, organizer.Config{
+		InputFolder:       dir,
+		OutputFolder:      out,
+		IgnoreHiddenFiles: true,
+		Duplicates:        organizer.DuplicatesDelete,
+	}).Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a := findAction(t, result, "b.pdf")
+	if !a.Removed {
+		t.Error("expected b.pdf to be removed")
+	}
+	if _, err := os.Stat(a.Trashed); err != nil {
+		t.Errorf("removed duplicate should be in the trash: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "b.pdf")); !os.IsNotExist(err) {
+		t.Error("b.pdf should no longer exist")
+	}
+	if _, err := os.Stat(filepath.Join(out, "Documents", "a.pdf")); err != nil {
+		t.Error("original should be organized")
+	}
+}
+
+func TestDuplicates_Move(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	out := t.TempDir()
+
+	testutil.WriteFile(t, dir, "a.pdf", "same content")
+	testutil.WriteFile(t, dir, "b.pdf", "same content")
+
+	result, err := newTestOrganizer(t, organizer.Config{
+		InputFolder:       dir,
+		OutputFolder:      out,
+		IgnoreHiddenFiles: true,
+		Duplicates:        organizer.DuplicatesMove,
+	}).Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a := findAction(t, result, "b.pdf")
+	if a.Destination != organizer.DuplicatesFolder || !a.Moved {
+		t.Errorf("b.pdf destination = %q moved = %v, want Duplicates folder", a.Destination, a.Moved)
+	}
+	if _, err := os.Stat(filepath.Join(out, organizer.DuplicatesFolder, "b.pdf")); err != nil {
+		t.Error("b.pdf should be in the Duplicates folder")
+	}
+}
+
+func TestDuplicates_Hardlink(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	out := t.TempDir()
+
+	testutil.WriteFile(t, dir, "a.pdf", "same content")
+	testutil.WriteFile(t, dir, "b.pdf", "same content")
+
+	result, err := newTestOrganizer(t, organizer.Config{
+		InputFolder:       dir,
+		OutputFolder:      out,
+		IgnoreHiddenFiles: true,
+		Duplicates:        organizer.DuplicatesHardlink,
+	}).Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a := findAction(t, result, "b.pdf")
+	if !a.Linked {
+		t.Fatal("expected b.pdf to be linked")
+	}
+
+	original, err := os.Stat(filepath.Join(out, "Documents", "a.pdf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	link, err := os.Stat(filepath.Join(out, "Documents", "b.pdf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !os.SameFile(original, link) {
+		t.Error("b.pdf should be a hard link to a.pdf")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "b.pdf")); !os.IsNotExist(err) {
+		t.Error("source of linked duplicate should be removed")
+	}
+}
@@ -0,0 +1,40 @@
+//go:build !windows
+
+package organizer_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/d6o/Gorganizer/internal/testutil"
+	"github.com/d6o/Gorganizer/pkg/organizer"
+)
+
+func TestDuplicates_SkipSymlinks(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+
+	target := testutil.WriteFile(t, dir, "report.pdf", "same content")
+	if err := os.Symlink(target, filepath.Join(dir, "link.pdf")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(dir, "missing.pdf"), filepath.Join(dir, "broken.pdf")); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := newTestOrganizer(t, organizer.Config{
+		InputFolder:       dir,
+		OutputFolder:      dir,
+		Preview:           true,
+		IgnoreHiddenFiles: true,
+		Duplicates:        organizer.DuplicatesReport,
+	}).Run()
+	if err != nil {
+		t.Fatalf("a dangling symlink should not abort the run: %v", err)
+	}
+
+	if len(result.Duplicates) != 0 {
+		t.Errorf("Duplicates = %v, want a symlink never matched against its target", result.Duplicates)
+	}
+}
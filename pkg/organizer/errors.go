@@ -0,0 +1,53 @@
+package organizer
+
+import "errors"
+
+// ErrUnknownDuplicatePolicy is returned when a duplicate policy name is not
+// one of off, report, skip, delete, move or hardlink.
+var ErrUnknownDuplicatePolicy = errors.New("unknown duplicate policy")
+
+// ErrUnsupportedPlanVersion is returned when reading a plan written in a
+// format version this build does not understand.
+var ErrUnsupportedPlanVersion = errors.New("unsupported plan version")
+
+// ErrUnknownOperation is returned when applying a plan entry whose operation
+// is not move, delete or link.
+var ErrUnknownOperation = errors.New("unknown plan operation")
+
+// ErrUnknownReason is returned when encoding or decoding an ActionReason
+// that has no name.
+var ErrUnknownReason = errors.New("unknown action reason")
+
+// ErrUnknownArchivePolicy is returned when an archive policy name is not one
+// of off, inspect or extract.
+var ErrUnknownArchivePolicy = errors.New("unknown archive policy")
+
+// ErrArchiveTooLarge is returned when an archive holds more files or more
+// data than the ArchiveLimits allow.
+var ErrArchiveTooLarge = errors.New("archive exceeds extraction limits")
+
+// ErrUnsafeArchivePath is returned for archive entries whose name would
+// place them outside the folder the archive is extracted into.
+var ErrUnsafeArchivePath = errors.New("archive entry escapes the extraction folder")
+
+// ErrUnknownRenameStep is returned when a rename step name is not one of
+// url-decode, transliterate, safe, whitespace, nfc, lower-ext or all.
+var ErrUnknownRenameStep = errors.New("unknown rename step")
+
+// ErrInvalidRenameTemplate is returned when a rename template does not
+// parse, uses an unknown placeholder or contains a path separator.
+var ErrInvalidRenameTemplate = errors.New("invalid rename template")
+
+// ErrNotAnArchive is returned when extracting a file that is not a zip or
+// tar archive.
+var ErrNotAnArchive = errors.New("not an archive")
+
+// ErrInvalidHook is returned when a hook is not written as EVENT=COMMAND.
+var ErrInvalidHook = errors.New("invalid hook, use EVENT=COMMAND")
+
+// ErrUnknownHookEvent is returned when a hook event is not one of pre-run,
+// pre-move, post-move or post-run.
+var ErrUnknownHookEvent = errors.New("unknown hook event")
+
+// ErrHookFailed is returned when a hook exits with an error or times out.
+var ErrHookFailed = errors.New("hook failed")
@@ -0,0 +1,151 @@
+package organizer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// HookEvent is when a hook runs.
+type HookEvent string
+
+const (
+	// HookPreRun runs once the files are classified, before anything is
+	// done to them. A failing pre-run hook cancels the run.
+	HookPreRun HookEvent = "pre-run"
+	// HookPreMove runs before every file operation. A failing pre-move
+	// hook vetoes the operation, and the file is reported with
+	// ReasonVetoed, as are the duplicates that would be hard links to it.
+	HookPreMove HookEvent = "pre-move"
+	// HookPostMove runs after every successful file operation.
+	HookPostMove HookEvent = "post-move"
+	// HookPostRun runs once the run is over.
+	HookPostRun HookEvent = "post-run"
+)
+
+// DefaultHookTimeout is how long a hook may run when its Timeout is zero.
+const DefaultHookTimeout = 30 * time.Second
+
+// Hook is a command run through the shell at an event of a run. Hooks run
+// only when files are actually organized, not for previews.
+//
+// The command gets the event and the operation in environment variables:
+// GORGANIZER_EVENT, GORGANIZER_RUN_ID and, for file events, GORGANIZER_OP,
+// GORGANIZER_SOURCE, GORGANIZER_TARGET and GORGANIZER_FOLDER, the folder of
+// the rule the file matched. A HookPayload is written as JSON to its
+// standard input. A hook fails when it exits with a status other than 0 or
+// runs longer than its timeout.
+type Hook struct {
+	Event   HookEvent     `json:"event"`
+	Command string        `json:"command"`
+	Timeout time.Duration `json:"timeout,omitempty"`
+}
+
+// HookPayload is what a hook reads on its standard input.
+type HookPayload struct {
+	Event  HookEvent `json:"event"`
+	RunID  string    `json:"run_id"`
+	Op     Operation `json:"op,omitempty"`
+	Source string    `json:"source,omitempty"`
+	Target string    `json:"target,omitempty"`
+	Folder string    `json:"folder,omitempty"`
+	// Action is the file the operation is about, for pre-move and
+	// post-move hooks.
+	Action *FileAction `json:"action,omitempty"`
+	// Result is what the run is about to do for pre-run hooks, and what
+	// it did for post-run hooks.
+	Result *OrganizeResult `json:"result,omitempty"`
+}
+
+// ParseHook parses a hook written as EVENT=COMMAND, such as
+// "post-move=notify-send 'New file'".
+func ParseHook(spec string) (Hook, error) {
+	event, command, ok := strings.Cut(spec, "=")
+	if !ok || strings.TrimSpace(command) == "" {
+		return Hook{}, fmt.Errorf("%w: %q", ErrInvalidHook, spec)
+	}
+	switch e := HookEvent(strings.TrimSpace(event)); e {
+	case HookPreRun, HookPreMove, HookPostMove, HookPostRun:
+		return Hook{Event: e, Command: command}, nil
+	}
+	return Hook{}, fmt.Errorf("%w: %q", ErrUnknownHookEvent, event)
+}
+
+// runHooks runs the hooks of the payload's event in order, stopping at the
+// first one that fails.
+func (o *Organizer) runHooks(p HookPayload) error {
+	for _, h := range o.config.Hooks {
+		if h.Event != p.Event {
+			continue
+		}
+		if err := o.runHook(h, p); err != nil {
+			o.log.Warn("hook failed", "event", h.Event, "command", h.Command, "path", p.Source, "error", err)
+			return err
+		}
+		o.log.Debug("hook done", "event", h.Event, "command", h.Command, "path", p.Source)
+	}
+	return nil
+}
+
+func (o *Organizer) runHook(h Hook, p HookPayload) error {
+	input, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+
+	timeout := h.Timeout
+	if timeout <= 0 {
+		timeout = DefaultHookTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	shell := shellCommand(h.Command)
+	cmd := exec.CommandContext(ctx, shell[0], shell[1:]...)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Env = append(os.Environ(),
+		"GORGANIZER_EVENT="+string(p.Event),
+		"GORGANIZER_RUN_ID="+p.RunID,
+		"GORGANIZER_OP="+string(p.Op),
+		"GORGANIZER_SOURCE="+p.Source,
+		"GORGANIZER_TARGET="+p.Target,
+		"GORGANIZER_FOLDER="+p.Folder,
+	)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	// Children the hook leaves running must not hold the run up.
+	cmd.WaitDelay = time.Second
+
+	err = cmd.Run()
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return fmt.Errorf("%w: %s %s timed out after %s", ErrHookFailed, h.Event, h.Command, timeout)
+	case err != nil:
+		msg := fmt.Sprintf("%s %s: %v", h.Event, h.Command, err)
+		if out := strings.TrimSpace(stderr.String()); out != "" {
+			msg += ": " + out
+		}
+		return fmt.Errorf("%w: %s", ErrHookFailed, msg)
+	}
+	return nil
+}
+
+// hookPayload returns the payload of a file event for the operation e on
+// the file of a.
+func hookPayload(event HookEvent, runID string, e PlanEntry, a *FileAction) HookPayload {
+	return HookPayload{
+		Event:  event,
+		RunID:  runID,
+		Op:     e.Op,
+		Source: absPath(e.Source),
+		Target: absPath(e.Target),
+		Folder: a.Destination,
+		Action: a,
+	}
+}
@@ -0,0 +1,216 @@
+package organizer_test
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/d6o/Gorganizer/internal/testutil"
+	"github.com/d6o/Gorganizer/pkg/organizer"
+)
+
+func skipWithoutShell(t *testing.T) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("hook tests use a POSIX shell")
+	}
+}
+
+func TestParseHook(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		spec    string
+		want    organizer.Hook
+		wantErr error
+	}{
+		{"post-move=echo a=b", organizer.Hook{Event: organizer.HookPostMove, Command: "echo a=b"}, nil},
+		{"pre-run=true", organizer.Hook{Event: organizer.HookPreRun, Command: "true"}, nil},
+		{"pre-move=", organizer.Hook{}, organizer.ErrInvalidHook},
+		{"true", organizer.Hook{}, organizer.ErrInvalidHook},
+		{"on-move=true", organizer.Hook{}, organizer.ErrUnknownHookEvent},
+	}
+	for _, tt := range tests {
+		got, err := organizer.ParseHook(tt.spec)
+		if !errors.Is(err, tt.wantErr) || got != tt.want {
+			t.Errorf("ParseHook(%q) = %+v, %v, want %+v, %v", tt.spec, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestHooks_Run(t *testing.T) {
+	t.Parallel()
+	skipWithoutShell(t)
+	in := t.TempDir()
+	out := t.TempDir()
+	logs := t.TempDir()
+	testutil.WriteFile(t, in, "song.mp3", "music")
+	testutil.WriteFile(t, in, "keep.pdf", "doc")
+
+	moves := filepath.Join(logs, "moves")
+	result, err := newTestOrganizer(t, organizer.Config{
+		InputFolder:  in,
+		OutputFolder: out,
+		RunID:        "run-1",
+		Hooks: []organizer.Hook{
+			organizer.Hook{Event: organizer.HookPreRun, Command: "cat > " + filepath.Join(logs, "pre-run.json")},
+			organizer.Hook{Event: organizer.HookPreMove, Command: `case "$GORGANIZER_SOURCE" in *.pdf) echo "not pdfs" >&2; exit 3;; esac`},
+			organizer.Hook{Event: organizer.HookPostMove, Command: `echo "$GORGANIZER_EVENT $GORGANIZER_RUN_ID $GORGANIZER_OP $GORGANIZER_FOLDER $GORGANIZER_TARGET" >> ` + moves},
+			organizer.Hook{Event: organizer.HookPostRun, Command: "cat > " + filepath.Join(logs, "post-run.json")},
+		},
+	}).Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	song := findAction(t, result, "song.mp3")
+	if !song.Moved || song.HookError != "" {
+		t.Errorf("song.mp3 = %+v, want moved without hook error", song)
+	}
+	pdf := findAction(t, result, "keep.pdf")
+	if pdf.Moved || pdf.Reason != organizer.ReasonVetoed || !strings.Contains(pdf.HookError, "not pdfs") {
+		t.Errorf("keep.pdf = %+v, want vetoed with the hook's message", pdf)
+	}
+	if _, err := os.Stat(filepath.Join(in, "keep.pdf")); err != nil {
+		t.Errorf("vetoed file was moved: %v", err)
+	}
+
+	data, err := os.ReadFile(moves)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "post-move run-1 move Music " + filepath.Join(out, "Music", "song.mp3") + "\n"
+	if string(data) != want {
+		t.Errorf("post-move hook saw %q, want %q", data, want)
+	}
+
+	for _, name := range []string{"pre-run.json", "post-run.json"} {
+		data, err := os.ReadFile(filepath.Join(logs, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var payload organizer.HookPayload
+		if err := json.Unmarshal(data, &payload); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if payload.RunID != "run-1" || payload.Result == nil || len(payload.Result.Actions) != 2 {
+			t.Errorf("%s = %+v, want the run's result", name, payload)
+		}
+	}
+}
+
+func TestHooks_VetoSkipsLinks(t *testing.T) {
+	t.Parallel()
+	skipWithoutShell(t)
+
+	for _, planned := range []bool{false, true} {
+		in := t.TempDir()
+		out := t.TempDir()
+		testutil.WriteFile(t, in, "a.pdf", "same content")
+		testutil.WriteFile(t, in, "b.pdf", "same content")
+
+		org := newTestOrganizer(t, organizer.Config{
+			InputFolder:  in,
+			OutputFolder: out,
+			Duplicates:   organizer.DuplicatesHardlink,
+			Hooks: []organizer.Hook{
+				{Event: organizer.HookPreMove, Command: `case "$GORGANIZER_SOURCE" in */a.pdf) exit 1;; esac`},
+			},
+		})
+		var result *organizer.OrganizeResult
+		var err error
+		if planned {
+			var plan *organizer.Plan
+			if plan, err = org.Plan(); err != nil {
+				t.Fatal(err)
+			}
+			result, err = org.Apply(plan)
+		} else {
+			result, err = org.Run()
+		}
+		if err != nil {
+			t.Fatalf("planned %v: %v", planned, err)
+		}
+
+		if b := findAction(t, result, "b.pdf"); b.Reason != organizer.ReasonVetoed || b.Linked {
+			t.Errorf("planned %v: b.pdf = %+v, want vetoed with a.pdf", planned, b)
+		}
+		for _, name := range []string{"a.pdf", "b.pdf"} {
+			if _, err := os.Stat(filepath.Join(in, name)); err != nil {
+				t.Errorf("planned %v: %s should stay in place: %v", planned, name, err)
+			}
+		}
+	}
+}
+
+func TestHooks_Failures(t *testing.T) {
+	t.Parallel()
+	skipWithoutShell(t)
+
+	t.Run("pre-run cancels the run", func(t *testing.T) {
+		t.Parallel()
+		in := t.TempDir()
+		testutil.WriteFile(t, in, "song.mp3", "music")
+
+		_, err := newTestOrganizer(t, organizer.Config{
+			InputFolder:  in,
+			OutputFolder: t.TempDir(),
+			Hooks:        []organizer.Hook{{Event: organizer.HookPreRun, Command: "exit 1"}},
+		}).Run()
+		if !errors.Is(err, organizer.ErrHookFailed) {
+			t.Errorf("Run() error = %v, want ErrHookFailed", err)
+		}
+		if _, err := os.Stat(filepath.Join(in, "song.mp3")); err != nil {
+			t.Errorf("file moved despite the failed pre-run hook: %v", err)
+		}
+	})
+
+	t.Run("post-move timeout", func(t *testing.T) {
+		t.Parallel()
+		in := t.TempDir()
+		testutil.WriteFile(t, in, "song.mp3", "music")
+
+		start := time.Now()
+		result, err := newTestOrganizer(t, organizer.Config{
+			InputFolder:  in,
+			OutputFolder: t.TempDir(),
+			Hooks: []organizer.Hook{
+				{Event: organizer.HookPostMove, Command: "sleep 10", Timeout: 100 * time.Millisecond},
+				{Event: organizer.HookPostRun, Command: "exit 2"},
+			},
+		}).Run()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if elapsed := time.Since(start); elapsed > 5*time.Second {
+			t.Errorf("run took %s, the hook was not stopped", elapsed)
+		}
+		song := findAction(t, result, "song.mp3")
+		if !song.Moved || !strings.Contains(song.HookError, "timed out") {
+			t.Errorf("song.mp3 = %+v, want moved with a timeout", song)
+		}
+		if !strings.Contains(result.HookError, "exit status 2") {
+			t.Errorf("result.HookError = %q, want the post-run failure", result.HookError)
+		}
+	})
+
+	t.Run("preview runs no hooks", func(t *testing.T) {
+		t.Parallel()
+		in := t.TempDir()
+		testutil.WriteFile(t, in, "song.mp3", "music")
+
+		_, err := newTestOrganizer(t, organizer.Config{
+			InputFolder:  in,
+			OutputFolder: t.TempDir(),
+			Preview:      true,
+			Hooks:        []organizer.Hook{{Event: organizer.HookPreRun, Command: "exit 1"}},
+		}).Run()
+		if err != nil {
+			t.Errorf("preview ran a hook: %v", err)
+		}
+	})
+}
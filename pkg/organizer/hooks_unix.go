@@ -0,0 +1,8 @@
+//go:build !windows
+
+package organizer
+
+// shellCommand returns the command line running command through the shell.
+func shellCommand(command string) []string {
+	return []string{"/bin/sh", "-c", command}
+}
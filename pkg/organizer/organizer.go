@@ -1,11 +1,17 @@
 // Package organizer scans directories and organizes files into folders
-// based on their extension using an ExtensionResolver.
+// based on their extension using an ExtensionResolver, or on anything else
+// about them using a Resolver.
 package organizer
 
 import (
+	"log/slog"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
+
+	"github.com/d6o/Gorganizer/internal/fsutil"
+	"github.com/d6o/Gorganizer/internal/trash"
 )
 
 // ExtensionResolver maps file extensions to destination folder names.
@@ -13,6 +19,12 @@ type ExtensionResolver interface {
 	Lookup(ext string) string
 }
 
+// Trash receives the files the organizer would otherwise delete or overwrite,
+// and returns the location they were moved to.
+type Trash interface {
+	Put(path string) (string, error)
+}
+
 // Config holds configuration for the Organizer.
 type Config struct {
 	InputFolder       string
@@ -21,97 +33,370 @@ type Config struct {
 	Recursive         bool
 	IgnoreHiddenFiles bool
 	ExcludeList       ExcludeList
+	Duplicates        DuplicatePolicy
+
+	// Resolver, if set, classifies files instead of the organizer's
+	// ExtensionResolver. Inputs with their own resolver still use theirs.
+	Resolver Resolver
+
+	// Archives selects whether archives are organized by their content and
+	// extracted. ArchiveLimits bounds the archives that are extracted.
+	Archives      ArchivePolicy
+	ArchiveLimits ArchiveLimits
+
+	// Rename, if set, normalizes or rewrites the names of the files that
+	// are moved. Preview reports the new names in FileAction.NewName.
+	Rename *Renamer
+
+	// Audit, if set, records every file operation Run and Apply carry out.
+	// RunID identifies the run in the log; a random one is used when empty.
+	Audit *AuditLog
+	RunID string
+
+	// Hooks are the commands run before and after the run and every file
+	// operation.
+	Hooks []Hook
+
+	// Permanent deletes files outright instead of moving them to the trash.
+	Permanent bool
+	// Trash overrides where removed files go. When nil, the user's
+	// freedesktop.org trash is used.
+	Trash Trash
+	// Logger receives how each file was classified and what was done to it
+	// at debug level, and problems at warn and error level. When nil,
+	// nothing is logged.
+	Logger *slog.Logger
+
+	// Progress, if set, is called with each file's action once the file has
+	// been handled.
+	Progress func(FileAction)
+
+	// Inputs lists the directories to organize, each with its own settings.
+	// When set, it replaces InputFolder, OutputFolder, Recursive,
+	// IgnoreHiddenFiles and ExcludeList; the remaining settings apply to
+	// every input.
+	Inputs []Input
+}
+
+// Input is one directory organized by a run.
+type Input struct {
+	Folder            string
+	OutputFolder      string
+	Recursive         bool
+	IgnoreHiddenFiles bool
+	ExcludeList       ExcludeList
+	// Resolver overrides the organizer's resolvers for this input. If it
+	// is also a Resolver, its Resolve method is used.
+	Resolver ExtensionResolver
 }
 
 // Organizer scans directories and organizes files by their extension.
 type Organizer struct {
 	resolver ExtensionResolver
 	config   Config
+	log      *slog.Logger
 }
 
 // NewOrganizer creates an Organizer with the given resolver and config.
 func NewOrganizer(resolver ExtensionResolver, config Config) *Organizer {
+	log := config.Logger
+	if log == nil {
+		log = slog.New(slog.DiscardHandler)
+	}
 	return &Organizer{
 		resolver: resolver,
 		config:   config,
+		log:      log,
 	}
 }
 
-// Run scans the input folder and organizes files according to the configured
+// Run scans the input folders and organizes files according to the configured
 // rules. It returns a structured result describing what happened to each file.
-// In preview mode, files are categorized but not moved.
+// In preview mode, files are categorized but not moved. When an operation
+// fails, Run stops and returns the result so far, the failed action's Error
+// set, along with the error.
 func (o *Organizer) Run() (*OrganizeResult, error) {
-	var actions []FileAction
+	start := time.Now()
+	result, err := o.Preview()
+	if err != nil {
+		return nil, err
+	}
+
+	if o.config.Preview {
+		for _, a := range result.Actions {
+			o.progress(a)
+		}
+		return result, nil
+	}
 
-	if err := o.scan(o.config.InputFolder, &actions); err != nil {
+	entries, index, err := o.entries(result.Actions)
+	if err != nil {
+		return nil, err
+	}
+
+	result.RunID = o.runID()
+	if err := o.runHooks(HookPayload{Event: HookPreRun, RunID: result.RunID, Result: result}); err != nil {
 		return nil, err
 	}
 
-	return &OrganizeResult{Actions: actions}, nil
+	next := 0
+	vetoed := make(map[string]bool)
+	for i := range result.Actions {
+		if next < len(entries) && index[next] == i {
+			if err := o.perform(result.RunID, entries[next], &result.Actions[i], vetoed); err != nil {
+				result.Actions[i].Error = err.Error()
+				result.Actions = result.Actions[:i+1]
+				result.summarize(start)
+				return result, err
+			}
+			next++
+		}
+		o.progress(result.Actions[i])
+	}
+
+	result.summarize(start)
+	o.postRun(result)
+	return result, nil
+}
+
+// postRun runs the post-run hooks and records their failure in result.
+func (o *Organizer) postRun(result *OrganizeResult) {
+	if err := o.runHooks(HookPayload{Event: HookPostRun, RunID: result.RunID, Result: result}); err != nil {
+		result.HookError = err.Error()
+	}
 }
 
-func (o *Organizer) scan(inputFolder string, actions *[]FileAction) error {
+func (o *Organizer) runID() string {
+	if o.config.RunID != "" {
+		return o.config.RunID
+	}
+	return NewRunID()
+}
+
+func (o *Organizer) progress(a FileAction) {
+	if o.config.Progress != nil {
+		o.config.Progress(a)
+	}
+}
+
+// inputs returns the directories to organize.
+func (o *Organizer) inputs() []Input {
+	if len(o.config.Inputs) > 0 {
+		return o.config.Inputs
+	}
+	return []Input{{
+		Folder:            o.config.InputFolder,
+		OutputFolder:      o.config.OutputFolder,
+		Recursive:         o.config.Recursive,
+		IgnoreHiddenFiles: o.config.IgnoreHiddenFiles,
+		ExcludeList:       o.config.ExcludeList,
+	}}
+}
+
+// output returns the output folder of the input a file was found in.
+func (o *Organizer) output(root string) string {
+	for _, in := range o.inputs() {
+		if in.Folder == root {
+			return in.OutputFolder
+		}
+	}
+	return o.config.OutputFolder
+}
+
+// destinationDir returns the directory a goes to.
+func (o *Organizer) destinationDir(a FileAction) string {
+	return DestinationDir(o.output(a.Root), a.Destination)
+}
+
+// DestinationDir returns the directory of a destination folder: the folder
+// itself when it is an absolute path, the folder in the home directory when
+// it starts with ~/, and the folder in output otherwise.
+func DestinationDir(output, folder string) string {
+	switch {
+	case fsutil.HomeRelative(folder):
+		if dir, err := fsutil.ExpandHome(folder); err == nil {
+			return dir
+		}
+	case filepath.IsAbs(folder):
+		return folder
+	}
+	return filepath.Join(output, folder)
+}
+
+// Preview scans the input folders and works out what should happen to each
+// file, without touching the file system, regardless of Config.Preview.
+func (o *Organizer) Preview() (*OrganizeResult, error) {
+	start := time.Now()
+	var actions []FileAction
+
+	for _, in := range o.inputs() {
+		if err := o.scan(in, o.resolverFor(in), in.Folder, &actions); err != nil {
+			return nil, err
+		}
+	}
+
+	result := &OrganizeResult{Actions: actions}
+
+	if o.config.Duplicates != DuplicatesOff {
+		groups, err := o.findDuplicates(result.Actions)
+		if err != nil {
+			return nil, err
+		}
+		result.Duplicates = groups
+	}
+
+	if o.config.Rename != nil {
+		if err := o.rename(result.Actions); err != nil {
+			return nil, err
+		}
+	}
+
+	result.summarize(start)
+	return result, nil
+}
+
+// resolverFor returns the resolver classifying the files of in.
+func (o *Organizer) resolverFor(in Input) Resolver {
+	switch {
+	case in.Resolver != nil:
+		return ExtensionRules(in.Resolver)
+	case o.config.Resolver != nil:
+		return o.config.Resolver
+	}
+	return ExtensionRules(o.resolver)
+}
+
+func (o *Organizer) scan(in Input, resolver Resolver, inputFolder string, actions *[]FileAction) error {
 	entries, err := os.ReadDir(inputFolder)
 	if err != nil {
 		return err
 	}
 
 	for _, entry := range entries {
-		if strings.HasPrefix(entry.Name(), ".") && !o.config.IgnoreHiddenFiles {
+		file := filepath.Join(inputFolder, entry.Name())
+		f := &File{Path: file, Name: entry.Name(), Mode: entry.Type()}
+		if info, err := entry.Info(); err == nil {
+			f = NewFile(file, info)
+		}
+		var size int64
+		if entry.Type().IsRegular() {
+			size = f.Size
+		}
+
+		if strings.HasPrefix(entry.Name(), ".") && !in.IgnoreHiddenFiles {
+			o.log.Debug("hidden file", "path", file)
 			*actions = append(*actions, FileAction{
 				FileName: entry.Name(),
+				Path:     file,
+				Size:     size,
+				Root:     in.Folder,
 				Reason:   ReasonHidden,
 			})
 			continue
 		}
 
-		if entry.IsDir() && o.config.Recursive {
-			if err := o.scan(filepath.Join(inputFolder, entry.Name()), actions); err != nil {
+		if entry.IsDir() && in.Recursive {
+			if err := o.scan(in, resolver, file, actions); err != nil {
 				return err
 			}
 		}
 
-		file := filepath.Join(inputFolder, entry.Name())
 		ext := strings.TrimPrefix(filepath.Ext(file), ".")
 
-		if o.config.ExcludeList.Contains(ext) {
+		if in.ExcludeList.Contains(ext) {
+			o.log.Debug("excluded extension", "path", file, "extension", ext)
 			*actions = append(*actions, FileAction{
 				FileName: entry.Name(),
+				Path:     file,
+				Size:     size,
+				Root:     in.Folder,
 				Reason:   ReasonExcluded,
 			})
 			continue
 		}
 
-		folder := o.resolver.Lookup(ext)
+		res, err := resolver.Resolve(f)
+		if err != nil {
+			o.log.Warn("cannot classify file", "path", file, "error", err)
+		}
+		folder, match := res.Destination, res.Reason
 
-		if folder != "" {
-			dest := filepath.Join(o.config.OutputFolder, folder)
-			newFile := filepath.Join(dest, entry.Name())
-
-			moved := false
-			if !o.config.Preview {
-				if err := os.Mkdir(dest, os.ModePerm); err != nil && !os.IsExist(err) {
-					return err
-				}
-				if err := os.Rename(file, newFile); err != nil {
-					return err
-				}
-				moved = true
+		var archive *ArchiveInfo
+		if o.config.Archives != ArchivesOff && entry.Type().IsRegular() {
+			archive = o.inspectArchive(file, resolver)
+			if archive != nil && archive.Error != "" {
+				o.log.Warn("cannot inspect archive", "path", file, "error", archive.Error)
+			}
+			if archive != nil && archive.Folder != "" {
+				o.log.Debug("archive classified by content", "path", file, "files", archive.Files, "folder", archive.Folder)
+				folder, match = archive.Folder, "archive content"
 			}
+		}
 
+		if folder != "" {
+			o.log.Debug("matched rule", "path", file, "extension", ext, "folder", folder, "match", match)
 			*actions = append(*actions, FileAction{
 				FileName:    entry.Name(),
+				Path:        file,
+				Size:        size,
+				Root:        in.Folder,
 				Destination: folder,
+				Match:       match,
 				Reason:      ReasonOrganized,
-				Moved:       moved,
+				Archive:     archive,
 			})
 		} else {
+			o.log.Debug("no rule", "path", file, "extension", ext)
 			*actions = append(*actions, FileAction{
 				FileName: entry.Name(),
+				Path:     file,
+				Size:     size,
+				Root:     in.Folder,
 				Reason:   ReasonUnknownExtension,
+				Archive:  archive,
 			})
 		}
 	}
 
 	return nil
 }
+
+// clear removes a file standing at target so that source can take its place.
+// It returns the trash location of the removed file, if any.
+func (o *Organizer) clear(source, target string) (string, error) {
+	targetInfo, err := os.Lstat(target)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", err
+	}
+
+	sourceInfo, err := os.Lstat(source)
+	if err != nil {
+		return "", err
+	}
+	if os.SameFile(sourceInfo, targetInfo) {
+		return "", nil
+	}
+
+	return o.remove(target)
+}
+
+// remove deletes path, moving it to the trash unless Permanent is set. It
+// returns the trash location, or an empty string for permanent deletes.
+func (o *Organizer) remove(path string) (string, error) {
+	if o.config.Permanent {
+		return "", os.Remove(path)
+	}
+
+	if o.config.Trash == nil {
+		t, err := trash.New()
+		if err != nil {
+			return "", err
+		}
+		o.config.Trash = t
+	}
+
+	return o.config.Trash.Put(path)
+}
@@ -1,10 +1,15 @@
 package organizer_test
 
 import (
+	"bytes"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/d6o/Gorganizer/internal/testutil"
+	"github.com/d6o/Gorganizer/internal/trash"
 	"github.com/d6o/Gorganizer/pkg/organizer"
 )
 
@@ -27,22 +32,30 @@ func newMockResolver() *mockResolver {
 	}
 }
 
-func createTestFile(t *testing.T, dir, name string) {
+func newTestTrash(t *testing.T) *trash.Trash {
 	t.Helper()
-	f, err := os.Create(filepath.Join(dir, name))
+	tr, err := trash.New(trash.WithHomeTrash(filepath.Join(t.TempDir(), "Trash")))
 	if err != nil {
 		t.Fatal(err)
 	}
-	if err := f.Close(); err != nil {
-		t.Fatal(err)
+	return tr
+}
+
+// newTestOrganizer returns an organizer with the mock rules and config,
+// sending removed files to a test trash unless config has one.
+func newTestOrganizer(t *testing.T, config organizer.Config) *organizer.Organizer {
+	t.Helper()
+	if config.Trash == nil {
+		config.Trash = newTestTrash(t)
 	}
+	return organizer.NewOrganizer(newMockResolver(), config)
 }
 
 func TestOrganizer_Run_EmptyDirectory(t *testing.T) {
 	t.Parallel()
 	dir := t.TempDir()
 
-	org := organizer.NewOrganizer(newMockResolver(), organizer.Config{
+	org := newTestOrganizer(t, organizer.Config{
 		InputFolder:       dir,
 		OutputFolder:      dir,
 		Preview:           true,
@@ -62,11 +75,11 @@ func TestOrganizer_Run_CategorizesFiles(t *testing.T) {
 	t.Parallel()
 	dir := t.TempDir()
 
-	createTestFile(t, dir, "song.mp3")
-	createTestFile(t, dir, "doc.pdf")
-	createTestFile(t, dir, "photo.jpg")
+	testutil.WriteFile(t, dir, "song.mp3", "")
+	testutil.WriteFile(t, dir, "doc.pdf", "")
+	testutil.WriteFile(t, dir, "photo.jpg", "")
 
-	org := organizer.NewOrganizer(newMockResolver(), organizer.Config{
+	org := newTestOrganizer(t, organizer.Config{
 		InputFolder:       dir,
 		OutputFolder:      dir,
 		Preview:           true,
@@ -99,9 +112,9 @@ func TestOrganizer_Run_PreviewDoesNotMove(t *testing.T) {
 	t.Parallel()
 	dir := t.TempDir()
 
-	createTestFile(t, dir, "song.mp3")
+	testutil.WriteFile(t, dir, "song.mp3", "")
 
-	org := organizer.NewOrganizer(newMockResolver(), organizer.Config{
+	org := newTestOrganizer(t, organizer.Config{
 		InputFolder:       dir,
 		OutputFolder:      dir,
 		Preview:           true,
@@ -129,9 +142,9 @@ func TestOrganizer_Run_MovesFiles(t *testing.T) {
 	dir := t.TempDir()
 	out := t.TempDir()
 
-	createTestFile(t, dir, "song.mp3")
+	testutil.WriteFile(t, dir, "song.mp3", "")
 
-	org := organizer.NewOrganizer(newMockResolver(), organizer.Config{
+	org := newTestOrganizer(t, organizer.Config{
 		InputFolder:       dir,
 		OutputFolder:      out,
 		Preview:           false,
@@ -164,13 +177,38 @@ func TestOrganizer_Run_MovesFiles(t *testing.T) {
 	}
 }
 
+func TestOrganizer_Run_StopsAtFailure(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	out := t.TempDir()
+
+	testutil.WriteFile(t, dir, "a.mp3", "")
+	testutil.WriteFile(t, dir, "b.pdf", "")
+	// A file where the Music folder should be makes the move fail.
+	testutil.WriteFile(t, out, "Music", "")
+
+	result, err := newTestOrganizer(t, organizer.Config{InputFolder: dir, OutputFolder: out}).Run()
+	if err == nil {
+		t.Fatal("Run() should fail")
+	}
+	if result == nil || len(result.Actions) != 1 || result.Actions[0].FileName != "a.mp3" || result.Actions[0].Error == "" {
+		t.Fatalf("result = %+v, want only the failed a.mp3", result)
+	}
+	if result.Summary == nil || result.Summary.Files != 1 {
+		t.Errorf("summary = %+v, want the one file handled", result.Summary)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "b.pdf")); err != nil {
+		t.Error("b.pdf should be left alone after the failure")
+	}
+}
+
 func TestOrganizer_Run_UnknownExtension(t *testing.T) {
 	t.Parallel()
 	dir := t.TempDir()
 
-	createTestFile(t, dir, "data.xyz")
+	testutil.WriteFile(t, dir, "data.xyz", "")
 
-	org := organizer.NewOrganizer(newMockResolver(), organizer.Config{
+	org := newTestOrganizer(t, organizer.Config{
 		InputFolder:       dir,
 		OutputFolder:      dir,
 		Preview:           true,
@@ -197,10 +235,10 @@ func TestOrganizer_Run_ExcludeList(t *testing.T) {
 	t.Parallel()
 	dir := t.TempDir()
 
-	createTestFile(t, dir, "song.mp3")
-	createTestFile(t, dir, "doc.pdf")
+	testutil.WriteFile(t, dir, "song.mp3", "")
+	testutil.WriteFile(t, dir, "doc.pdf", "")
 
-	org := organizer.NewOrganizer(newMockResolver(), organizer.Config{
+	org := newTestOrganizer(t, organizer.Config{
 		InputFolder:       dir,
 		OutputFolder:      dir,
 		Preview:           true,
@@ -232,10 +270,10 @@ func TestOrganizer_Run_Recursive(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	createTestFile(t, dir, "top.mp3")
-	createTestFile(t, subdir, "nested.pdf")
+	testutil.WriteFile(t, dir, "top.mp3", "")
+	testutil.WriteFile(t, subdir, "nested.pdf", "")
 
-	org := organizer.NewOrganizer(newMockResolver(), organizer.Config{
+	org := newTestOrganizer(t, organizer.Config{
 		InputFolder:       dir,
 		OutputFolder:      dir,
 		Preview:           true,
@@ -265,10 +303,10 @@ func TestOrganizer_Run_HiddenFiles(t *testing.T) {
 	t.Parallel()
 	dir := t.TempDir()
 
-	createTestFile(t, dir, ".hidden")
-	createTestFile(t, dir, "visible.mp3")
+	testutil.WriteFile(t, dir, ".hidden", "")
+	testutil.WriteFile(t, dir, "visible.mp3", "")
 
-	org := organizer.NewOrganizer(newMockResolver(), organizer.Config{
+	org := newTestOrganizer(t, organizer.Config{
 		InputFolder:       dir,
 		OutputFolder:      dir,
 		Preview:           true,
@@ -286,3 +324,243 @@ func TestOrganizer_Run_HiddenFiles(t *testing.T) {
 		}
 	}
 }
+
+func TestOrganizer_Run_TrashesOverwrittenFiles(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	out := t.TempDir()
+
+	music := filepath.Join(out, "Music")
+	if err := os.Mkdir(music, os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	testutil.WriteFile(t, music, "song.mp3", "")
+	testutil.WriteFile(t, dir, "song.mp3", "")
+
+	org := newTestOrganizer(t, organizer.Config{
+		InputFolder:       dir,
+		OutputFolder:      out,
+		IgnoreHiddenFiles: true,
+		Trash:             newTestTrash(t),
+	})
+
+	result, err := org.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a := result.Actions[0]
+	if !a.Moved {
+		t.Fatal("expected song.mp3 to be moved")
+	}
+	if a.Trashed == "" {
+		t.Fatal("expected the overwritten file to be trashed")
+	}
+	if _, err := os.Stat(a.Trashed); err != nil {
+		t.Errorf("trashed file should exist: %v", err)
+	}
+}
+
+func TestOrganizer_Run_Permanent(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	out := t.TempDir()
+
+	music := filepath.Join(out, "Music")
+	if err := os.Mkdir(music, os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	testutil.WriteFile(t, music, "song.mp3", "")
+	testutil.WriteFile(t, dir, "song.mp3", "")
+
+	org := newTestOrganizer(t, organizer.Config{
+		InputFolder:       dir,
+		OutputFolder:      out,
+		IgnoreHiddenFiles: true,
+		Permanent:         true,
+	})
+
+	result, err := org.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Actions[0].Trashed != "" {
+		t.Errorf("permanent mode should not trash, got %q", result.Actions[0].Trashed)
+	}
+}
+
+func TestOrganizer_Run_Progress(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	out := t.TempDir()
+
+	testutil.WriteFile(t, dir, "song.mp3", "")
+	testutil.WriteFile(t, dir, "data.xyz", "")
+
+	var seen []organizer.FileAction
+	org := newTestOrganizer(t, organizer.Config{
+		InputFolder:       dir,
+		OutputFolder:      out,
+		IgnoreHiddenFiles: true,
+		Progress: func(a organizer.FileAction) {
+			seen = append(seen, a)
+		},
+	})
+
+	result, err := org.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(seen) != len(result.Actions) {
+		t.Fatalf("progress called %d times, want %d", len(seen), len(result.Actions))
+	}
+	for i, a := range seen {
+		if a != result.Actions[i] {
+			t.Errorf("progress[%d] = %+v, want %+v", i, a, result.Actions[i])
+		}
+	}
+}
+
+func TestOrganizer_Run_MultipleInputs(t *testing.T) {
+	t.Parallel()
+	downloads := t.TempDir()
+	scans := t.TempDir()
+	scansOut := t.TempDir()
+
+	testutil.WriteFile(t, downloads, "song.mp3", "")
+	testutil.WriteFile(t, downloads, "doc.pdf", "")
+	testutil.WriteFile(t, scans, "scan.pdf", "")
+	testutil.WriteFile(t, scans, "song.mp3", "")
+
+	org := newTestOrganizer(t, organizer.Config{
+		Inputs: []organizer.Input{
+			{Folder: downloads, OutputFolder: downloads, IgnoreHiddenFiles: true},
+			{
+				Folder:            scans,
+				OutputFolder:      scansOut,
+				IgnoreHiddenFiles: true,
+				ExcludeList:       organizer.ExcludeList{"mp3"},
+				Resolver:          &mockResolver{rules: map[string]string{"pdf": "Scans"}},
+			},
+		},
+	})
+
+	result, err := org.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{
+		filepath.Join(downloads, "Music", "song.mp3"),
+		filepath.Join(downloads, "Documents", "doc.pdf"),
+		filepath.Join(scansOut, "Scans", "scan.pdf"),
+		filepath.Join(scans, "song.mp3"),
+	} {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("expected %s: %v", path, err)
+		}
+	}
+
+	groups := result.ByRoot()
+	if len(groups) != 2 || groups[0].Root != downloads || groups[1].Root != scans {
+		t.Fatalf("ByRoot = %+v, want downloads then scans", groups)
+	}
+	if len(groups[0].Actions) != 2 || len(groups[1].Actions) != 2 {
+		t.Errorf("ByRoot sizes = %d, %d, want 2, 2", len(groups[0].Actions), len(groups[1].Actions))
+	}
+	for _, a := range groups[1].Actions {
+		if a.FileName == "song.mp3" && a.Reason != organizer.ReasonExcluded {
+			t.Errorf("song.mp3 in scans should use that input's exclude list, got %s", a.Reason)
+		}
+	}
+}
+
+func TestOrganizer_Run_Logs(t *testing.T) {
+	t.Parallel()
+	in := t.TempDir()
+	out := t.TempDir()
+	testutil.WriteFile(t, in, "song.mp3", "")
+	testutil.WriteFile(t, in, "notes.xyz", "")
+	testutil.WriteFile(t, in, "broken.zip", "not a zip")
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	org := newTestOrganizer(t, organizer.Config{
+		InputFolder:  in,
+		OutputFolder: out,
+		Archives:     organizer.ArchivesInspect,
+		Permanent:    true,
+		Logger:       logger,
+	})
+	if _, err := org.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{
+		`level=DEBUG msg="matched rule" path=` + filepath.Join(in, "song.mp3") + " extension=mp3 folder=Music",
+		`level=DEBUG msg="no rule" path=` + filepath.Join(in, "notes.xyz"),
+		`level=WARN msg="cannot inspect archive" path=` + filepath.Join(in, "broken.zip"),
+		`level=DEBUG msg=done op=move path=` + filepath.Join(in, "song.mp3"),
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("log lacks %q:\n%s", want, buf.String())
+		}
+	}
+}
+
+func TestOrganizer_Run_DestinationRoots(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	out := t.TempDir()
+	videos := filepath.Join(t.TempDir(), "media", "Videos")
+
+	testutil.WriteFile(t, dir, "movie.mkv", "")
+	testutil.WriteFile(t, dir, "song.mp3", "")
+
+	resolver := newMockResolver()
+	resolver.rules["mkv"] = videos
+	org := organizer.NewOrganizer(resolver, organizer.Config{
+		InputFolder:  dir,
+		OutputFolder: out,
+	})
+	if _, err := org.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{
+		filepath.Join(videos, "movie.mkv"),
+		filepath.Join(out, "Music", "song.mp3"),
+	} {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("expected %s: %v", path, err)
+		}
+	}
+}
+
+func TestDestinationDir(t *testing.T) {
+	t.Parallel()
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Skip(err)
+	}
+	out := filepath.Join("srv", "out")
+	abs := filepath.Join(t.TempDir(), "Videos")
+
+	tests := []struct {
+		folder, want string
+	}{
+		{"Music", filepath.Join(out, "Music")},
+		{"Media/Videos", filepath.Join(out, "Media", "Videos")},
+		{abs, abs},
+		{"~", home},
+		{"~/Documents", filepath.Join(home, "Documents")},
+	}
+	for _, tt := range tests {
+		if got := organizer.DestinationDir(out, tt.folder); got != tt.want {
+			t.Errorf("DestinationDir(%q) = %q, want %q", tt.folder, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,392 @@
+package organizer
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/d6o/Gorganizer/internal/fsutil"
+)
+
+// PlanVersion is the version of the plan format written by WritePlan.
+const PlanVersion = 1
+
+// Operation is the kind of change a PlanEntry makes to the file system.
+type Operation string
+
+const (
+	// OpMove moves Source to Target, copying it when they are on different
+	// file systems.
+	OpMove Operation = "move"
+	// OpDelete removes Source, moving it to the trash unless deletes are
+	// permanent.
+	OpDelete Operation = "delete"
+	// OpLink creates Target as a hard link to LinkTo and removes Source.
+	// When Target and LinkTo are on different file systems, it moves
+	// Source to Target instead.
+	OpLink Operation = "link"
+	// OpExtract extracts the archive Source into the folder Target, then
+	// removes Source like OpDelete.
+	OpExtract Operation = "extract"
+)
+
+// Plan is the list of operations an organize run intends to perform. It can
+// be saved, reviewed or edited, and executed later with Organizer.Apply.
+// Input and Output are the folders of the run's first input; each entry
+// records the output folder of its own input.
+type Plan struct {
+	Version int         `json:"version"`
+	Created time.Time   `json:"created"`
+	Input   string      `json:"input"`
+	Output  string      `json:"output"`
+	Entries []PlanEntry `json:"entries"`
+}
+
+// PlanEntry is a single planned operation. Size and ModTime describe Source
+// when the plan was made; Apply skips the entry if they no longer match.
+type PlanEntry struct {
+	Op          Operation    `json:"op"`
+	Reason      ActionReason `json:"reason"`
+	Root        string       `json:"root,omitempty"`
+	Output      string       `json:"output,omitempty"`
+	Source      string       `json:"source"`
+	Target      string       `json:"target,omitempty"`
+	LinkTo      string       `json:"link_to,omitempty"`
+	DuplicateOf string       `json:"duplicate_of,omitempty"`
+	Size        int64        `json:"size"`
+	ModTime     time.Time    `json:"mtime"`
+}
+
+// ReadPlan decodes a plan written by WritePlan. It returns
+// ErrUnsupportedPlanVersion for plans of any other format version.
+func ReadPlan(r io.Reader) (*Plan, error) {
+	var p Plan
+	if err := json.NewDecoder(r).Decode(&p); err != nil {
+		return nil, err
+	}
+	if p.Version != PlanVersion {
+		return nil, ErrUnsupportedPlanVersion
+	}
+	return &p, nil
+}
+
+// WritePlan encodes p as indented JSON, suitable for editing by hand.
+func WritePlan(w io.Writer, p *Plan) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(p)
+}
+
+// Stale returns the entries whose source file is missing or has changed size
+// or modification time since the plan was made.
+func (p *Plan) Stale() []PlanEntry {
+	var stale []PlanEntry
+	for _, e := range p.Entries {
+		if !e.holds() {
+			stale = append(stale, e)
+		}
+	}
+	return stale
+}
+
+func (e PlanEntry) holds() bool {
+	info, err := os.Lstat(e.Source)
+	if err != nil {
+		return false
+	}
+	return info.Size() == e.Size && info.ModTime().Equal(e.ModTime)
+}
+
+// linkable reports whether the file a link entry points to exists. It may be
+// missing when the entry that would have moved it was dropped from the plan.
+func (e PlanEntry) linkable() bool {
+	if e.Op != OpLink {
+		return true
+	}
+	_, err := os.Lstat(e.LinkTo)
+	return err == nil
+}
+
+// action returns the FileAction describing e in a result, with the
+// destination expressed relative to the output folder of e's input when
+// possible. output is used for entries of plans that did not record it.
+func (e PlanEntry) action(output string) FileAction {
+	if e.Output != "" {
+		output = e.Output
+	}
+	a := FileAction{
+		FileName:    filepath.Base(e.Source),
+		Path:        e.Source,
+		Size:        e.Size,
+		Root:        e.Root,
+		Reason:      e.Reason,
+		DuplicateOf: e.DuplicateOf,
+	}
+
+	if e.Target != "" {
+		dir := filepath.Dir(e.Target)
+		if e.Op == OpExtract {
+			dir = e.Target
+		} else if name := filepath.Base(e.Target); name != a.FileName {
+			a.NewName = name
+		}
+		if rel, err := filepath.Rel(output, dir); err == nil && filepath.IsLocal(rel) {
+			dir = rel
+		}
+		a.Destination = dir
+	}
+
+	return a
+}
+
+// Plan scans the input folder and returns the operations Run would perform,
+// without changing anything.
+func (o *Organizer) Plan() (*Plan, error) {
+	result, err := o.Preview()
+	if err != nil {
+		return nil, err
+	}
+	return o.PlanFor(result.Actions)
+}
+
+// PlanFor returns the operations needed to carry out actions, typically those
+// of a Preview result after the caller changed some destinations or reasons.
+// All paths in the plan are absolute so it can be applied from any working
+// directory.
+func (o *Organizer) PlanFor(actions []FileAction) (*Plan, error) {
+	entries, _, err := o.entries(actions)
+	if err != nil {
+		return nil, err
+	}
+
+	first := o.inputs()[0]
+	p := &Plan{
+		Version: PlanVersion,
+		Created: time.Now(),
+		Input:   first.Folder,
+		Output:  first.OutputFolder,
+		Entries: entries,
+	}
+	if err := p.absolute(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *Plan) absolute() error {
+	paths := []*string{&p.Input, &p.Output}
+	for i := range p.Entries {
+		e := &p.Entries[i]
+		paths = append(paths, &e.Root, &e.Output, &e.Source, &e.Target, &e.LinkTo, &e.DuplicateOf)
+	}
+
+	for _, path := range paths {
+		if *path == "" {
+			continue
+		}
+		abs, err := filepath.Abs(*path)
+		if err != nil {
+			return err
+		}
+		*path = abs
+	}
+	return nil
+}
+
+// Apply executes a plan, typically one produced by Plan and saved with
+// WritePlan. Entries whose preconditions no longer hold, or links whose
+// original is missing, are skipped and reported with ReasonStale. The
+// organizer's resolver and scan settings are not used; only Permanent,
+// Trash, Audit and Hooks apply. Like Run, Apply stops at the first failed
+// operation and returns the result so far with the error.
+func (o *Organizer) Apply(p *Plan) (*OrganizeResult, error) {
+	start := time.Now()
+	result := &OrganizeResult{Actions: make([]FileAction, len(p.Entries)), RunID: o.runID()}
+	for i, e := range p.Entries {
+		result.Actions[i] = e.action(p.Output)
+	}
+	if err := o.runHooks(HookPayload{Event: HookPreRun, RunID: result.RunID, Result: result}); err != nil {
+		return nil, err
+	}
+
+	vetoed := make(map[string]bool)
+	for i, e := range p.Entries {
+		a := &result.Actions[i]
+
+		if !e.holds() || (!e.linkable() && !vetoed[e.LinkTo]) {
+			o.log.Warn("skipping file changed since planned", "path", e.Source)
+			a.Reason = ReasonStale
+			o.progress(*a)
+			continue
+		}
+
+		if err := o.perform(result.RunID, e, a, vetoed); err != nil {
+			a.Error = err.Error()
+			result.Actions = result.Actions[:i+1]
+			result.summarize(start)
+			return result, err
+		}
+		o.progress(*a)
+	}
+
+	result.summarize(start)
+	o.postRun(result)
+	return result, nil
+}
+
+// entries turns the decided actions into plan entries, in execution order.
+// The second return value maps each entry to the index of its action.
+func (o *Organizer) entries(actions []FileAction) ([]PlanEntry, []int, error) {
+	var entries []PlanEntry
+	var index []int
+	targets := make(map[string]string)
+
+	for i, a := range actions {
+		e := PlanEntry{
+			Reason:      a.Reason,
+			Root:        a.Root,
+			Output:      o.output(a.Root),
+			Source:      a.Path,
+			DuplicateOf: a.DuplicateOf,
+		}
+
+		switch {
+		case a.Reason == ReasonOrganized && o.extracts(a):
+			e.Op = OpExtract
+			e.Target = extractDir(o.destinationDir(a), a.TargetName())
+
+		case a.Reason == ReasonOrganized:
+			e.Op = OpMove
+			e.Target = o.target(a)
+			targets[a.Path] = e.Target
+
+		case a.Reason == ReasonDuplicate && o.config.Duplicates == DuplicatesDelete:
+			e.Op = OpDelete
+
+		case a.Reason == ReasonDuplicate && o.config.Duplicates == DuplicatesMove:
+			e.Op = OpMove
+			e.Target = o.target(a)
+
+		case a.Reason == ReasonDuplicate && o.config.Duplicates == DuplicatesHardlink:
+			e.Op = OpLink
+			e.Target = o.target(a)
+			e.LinkTo = a.DuplicateOf
+			if t, ok := targets[a.DuplicateOf]; ok {
+				e.LinkTo = t
+			}
+
+		default:
+			continue
+		}
+
+		info, err := os.Lstat(a.Path)
+		if err != nil {
+			return nil, nil, err
+		}
+		e.Size = info.Size()
+		e.ModTime = info.ModTime()
+
+		entries = append(entries, e)
+		index = append(index, i)
+	}
+
+	return entries, index, nil
+}
+
+// extracts reports whether the archive of a is extracted rather than moved.
+func (o *Organizer) extracts(a FileAction) bool {
+	return o.config.Archives == ArchivesExtract && a.Archive != nil && a.Archive.Error == ""
+}
+
+func (o *Organizer) target(a FileAction) string {
+	return filepath.Join(o.destinationDir(a), a.TargetName())
+}
+
+// apply performs a single plan entry and records the outcome on a.
+func (o *Organizer) apply(e PlanEntry, a *FileAction) error {
+	switch e.Op {
+	case OpMove:
+		if err := os.MkdirAll(filepath.Dir(e.Target), os.ModePerm); err != nil {
+			return err
+		}
+
+		trashed, err := o.clear(e.Source, e.Target)
+		if err != nil {
+			return err
+		}
+		a.Trashed = trashed
+
+		if err := fsutil.Move(e.Source, e.Target); err != nil {
+			return err
+		}
+		a.Moved = true
+
+	case OpDelete:
+		trashed, err := o.remove(e.Source)
+		if err != nil {
+			return err
+		}
+		a.Removed = true
+		a.Trashed = trashed
+
+	case OpLink:
+		if err := os.MkdirAll(filepath.Dir(e.Target), os.ModePerm); err != nil {
+			return err
+		}
+
+		if e.Target != e.LinkTo {
+			trashed, err := o.clear(e.LinkTo, e.Target)
+			if err != nil {
+				return err
+			}
+			a.Trashed = trashed
+
+			err = os.Link(e.LinkTo, e.Target)
+			if fsutil.CrossDevice(err) {
+				// A hard link cannot span file systems, so the duplicate
+				// is moved there instead.
+				o.log.Debug("moving duplicate, its original is on another file system", "path", e.Source, "original", e.LinkTo)
+				if err := fsutil.Move(e.Source, e.Target); err != nil {
+					return err
+				}
+				a.Moved = true
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+		}
+		// The content survives through the link, so the source is not trashed.
+		if err := os.Remove(e.Source); err != nil {
+			return err
+		}
+		a.Moved = true
+		a.Linked = true
+
+	case OpExtract:
+		extracted, err := o.extractArchive(e.Source, e.Target)
+		if err != nil {
+			return err
+		}
+		if a.Archive == nil {
+			format, _ := archiveFormat(filepath.Base(e.Source))
+			a.Archive = &ArchiveInfo{Format: format}
+		}
+		a.Archive.ExtractedTo = e.Target
+		a.Archive.Extracted = extracted
+
+		trashed, err := o.remove(e.Source)
+		if err != nil {
+			return err
+		}
+		a.Removed = true
+		a.Trashed = trashed
+
+	default:
+		return ErrUnknownOperation
+	}
+
+	return nil
+}
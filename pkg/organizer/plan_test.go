@@ -0,0 +1,275 @@
+package organizer_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/d6o/Gorganizer/internal/testutil"
+	"github.com/d6o/Gorganizer/pkg/organizer"
+)
+
+func TestPlan_DoesNotMove(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	out := t.TempDir()
+
+	testutil.WriteFile(t, dir, "song.mp3", "music")
+	testutil.WriteFile(t, dir, "data.xyz", "unknown")
+
+	plan, err := newTestOrganizer(t, organizer.Config{InputFolder: dir, OutputFolder: out, IgnoreHiddenFiles: true}).Plan()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(plan.Entries) != 1 {
+		t.Fatalf("expected 1 entry, got %d", len(plan.Entries))
+	}
+
+	e := plan.Entries[0]
+	if e.Op != organizer.OpMove {
+		t.Errorf("Op = %q, want move", e.Op)
+	}
+	if e.Target != filepath.Join(out, "Music", "song.mp3") {
+		t.Errorf("Target = %q", e.Target)
+	}
+	if e.Size != int64(len("music")) {
+		t.Errorf("Size = %d, want %d", e.Size, len("music"))
+	}
+	if _, err := os.Stat(filepath.Join(dir, "song.mp3")); err != nil {
+		t.Error("planning should not move files")
+	}
+}
+
+func TestPlan_RoundTrip(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+
+	testutil.WriteFile(t, dir, "song.mp3", "music")
+
+	plan, err := newTestOrganizer(t, organizer.Config{InputFolder: dir, OutputFolder: dir, IgnoreHiddenFiles: true}).Plan()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := organizer.WritePlan(&buf, plan); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `"reason": "organized"`) {
+		t.Errorf("plan should name reasons, got %s", buf.String())
+	}
+
+	got, err := organizer.ReadPlan(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Entries) != 1 || !got.Entries[0].ModTime.Equal(plan.Entries[0].ModTime) {
+		t.Errorf("ReadPlan() = %+v, want %+v", got.Entries, plan.Entries)
+	}
+	if len(got.Stale()) != 0 {
+		t.Error("fresh plan should not have stale entries")
+	}
+}
+
+func TestReadPlan_UnsupportedVersion(t *testing.T) {
+	t.Parallel()
+
+	_, err := organizer.ReadPlan(strings.NewReader(`{"version": 99, "entries": []}`))
+	if !errors.Is(err, organizer.ErrUnsupportedPlanVersion) {
+		t.Errorf("ReadPlan() error = %v, want ErrUnsupportedPlanVersion", err)
+	}
+}
+
+func TestApply_ExecutesPlan(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	out := t.TempDir()
+
+	testutil.WriteFile(t, dir, "song.mp3", "music")
+	testutil.WriteFile(t, dir, "doc.pdf", "document")
+
+	org := newTestOrganizer(t, organizer.Config{InputFolder: dir, OutputFolder: out, IgnoreHiddenFiles: true})
+	plan, err := org.Plan()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := org.Apply(plan)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, a := range result.Actions {
+		if !a.Moved {
+			t.Errorf("%s should be moved", a.FileName)
+		}
+	}
+	if a := findAction(t, result, "song.mp3"); a.Destination != "Music" {
+		t.Errorf("Destination = %q, want Music", a.Destination)
+	}
+	if _, err := os.Stat(filepath.Join(out, "Documents", "doc.pdf")); err != nil {
+		t.Error("doc.pdf should be organized")
+	}
+}
+
+func TestApply_MultipleInputs(t *testing.T) {
+	t.Parallel()
+	downloads := t.TempDir()
+	scans := t.TempDir()
+	scansOut := t.TempDir()
+
+	testutil.WriteFile(t, downloads, "song.mp3", "music")
+	testutil.WriteFile(t, scans, "scan.pdf", "document")
+
+	org := newTestOrganizer(t, organizer.Config{
+		Inputs: []organizer.Input{
+			{Folder: downloads, OutputFolder: downloads, IgnoreHiddenFiles: true},
+			{Folder: scans, OutputFolder: scansOut, IgnoreHiddenFiles: true},
+		},
+	})
+	plan, err := org.Plan()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan.Input != downloads || plan.Output != downloads {
+		t.Errorf("plan folders = %q, %q, want the first input's", plan.Input, plan.Output)
+	}
+
+	var buf bytes.Buffer
+	if err := organizer.WritePlan(&buf, plan); err != nil {
+		t.Fatal(err)
+	}
+	if plan, err = organizer.ReadPlan(&buf); err != nil {
+		t.Fatal(err)
+	}
+	result, err := org.Apply(plan)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if a := findAction(t, result, "song.mp3"); a.Destination != "Music" {
+		t.Errorf("song.mp3 Destination = %q, want Music", a.Destination)
+	}
+	if a := findAction(t, result, "scan.pdf"); a.Destination != "Documents" {
+		t.Errorf("scan.pdf Destination = %q, want Documents relative to its input's output", a.Destination)
+	}
+	if _, err := os.Stat(filepath.Join(scansOut, "Documents", "scan.pdf")); err != nil {
+		t.Error("scan.pdf should be organized into its input's output")
+	}
+}
+
+func TestApply_SkipsStaleEntries(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	out := t.TempDir()
+
+	testutil.WriteFile(t, dir, "song.mp3", "music")
+	testutil.WriteFile(t, dir, "doc.pdf", "document")
+
+	org := newTestOrganizer(t, organizer.Config{InputFolder: dir, OutputFolder: out, IgnoreHiddenFiles: true})
+	plan, err := org.Plan()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testutil.WriteFile(t, dir, "song.mp3", "a much longer recording")
+	if err := os.Remove(filepath.Join(dir, "doc.pdf")); err != nil {
+		t.Fatal(err)
+	}
+
+	if stale := plan.Stale(); len(stale) != 2 {
+		t.Errorf("Stale() returned %d entries, want 2", len(stale))
+	}
+
+	result, err := org.Apply(plan)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, a := range result.Actions {
+		if a.Reason != organizer.ReasonStale || a.Moved {
+			t.Errorf("%s: reason = %s moved = %v, want stale and not moved", a.FileName, a.Reason, a.Moved)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "song.mp3")); err != nil {
+		t.Error("changed file should stay in place")
+	}
+}
+
+func TestApply_EditedPlan(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	out := t.TempDir()
+
+	testutil.WriteFile(t, dir, "song.mp3", "music")
+	testutil.WriteFile(t, dir, "doc.pdf", "document")
+
+	org := newTestOrganizer(t, organizer.Config{InputFolder: dir, OutputFolder: out, IgnoreHiddenFiles: true})
+	plan, err := org.Plan()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var kept []organizer.PlanEntry
+	for _, e := range plan.Entries {
+		if filepath.Base(e.Source) == "doc.pdf" {
+			continue
+		}
+		e.Target = filepath.Join(out, "Podcasts", "2026", "song.mp3")
+		kept = append(kept, e)
+	}
+	plan.Entries = kept
+
+	result, err := org.Apply(plan)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(result.Actions) != 1 {
+		t.Fatalf("expected 1 action, got %d", len(result.Actions))
+	}
+	if want := filepath.Join("Podcasts", "2026"); result.Actions[0].Destination != want {
+		t.Errorf("Destination = %q, want %q", result.Actions[0].Destination, want)
+	}
+	if _, err := os.Stat(filepath.Join(out, "Podcasts", "2026", "song.mp3")); err != nil {
+		t.Error("song.mp3 should be moved to the redirected target")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "doc.pdf")); err != nil {
+		t.Error("dropped entry should not be applied")
+	}
+}
+
+func TestApply_UnknownOperation(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+
+	testutil.WriteFile(t, dir, "song.mp3", "music")
+	info, err := os.Stat(filepath.Join(dir, "song.mp3"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plan := &organizer.Plan{
+		Version: organizer.PlanVersion,
+		Created: time.Now(),
+		Entries: []organizer.PlanEntry{{
+			Op:      "shred",
+			Source:  filepath.Join(dir, "song.mp3"),
+			Size:    info.Size(),
+			ModTime: info.ModTime(),
+		}},
+	}
+
+	result, err := newTestOrganizer(t, organizer.Config{InputFolder: dir, OutputFolder: dir, IgnoreHiddenFiles: true}).Apply(plan)
+	if !errors.Is(err, organizer.ErrUnknownOperation) {
+		t.Errorf("Apply() error = %v, want ErrUnknownOperation", err)
+	}
+	if result == nil || len(result.Actions) != 1 || result.Actions[0].Error == "" {
+		t.Errorf("result = %+v, want the failed action with its error", result)
+	}
+}
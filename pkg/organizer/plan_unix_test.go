@@ -0,0 +1,92 @@
+//go:build !windows
+
+package organizer_test
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/d6o/Gorganizer/internal/testutil"
+	"github.com/d6o/Gorganizer/pkg/organizer"
+)
+
+// otherDevice returns a directory on another file system than dir, or
+// skips the test when there is none.
+func otherDevice(t *testing.T, dir string) string {
+	t.Helper()
+	other, err := os.MkdirTemp("/dev/shm", "organizer")
+	if err != nil {
+		t.Skip("no tmpfs to organize files into:", err)
+	}
+	t.Cleanup(func() {
+		_ = os.RemoveAll(other)
+	})
+	if device(t, other) == device(t, dir) {
+		t.Skip("/dev/shm is on the same file system")
+	}
+	return other
+}
+
+func device(t *testing.T, path string) uint64 {
+	t.Helper()
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return uint64(info.Sys().(*syscall.Stat_t).Dev) //nolint:unconvert // Dev is not uint64 on every platform
+}
+
+func TestOrganizer_Run_AcrossFileSystems(t *testing.T) {
+	t.Parallel()
+	in := t.TempDir()
+	out := otherDevice(t, in)
+	testutil.WriteFile(t, in, "song.mp3", "music")
+
+	result, err := newTestOrganizer(t, organizer.Config{InputFolder: in, OutputFolder: out}).Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a := findAction(t, result, "song.mp3"); !a.Moved {
+		t.Errorf("song.mp3 = %+v, want moved", a)
+	}
+	data, err := os.ReadFile(filepath.Join(out, "Music", "song.mp3"))
+	if err != nil || string(data) != "music" {
+		t.Errorf("moved song.mp3 = %q, %v, want its content", data, err)
+	}
+	if _, err := os.Stat(filepath.Join(in, "song.mp3")); !os.IsNotExist(err) {
+		t.Error("source of the move should be gone")
+	}
+}
+
+func TestDuplicates_HardlinkAcrossFileSystems(t *testing.T) {
+	t.Parallel()
+	first := t.TempDir()
+	second := t.TempDir()
+	other := otherDevice(t, second)
+	testutil.WriteFile(t, first, "a.pdf", "same content")
+	testutil.WriteFile(t, second, "b.pdf", "same content")
+
+	result, err := newTestOrganizer(t, organizer.Config{
+		Duplicates: organizer.DuplicatesHardlink,
+		Inputs: []organizer.Input{
+			{Folder: first, OutputFolder: first},
+			{Folder: second, OutputFolder: other},
+		},
+	}).Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if b := findAction(t, result, "b.pdf"); !b.Moved || b.Linked {
+		t.Errorf("b.pdf = %+v, want moved instead of linked", b)
+	}
+	data, err := os.ReadFile(filepath.Join(other, "Documents", "b.pdf"))
+	if err != nil || string(data) != "same content" {
+		t.Errorf("b.pdf = %q, %v, want it moved to the other file system", data, err)
+	}
+	if _, err := os.Stat(filepath.Join(first, "Documents", "a.pdf")); err != nil {
+		t.Errorf("the original should be organized: %v", err)
+	}
+}
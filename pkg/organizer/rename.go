@@ -0,0 +1,361 @@
+package organizer
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// RenameStep is one normalization applied to file names as they are
+// organized.
+type RenameStep string
+
+const (
+	// RenameURLDecode decodes percent-encoded names, as saved by browsers:
+	// "Invoice%20(3).pdf" becomes "Invoice (3).pdf". Names that would decode
+	// to a path separator are left alone.
+	RenameURLDecode RenameStep = "url-decode"
+	// RenameTransliterate replaces accented and other Latin letters with
+	// their closest ASCII spelling: "Café Ørsted.pdf" becomes
+	// "Cafe Orsted.pdf". Characters without one are kept.
+	RenameTransliterate RenameStep = "transliterate"
+	// RenameSafe strips control characters and the characters Windows does
+	// not allow in names (<>:"/\|?*), and trailing dots and spaces.
+	RenameSafe RenameStep = "safe"
+	// RenameWhitespace turns any run of white space into a single space and
+	// trims it around the name and before the extension.
+	RenameWhitespace RenameStep = "whitespace"
+	// RenameNFC converts the name to Unicode normalization form C, so that
+	// names typed on different systems compare equal.
+	RenameNFC RenameStep = "nfc"
+	// RenameLowerExtension lower cases the extension: "IMG_1.JPG" becomes
+	// "IMG_1.jpg".
+	RenameLowerExtension RenameStep = "lower-ext"
+)
+
+// DefaultRenameSteps are the steps "all" stands for, in the order they are
+// applied.
+var DefaultRenameSteps = []RenameStep{
+	RenameURLDecode,
+	RenameTransliterate,
+	RenameSafe,
+	RenameWhitespace,
+	RenameNFC,
+	RenameLowerExtension,
+}
+
+var renameSteps = map[RenameStep]func(string) string{
+	RenameURLDecode:      urlDecode,
+	RenameTransliterate:  transliterate,
+	RenameSafe:           stripUnsafe,
+	RenameWhitespace:     collapseWhitespace,
+	RenameNFC:            norm.NFC.String,
+	RenameLowerExtension: lowerExtension,
+}
+
+// ParseRenameSteps converts a comma separated list of step names to the
+// steps to apply, in the order given. "all" stands for DefaultRenameSteps.
+// Returns ErrUnknownRenameStep for any other name.
+func ParseRenameSteps(list string) ([]RenameStep, error) {
+	var steps []RenameStep
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		switch {
+		case name == "":
+		case name == "all":
+			steps = append(steps, DefaultRenameSteps...)
+		case renameSteps[RenameStep(name)] != nil:
+			steps = append(steps, RenameStep(name))
+		default:
+			return nil, fmt.Errorf("%w: %q", ErrUnknownRenameStep, name)
+		}
+	}
+	return steps, nil
+}
+
+// Renamer gives files a new name as they are organized: it normalizes
+// their name with a list of steps, then optionally builds the name from a
+// template.
+//
+// A template is text with placeholders in braces, and gives the name
+// without its extension; the file's own extension is always kept.
+//
+//	{name}            the normalized name, without extension
+//	{date}            the modification date, as 2006-01-02
+//	{date:LAYOUT}     the modification time in a Go time layout
+//	{counter}         the file's position among those organized into the
+//	                  same folder during the run, from 1
+//	{counter:WIDTH}   the counter padded with zeros to WIDTH digits
+type Renamer struct {
+	steps    []RenameStep
+	template []templatePart
+}
+
+// templatePart is literal text when field is empty, otherwise a
+// placeholder with an optional argument.
+type templatePart struct {
+	text  string
+	field string
+	arg   string
+}
+
+// NewRenamer returns a Renamer applying steps, then template if it is not
+// empty. Returns ErrUnknownRenameStep for steps it does not know and
+// ErrInvalidRenameTemplate for templates that do not parse or could produce
+// a path rather than a name.
+func NewRenamer(steps []RenameStep, template string) (*Renamer, error) {
+	for _, s := range steps {
+		if renameSteps[s] == nil {
+			return nil, fmt.Errorf("%w: %q", ErrUnknownRenameStep, s)
+		}
+	}
+	parts, err := parseTemplate(template)
+	if err != nil {
+		return nil, err
+	}
+	return &Renamer{steps: steps, template: parts}, nil
+}
+
+func parseTemplate(template string) ([]templatePart, error) {
+	var parts []templatePart
+	rest := template
+	for rest != "" {
+		open := strings.IndexByte(rest, '{')
+		if open < 0 {
+			parts = append(parts, templatePart{text: rest})
+			break
+		}
+		if open > 0 {
+			parts = append(parts, templatePart{text: rest[:open]})
+		}
+		end := strings.IndexByte(rest[open:], '}')
+		if end < 0 {
+			return nil, fmt.Errorf("%w: unclosed { in %q", ErrInvalidRenameTemplate, template)
+		}
+		field, arg, _ := strings.Cut(rest[open+1:open+end], ":")
+		parts = append(parts, templatePart{field: field, arg: arg})
+		rest = rest[open+end+1:]
+	}
+
+	for _, p := range parts {
+		switch p.field {
+		case "", "date":
+		case "name":
+			if p.arg != "" {
+				return nil, fmt.Errorf("%w: {name} takes no argument", ErrInvalidRenameTemplate)
+			}
+		case "counter":
+			if p.arg != "" {
+				if width, err := strconv.Atoi(p.arg); err != nil || width < 1 {
+					return nil, fmt.Errorf("%w: invalid counter width %q", ErrInvalidRenameTemplate, p.arg)
+				}
+			}
+		default:
+			return nil, fmt.Errorf("%w: unknown placeholder {%s}", ErrInvalidRenameTemplate, p.field)
+		}
+		if strings.ContainsAny(p.text+p.arg, `/\`) {
+			return nil, fmt.Errorf("%w: %q would not be a single name", ErrInvalidRenameTemplate, template)
+		}
+	}
+	return parts, nil
+}
+
+// Normalize applies the renamer's steps to name. It returns name unchanged
+// if the steps would leave nothing of it.
+func (r *Renamer) Normalize(name string) string {
+	normalized := name
+	for _, s := range r.steps {
+		normalized = renameSteps[s](normalized)
+	}
+	if strings.TrimSuffix(normalized, filepath.Ext(normalized)) == "" {
+		return name
+	}
+	return normalized
+}
+
+// Rename returns the name a file called name, last modified at modTime, gets
+// at its destination. counter is its position among the files organized
+// into the same folder.
+func (r *Renamer) Rename(name string, modTime time.Time, counter int) string {
+	name = r.Normalize(name)
+	if len(r.template) == 0 {
+		return name
+	}
+
+	ext := filepath.Ext(name)
+	var b strings.Builder
+	for _, p := range r.template {
+		switch p.field {
+		case "":
+			b.WriteString(p.text)
+		case "name":
+			b.WriteString(strings.TrimSuffix(name, ext))
+		case "date":
+			layout := p.arg
+			if layout == "" {
+				layout = time.DateOnly
+			}
+			b.WriteString(modTime.Format(layout))
+		case "counter":
+			width, _ := strconv.Atoi(p.arg)
+			fmt.Fprintf(&b, "%0*d", width, counter)
+		}
+	}
+
+	stem := strings.TrimSpace(b.String())
+	if stem == "" || stem == "." || stem == ".." {
+		return name
+	}
+	return stem + ext
+}
+
+// usesModTime reports whether the template needs the files' modification
+// time.
+func (r *Renamer) usesModTime() bool {
+	for _, p := range r.template {
+		if p.field == "date" {
+			return true
+		}
+	}
+	return false
+}
+
+func urlDecode(name string) string {
+	decoded, err := url.PathUnescape(name)
+	if err != nil || strings.ContainsAny(decoded, "/\\\x00") {
+		return name
+	}
+	return decoded
+}
+
+// asciiLetters spells the Latin letters that do not decompose into a base
+// letter and accents.
+var asciiLetters = strings.NewReplacer(
+	"ß", "ss", "Æ", "AE", "æ", "ae", "Œ", "OE", "œ", "oe",
+	"Ø", "O", "ø", "o", "Ł", "L", "ł", "l", "Đ", "D", "đ", "d",
+	"Ð", "D", "ð", "d", "Þ", "TH", "þ", "th", "ı", "i",
+)
+
+var stripMarks = transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+
+func transliterate(name string) string {
+	stripped, _, err := transform.String(stripMarks, asciiLetters.Replace(name))
+	if err != nil {
+		return name
+	}
+	return stripped
+}
+
+func stripUnsafe(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || strings.ContainsRune(`<>:"/\|?*`, r) {
+			return -1
+		}
+		return r
+	}, name)
+	return strings.TrimRight(name, ". ")
+}
+
+func collapseWhitespace(name string) string {
+	name = strings.Join(strings.Fields(name), " ")
+	ext := filepath.Ext(name)
+	return strings.TrimSpace(strings.TrimSuffix(name, ext)) + ext
+}
+
+func lowerExtension(name string) string {
+	ext := filepath.Ext(name)
+	return strings.TrimSuffix(name, ext) + strings.ToLower(ext)
+}
+
+// moves reports whether a's file is moved to a new name in its destination
+// folder.
+func (o *Organizer) moves(a FileAction) bool {
+	switch a.Reason {
+	case ReasonOrganized:
+		return !o.extracts(a)
+	case ReasonDuplicate:
+		return o.config.Duplicates == DuplicatesMove || o.config.Duplicates == DuplicatesHardlink
+	}
+	return false
+}
+
+// rename sets the NewName of the actions whose file is moved, numbering
+// the files of each destination folder in scan order. A new name that
+// clashes with another file of the run in the same folder, or with a file
+// already there, gets a " (2)"-style suffix.
+func (o *Organizer) rename(actions []FileAction) error {
+	r := o.config.Rename
+	counters := make(map[string]int)
+	taken := make(map[string]bool)
+	listed := make(map[string]bool)
+
+	for i := range actions {
+		a := &actions[i]
+		if !o.moves(*a) {
+			continue
+		}
+		dir := filepath.Dir(o.target(*a))
+		counters[dir]++
+
+		var modTime time.Time
+		if r.usesModTime() {
+			info, err := os.Lstat(a.Path)
+			if err != nil {
+				return err
+			}
+			modTime = info.ModTime()
+		}
+
+		if name := r.Rename(a.FileName, modTime, counters[dir]); name != a.FileName {
+			o.log.Debug("renaming", "path", a.Path, "name", name)
+			a.NewName = name
+		} else {
+			taken[filepath.Join(dir, a.FileName)] = true
+		}
+	}
+
+	for i := range actions {
+		a := &actions[i]
+		if a.NewName == "" {
+			continue
+		}
+		dir := filepath.Dir(o.target(*a))
+		if !listed[dir] {
+			listed[dir] = true
+			if err := takeExisting(taken, dir); err != nil {
+				return err
+			}
+		}
+		ext := filepath.Ext(a.NewName)
+		stem := strings.TrimSuffix(a.NewName, ext)
+		for n := 2; taken[filepath.Join(dir, a.NewName)]; n++ {
+			a.NewName = fmt.Sprintf("%s (%d)%s", stem, n, ext)
+		}
+		taken[filepath.Join(dir, a.NewName)] = true
+	}
+
+	return nil
+}
+
+// takeExisting marks the entries of dir as taken. A missing dir holds none.
+func takeExisting(taken map[string]bool, dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	for _, e := range entries {
+		taken[filepath.Join(dir, e.Name())] = true
+	}
+	return nil
+}
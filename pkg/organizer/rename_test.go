@@ -0,0 +1,223 @@
+package organizer_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/d6o/Gorganizer/internal/testutil"
+	"github.com/d6o/Gorganizer/pkg/organizer"
+)
+
+func newTestRenamer(t *testing.T, steps, template string) *organizer.Renamer {
+	t.Helper()
+	s, err := organizer.ParseRenameSteps(steps)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := organizer.NewRenamer(s, template)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func TestParseRenameSteps(t *testing.T) {
+	t.Parallel()
+
+	steps, err := organizer.ParseRenameSteps("nfc, lower-ext")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []organizer.RenameStep{organizer.RenameNFC, organizer.RenameLowerExtension}; !slices.Equal(steps, want) {
+		t.Errorf("steps = %v, want %v", steps, want)
+	}
+
+	if steps, _ := organizer.ParseRenameSteps("all"); !slices.Equal(steps, organizer.DefaultRenameSteps) {
+		t.Errorf("all = %v, want %v", steps, organizer.DefaultRenameSteps)
+	}
+	if _, err := organizer.ParseRenameSteps("upper"); !errors.Is(err, organizer.ErrUnknownRenameStep) {
+		t.Errorf("ParseRenameSteps(upper) = %v, want ErrUnknownRenameStep", err)
+	}
+}
+
+func TestRenamer_Normalize(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		steps string
+		name  string
+		want  string
+	}{
+		{"lower-ext", "IMG_20261001_0931.JPG", "IMG_20261001_0931.jpg"},
+		{"url-decode", "Invoice%20(3).PDF", "Invoice (3).PDF"},
+		{"url-decode", "a%2Fb.pdf", "a%2Fb.pdf"},
+		{"url-decode", "100%.pdf", "100%.pdf"},
+		{"transliterate", "Café Ørsted Straße.pdf", "Cafe Orsted Strasse.pdf"},
+		{"transliterate", "写真.jpg", "写真.jpg"},
+		{"safe", "what? <draft>.pdf", "what draft.pdf"},
+		{"safe", "notes.txt. ", "notes.txt"},
+		{"whitespace", "  my\t report  .pdf", "my report.pdf"},
+		{"nfc", "Cafe\u0301.pdf", "Caf\u00e9.pdf"},
+		{"safe", "???.pdf", "???.pdf"},
+		{"all", "Invoice%20%20(3)%20.PDF", "Invoice (3).pdf"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.steps+"/"+tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := newTestRenamer(t, tt.steps, "").Normalize(tt.name); got != tt.want {
+				t.Errorf("Normalize(%q) = %q, want %q", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenamer_Template(t *testing.T) {
+	t.Parallel()
+	modTime := time.Date(2026, 10, 1, 9, 31, 0, 0, time.UTC)
+
+	tests := []struct {
+		template string
+		name     string
+		want     string
+	}{
+		{"{date} {name}", "scan.pdf", "2026-10-01 scan.pdf"},
+		{"{date:20060102-1504}", "scan.pdf", "20261001-0931.pdf"},
+		{"photo-{counter:3}", "IMG_1.JPG", "photo-007.jpg"},
+		{"{counter}", "README", "7"},
+		{" {name} ", "scan.pdf", "scan.pdf"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.template, func(t *testing.T) {
+			t.Parallel()
+			r := newTestRenamer(t, "lower-ext", tt.template)
+			if got := r.Rename(tt.name, modTime, 7); got != tt.want {
+				t.Errorf("Rename(%q) = %q, want %q", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewRenamer_InvalidTemplate(t *testing.T) {
+	t.Parallel()
+
+	for _, template := range []string{"{name", "{size}", "{counter:0}", "{name:x}", "{date}/{name}", `{date:2006\01}`} {
+		if _, err := organizer.NewRenamer(nil, template); !errors.Is(err, organizer.ErrInvalidRenameTemplate) {
+			t.Errorf("NewRenamer(%q) = %v, want ErrInvalidRenameTemplate", template, err)
+		}
+	}
+}
+
+func TestOrganizer_Rename(t *testing.T) {
+	t.Parallel()
+	in := t.TempDir()
+	out := t.TempDir()
+
+	for _, name := range []string{"Invoice%20(3).pdf", "Invoice (3).pdf", "b.mp3", "a.mp3", "notes.xyz"} {
+		testutil.WriteFile(t, in, name, "")
+	}
+
+	config := organizer.Config{
+		InputFolder:  in,
+		OutputFolder: out,
+		Permanent:    true,
+		Rename:       newTestRenamer(t, "url-decode", ""),
+	}
+
+	result, err := newTestOrganizer(t, config).Preview()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a := findAction(t, result, "Invoice%20(3).pdf"); a.NewName != "Invoice (3) (2).pdf" {
+		t.Errorf("NewName = %q, want the decoded name made unique", a.NewName)
+	}
+	if a := findAction(t, result, "Invoice (3).pdf"); a.NewName != "" {
+		t.Errorf("unchanged name got NewName %q", a.NewName)
+	}
+
+	config.Rename = newTestRenamer(t, "", "track {counter:2}")
+	result, err = newTestOrganizer(t, config).Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, want := range map[string]string{
+		"a.mp3":             "Music/track 01.mp3",
+		"b.mp3":             "Music/track 02.mp3",
+		"Invoice (3).pdf":   "Documents/track 01.pdf",
+		"Invoice%20(3).pdf": "Documents/track 02.pdf",
+	} {
+		a := findAction(t, result, name)
+		if !a.Moved || a.NewName != filepath.Base(want) {
+			t.Errorf("%s: moved %v as %q, want %s", name, a.Moved, a.NewName, want)
+		}
+		if _, err := os.Stat(filepath.Join(out, filepath.FromSlash(want))); err != nil {
+			t.Error(err)
+		}
+	}
+	if a := findAction(t, result, "notes.xyz"); a.NewName != "" {
+		t.Errorf("file that is not moved got NewName %q", a.NewName)
+	}
+}
+
+func TestOrganizer_RenameKeepsExistingFiles(t *testing.T) {
+	t.Parallel()
+	out := t.TempDir()
+	config := organizer.Config{
+		OutputFolder: out,
+		Permanent:    true,
+		Rename:       newTestRenamer(t, "", "{counter}"),
+	}
+
+	for run, content := range []string{"first", "second"} {
+		config.InputFolder = t.TempDir()
+		testutil.WriteFile(t, config.InputFolder, "photo.jpg", content)
+		result, err := newTestOrganizer(t, config).Run()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if a := findAction(t, result, "photo.jpg"); !a.Moved {
+			t.Fatalf("run %d: photo.jpg = %+v, want moved", run+1, a)
+		}
+	}
+
+	for name, want := range map[string]string{"1.jpg": "first", "1 (2).jpg": "second"} {
+		data, err := os.ReadFile(filepath.Join(out, "Pictures", name))
+		if err != nil || string(data) != want {
+			t.Errorf("Pictures/%s = %q, %v, want %q", name, data, err, want)
+		}
+	}
+}
+
+func TestPlan_Rename(t *testing.T) {
+	t.Parallel()
+	in := t.TempDir()
+	out := t.TempDir()
+	testutil.WriteFile(t, in, "Song%201.mp3", "")
+
+	org := newTestOrganizer(t, organizer.Config{
+		InputFolder:  in,
+		OutputFolder: out,
+		Rename:       newTestRenamer(t, "all", ""),
+	})
+	plan, err := org.Plan()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(out, "Music", "Song 1.mp3"); plan.Entries[0].Target != want {
+		t.Fatalf("Target = %q, want %q", plan.Entries[0].Target, want)
+	}
+
+	result, err := org.Apply(plan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a := result.Actions[0]; a.FileName != "Song%201.mp3" || a.NewName != "Song 1.mp3" || !a.Moved {
+		t.Errorf("action = %+v, want Song%%201.mp3 moved as Song 1.mp3", a)
+	}
+}
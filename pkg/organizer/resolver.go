@@ -0,0 +1,162 @@
+package organizer
+
+import (
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// HeaderSize is how many bytes File.Header reads from the start of a file,
+// enough for http.DetectContentType.
+const HeaderSize = 512
+
+// File describes a file to classify.
+type File struct {
+	// Path is where the file is. For a file inside an archive, it is the
+	// archive's path followed by the file's name in the archive.
+	Path    string
+	Name    string
+	Size    int64
+	Mode    fs.FileMode
+	ModTime time.Time
+
+	open   func() (io.ReadCloser, error)
+	header []byte
+	err    error
+	read   bool
+}
+
+// NewFile describes the file at path with its info, reading its header
+// from path when it is asked for.
+func NewFile(path string, info fs.FileInfo) *File {
+	return &File{
+		Path:    path,
+		Name:    info.Name(),
+		Size:    info.Size(),
+		Mode:    info.Mode(),
+		ModTime: info.ModTime(),
+		open: func() (io.ReadCloser, error) {
+			return os.Open(path)
+		},
+	}
+}
+
+// Ext returns the file's extension without its dot, as it is written.
+func (f *File) Ext() string {
+	return strings.TrimPrefix(filepath.Ext(f.Name), ".")
+}
+
+// Header returns the first HeaderSize bytes of the file, fewer for shorter
+// files. It reads them on the first call only, and returns nil for
+// anything but regular files.
+func (f *File) Header() ([]byte, error) {
+	if f.read {
+		return f.header, f.err
+	}
+	f.read = true
+	if f.open == nil || !f.Mode.IsRegular() {
+		return nil, nil
+	}
+
+	r, err := f.open()
+	if err != nil {
+		f.err = err
+		return nil, err
+	}
+	defer func() {
+		_ = r.Close()
+	}()
+
+	buf := make([]byte, HeaderSize)
+	n, err := io.ReadFull(r, buf)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		f.err = err
+		return nil, err
+	}
+	f.header = buf[:n]
+	return f.header, nil
+}
+
+// Resolution is where a Resolver sends a file.
+type Resolution struct {
+	// Destination is the folder the file goes to, or empty when the
+	// resolver has no opinion about it.
+	Destination string
+	// Reason says why, such as "rule for mp3".
+	Reason string
+}
+
+// Resolver classifies files. Unlike an ExtensionResolver, it can look at
+// more than the extension, down to the content of the file.
+type Resolver interface {
+	Resolve(f *File) (Resolution, error)
+}
+
+// ResolverFunc adapts a function to a Resolver.
+type ResolverFunc func(f *File) (Resolution, error)
+
+// Resolve calls fn(f).
+func (fn ResolverFunc) Resolve(f *File) (Resolution, error) {
+	return fn(f)
+}
+
+// Chain is a Resolver trying resolvers in order. The first one finding a
+// destination, or failing, decides.
+type Chain []Resolver
+
+// Resolve returns the first resolution with a destination.
+func (c Chain) Resolve(f *File) (Resolution, error) {
+	for _, r := range c {
+		res, err := r.Resolve(f)
+		if err != nil || res.Destination != "" {
+			return res, err
+		}
+	}
+	return Resolution{}, nil
+}
+
+// ExtensionRules adapts an ExtensionResolver, such as store.Store, to a
+// Resolver that looks up the extension of files. A resolver that is already
+// a Resolver is returned as is.
+func ExtensionRules(r ExtensionResolver) Resolver {
+	if resolver, ok := r.(Resolver); ok {
+		return resolver
+	}
+	return ResolverFunc(func(f *File) (Resolution, error) {
+		ext := f.Ext()
+		folder := r.Lookup(ext)
+		if folder == "" {
+			return Resolution{}, nil
+		}
+		return Resolution{Destination: folder, Reason: "rule for " + strings.ToLower(ext)}, nil
+	})
+}
+
+// ContentTypes returns a Resolver sending files to a folder by the media
+// type of their content, as sniffed by http.DetectContentType. The keys of
+// folders are media types such as "application/pdf", or major types ending
+// with a slash such as "image/". A full media type wins over its major type.
+func ContentTypes(folders map[string]string) Resolver {
+	return ResolverFunc(func(f *File) (Resolution, error) {
+		header, err := f.Header()
+		if err != nil || len(header) == 0 {
+			return Resolution{}, err
+		}
+		ct, _, err := mime.ParseMediaType(http.DetectContentType(header))
+		if err != nil {
+			return Resolution{}, nil
+		}
+		major, _, _ := strings.Cut(ct, "/")
+		for _, key := range []string{ct, major + "/"} {
+			if folder, ok := folders[key]; ok {
+				return Resolution{Destination: folder, Reason: "content " + ct}, nil
+			}
+		}
+		return Resolution{}, nil
+	})
+}
@@ -0,0 +1,162 @@
+package organizer_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/d6o/Gorganizer/internal/testutil"
+	"github.com/d6o/Gorganizer/pkg/organizer"
+)
+
+// pngHeader is the signature of a PNG image.
+const pngHeader = "\x89PNG\r\n\x1a\n"
+
+func statTestFile(t *testing.T, path string) *organizer.File {
+	t.Helper()
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return organizer.NewFile(path, info)
+}
+
+func TestFile_Header(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	long := strings.Repeat("x", organizer.HeaderSize+100)
+	testutil.WriteFile(t, dir, "long.txt", long)
+	testutil.WriteFile(t, dir, "short.txt", "hi")
+
+	f := statTestFile(t, filepath.Join(dir, "long.txt"))
+	if f.Name != "long.txt" || f.Ext() != "txt" || f.Size != int64(len(long)) || f.ModTime.IsZero() {
+		t.Errorf("file = %+v", f)
+	}
+	header, err := f.Header()
+	if err != nil || string(header) != long[:organizer.HeaderSize] {
+		t.Fatalf("Header() = %d bytes, %v, want the first %d", len(header), err, organizer.HeaderSize)
+	}
+	// The header is read once.
+	if err := os.Remove(f.Path); err != nil {
+		t.Fatal(err)
+	}
+	if again, err := f.Header(); err != nil || len(again) != organizer.HeaderSize {
+		t.Errorf("second Header() = %d bytes, %v", len(again), err)
+	}
+
+	if header, err := statTestFile(t, filepath.Join(dir, "short.txt")).Header(); err != nil || string(header) != "hi" {
+		t.Errorf("short Header() = %q, %v", header, err)
+	}
+	if header, err := statTestFile(t, dir).Header(); err != nil || header != nil {
+		t.Errorf("directory Header() = %q, %v, want nothing", header, err)
+	}
+}
+
+func TestChain(t *testing.T) {
+	t.Parallel()
+	errBroken := errors.New("broken")
+	none := organizer.ResolverFunc(func(*organizer.File) (organizer.Resolution, error) {
+		return organizer.Resolution{}, nil
+	})
+	fixed := func(folder string) organizer.Resolver {
+		return organizer.ResolverFunc(func(*organizer.File) (organizer.Resolution, error) {
+			return organizer.Resolution{Destination: folder, Reason: "fixed"}, nil
+		})
+	}
+	broken := organizer.ResolverFunc(func(*organizer.File) (organizer.Resolution, error) {
+		return organizer.Resolution{}, errBroken
+	})
+	song := &organizer.File{Name: "song.mp3"}
+
+	tests := []struct {
+		name    string
+		chain   organizer.Chain
+		want    organizer.Resolution
+		wantErr error
+	}{
+		{"empty", nil, organizer.Resolution{}, nil},
+		{"first wins", organizer.Chain{none, fixed("A"), fixed("B")}, organizer.Resolution{Destination: "A", Reason: "fixed"}, nil},
+		{"extension rules", organizer.Chain{none, organizer.ExtensionRules(newMockResolver())}, organizer.Resolution{Destination: "Music", Reason: "rule for mp3"}, nil},
+		{"nobody knows", organizer.Chain{none, organizer.ExtensionRules(&mockResolver{})}, organizer.Resolution{}, nil},
+		{"error stops", organizer.Chain{broken, fixed("A")}, organizer.Resolution{}, errBroken},
+	}
+	for _, tt := range tests {
+		got, err := tt.chain.Resolve(song)
+		if got != tt.want || !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: Resolve() = %+v, %v, want %+v, %v", tt.name, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestContentTypes(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	testutil.WriteFile(t, dir, "scan", pngHeader+"rest")
+	testutil.WriteFile(t, dir, "doc", "%PDF-1.7\n")
+	testutil.WriteFile(t, dir, "empty", "")
+
+	r := organizer.ContentTypes(map[string]string{
+		"image/":          "Pictures",
+		"image/png":       "Screenshots",
+		"application/pdf": "Documents",
+	})
+	tests := []struct {
+		file string
+		want organizer.Resolution
+	}{
+		{"scan", organizer.Resolution{Destination: "Screenshots", Reason: "content image/png"}},
+		{"doc", organizer.Resolution{Destination: "Documents", Reason: "content application/pdf"}},
+		{"empty", organizer.Resolution{}},
+	}
+	for _, tt := range tests {
+		got, err := r.Resolve(statTestFile(t, filepath.Join(dir, tt.file)))
+		if err != nil || got != tt.want {
+			t.Errorf("Resolve(%s) = %+v, %v, want %+v", tt.file, got, err, tt.want)
+		}
+	}
+}
+
+func TestOrganizer_Resolver(t *testing.T) {
+	t.Parallel()
+	in := t.TempDir()
+	out := t.TempDir()
+	testutil.WriteFile(t, in, "song.mp3", "music")
+	testutil.WriteFile(t, in, "IMG_0001", pngHeader)
+	testutil.WriteFile(t, in, "notes", "plain text")
+	createZip(t, filepath.Join(in, "shots.zip"),
+		archiveFile{name: "a", content: pngHeader},
+		archiveFile{name: "b", content: pngHeader},
+	)
+
+	org := organizer.NewOrganizer(nil, organizer.Config{
+		InputFolder:  in,
+		OutputFolder: out,
+		Preview:      true,
+		Archives:     organizer.ArchivesInspect,
+		Resolver: organizer.Chain{
+			organizer.ContentTypes(map[string]string{"image/": "Pictures"}),
+			organizer.ExtensionRules(newMockResolver()),
+		},
+	})
+	result, err := org.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		file, folder, match string
+	}{
+		{"song.mp3", "Music", "rule for mp3"},
+		{"IMG_0001", "Pictures", "content image/png"},
+		{"notes", "", ""},
+		{"shots.zip", "Pictures", "archive content"},
+	}
+	for _, tt := range tests {
+		a := findAction(t, result, tt.file)
+		if a.Destination != tt.folder || a.Match != tt.match {
+			t.Errorf("%s went to %q because %q, want %q because %q", tt.file, a.Destination, a.Match, tt.folder, tt.match)
+		}
+	}
+}
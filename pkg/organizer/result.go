@@ -1,5 +1,10 @@
 package organizer
 
+import (
+	"fmt"
+	"time"
+)
+
 // ActionReason describes why a file was categorized in a particular way.
 type ActionReason int
 
@@ -12,19 +17,165 @@ const (
 	ReasonHidden
 	// ReasonUnknownExtension means no rule matched the file's extension.
 	ReasonUnknownExtension
+	// ReasonDuplicate means the file's content is identical to another file
+	// and it was handled according to the configured DuplicatePolicy.
+	ReasonDuplicate
+	// ReasonStale means a plan entry was skipped because its source file
+	// changed or disappeared after the plan was made.
+	ReasonStale
+	// ReasonVetoed means a pre-move hook refused the file's operation, or
+	// the move of the file a duplicate would be a hard link to.
+	ReasonVetoed
 )
 
+var reasonNames = map[ActionReason]string{
+	ReasonOrganized:        "organized",
+	ReasonExcluded:         "excluded",
+	ReasonHidden:           "hidden",
+	ReasonUnknownExtension: "unknown",
+	ReasonDuplicate:        "duplicate",
+	ReasonStale:            "stale",
+	ReasonVetoed:           "vetoed",
+}
+
+// String returns the lower case name of the reason.
+func (r ActionReason) String() string {
+	if name, ok := reasonNames[r]; ok {
+		return name
+	}
+	return fmt.Sprintf("ActionReason(%d)", int(r))
+}
+
+// MarshalText encodes the reason as its name.
+func (r ActionReason) MarshalText() ([]byte, error) {
+	name, ok := reasonNames[r]
+	if !ok {
+		return nil, fmt.Errorf("%w: %d", ErrUnknownReason, int(r))
+	}
+	return []byte(name), nil
+}
+
+// UnmarshalText decodes a reason from its name.
+func (r *ActionReason) UnmarshalText(text []byte) error {
+	for reason, name := range reasonNames {
+		if name == string(text) {
+			*r = reason
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %q", ErrUnknownReason, text)
+}
+
 // FileAction describes what happened (or would happen) to a single file
 // during an organize operation.
 type FileAction struct {
-	FileName    string
-	Destination string
-	Reason      ActionReason
-	Moved       bool
+	FileName string `json:"file_name"`
+	// NewName is the name the file gets at its destination when renaming
+	// is enabled and changes it.
+	NewName string `json:"new_name,omitempty"`
+	Path    string `json:"path"`
+	// Size is the size of the file when it was scanned.
+	Size int64 `json:"size,omitempty"`
+	// Root is the input folder the file was found in.
+	Root        string `json:"root,omitempty"`
+	Destination string `json:"destination,omitempty"`
+	// Match is why the resolver chose Destination, such as "rule for mp3".
+	Match  string       `json:"match,omitempty"`
+	Reason ActionReason `json:"reason"`
+	Moved  bool         `json:"moved"`
+
+	// DuplicateOf is the path of the file whose content this file repeats.
+	// It is only set when duplicate detection is enabled.
+	DuplicateOf string `json:"duplicate_of,omitempty"`
+	// Removed reports whether the file was deleted as a duplicate.
+	Removed bool `json:"removed,omitempty"`
+	// Linked reports whether the file was replaced with a hard link.
+	Linked bool `json:"linked,omitempty"`
+	// Trashed is where the file removed by this action was moved to: the
+	// file itself when it was deleted as a duplicate, or the file it
+	// replaced at its destination. Empty when nothing was trashed or the
+	// deletion was permanent.
+	Trashed string `json:"trashed,omitempty"`
+
+	// Archive describes the contents of the file when archives are
+	// inspected and the file is one, and what was extracted from it.
+	Archive *ArchiveInfo `json:"archive,omitempty"`
+
+	// Error is why the operation on the file failed.
+	Error string `json:"error,omitempty"`
+	// HookError is why the pre-move hook vetoed the operation, or why a
+	// post-move hook failed after it.
+	HookError string `json:"hook_error,omitempty"`
+}
+
+// TargetName returns the name the file has at its destination: NewName if
+// it is renamed, FileName otherwise.
+func (a FileAction) TargetName() string {
+	if a.NewName != "" {
+		return a.NewName
+	}
+	return a.FileName
+}
+
+// DuplicateGroup is a set of files sharing the same content. Original is the
+// copy that is kept: a file already present in a destination folder when
+// there is one, otherwise the first file encountered during the scan.
+type DuplicateGroup struct {
+	Hash       string   `json:"hash"`
+	Size       int64    `json:"size"`
+	Original   string   `json:"original"`
+	Duplicates []string `json:"duplicates"`
 }
 
 // OrganizeResult is the structured output of an organize operation,
 // containing one FileAction per file encountered.
 type OrganizeResult struct {
-	Actions []FileAction
+	// RunID identifies the run in the audit log. It is empty for previews.
+	RunID      string           `json:"run_id,omitempty"`
+	Actions    []FileAction     `json:"actions"`
+	Duplicates []DuplicateGroup `json:"duplicates,omitempty"`
+
+	// HookError is why a post-run hook failed.
+	HookError string `json:"hook_error,omitempty"`
+
+	// Elapsed is how long the run took, and Summary its totals. In JSON
+	// the time is only given in the summary.
+	Elapsed time.Duration `json:"-"`
+	Summary *Summary      `json:"summary,omitempty"`
+}
+
+// Extracted returns the files extracted from archives during the run.
+func (r *OrganizeResult) Extracted() []string {
+	var files []string
+	for _, a := range r.Actions {
+		if a.Archive != nil {
+			files = append(files, a.Archive.Extracted...)
+		}
+	}
+	return files
+}
+
+// InputResult holds the actions of the files found in one input folder.
+type InputResult struct {
+	Root    string       `json:"root"`
+	Actions []FileAction `json:"actions"`
+}
+
+// ByRoot groups the actions by the input folder their files were found in,
+// in the order the folders were scanned.
+func (r *OrganizeResult) ByRoot() []InputResult {
+	var groups []InputResult
+	index := make(map[string]int)
+
+	for _, a := range r.Actions {
+		i, ok := index[a.Root]
+		if !ok {
+			i = len(groups)
+			index[a.Root] = i
+			groups = append(groups, InputResult{Root: a.Root})
+		}
+		groups[i].Actions = append(groups[i].Actions, a)
+	}
+
+	return groups
 }
@@ -0,0 +1,177 @@
+package organizer
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// SummaryTop is how many unknown extensions and largest files a Summary
+// lists.
+const SummaryTop = 10
+
+// Summary gives the totals of an organize run.
+type Summary struct {
+	Files int   `json:"files"`
+	Bytes int64 `json:"bytes"`
+	// Folders counts the files per destination folder, the folder with the
+	// most files first. Skipped files are not counted.
+	Folders []Count `json:"folders,omitempty"`
+	// Reasons counts the files per ActionReason, in the order of the
+	// reasons.
+	Reasons []Count `json:"reasons"`
+	// UnknownExtensions are the most common extensions without a rule,
+	// without their dot, and Largest the largest files.
+	UnknownExtensions []Count      `json:"unknown_extensions,omitempty"`
+	Largest           []FileAction `json:"largest,omitempty"`
+	// Elapsed is how long the run took, as a duration such as "1.5s" in
+	// JSON.
+	Elapsed time.Duration `json:"-"`
+}
+
+// summaryJSON is the JSON form of a Summary.
+type summaryJSON struct {
+	*summaryFields
+	Elapsed string `json:"elapsed"`
+}
+
+type summaryFields Summary
+
+// MarshalJSON implements json.Marshaler.
+func (s Summary) MarshalJSON() ([]byte, error) {
+	return json.Marshal(summaryJSON{summaryFields: (*summaryFields)(&s), Elapsed: s.Elapsed.String()})
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (s *Summary) UnmarshalJSON(data []byte) error {
+	v := summaryJSON{summaryFields: (*summaryFields)(s)}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	if v.Elapsed == "" {
+		s.Elapsed = 0
+		return nil
+	}
+	d, err := time.ParseDuration(v.Elapsed)
+	if err != nil {
+		return fmt.Errorf("summary elapsed: %w", err)
+	}
+	s.Elapsed = d
+	return nil
+}
+
+// Count is the number and total size of the files sharing a folder, a
+// reason or an extension.
+type Count struct {
+	Name  string `json:"name"`
+	Files int    `json:"files"`
+	Bytes int64  `json:"bytes"`
+}
+
+// Summarize computes the totals of the result's actions.
+func (r *OrganizeResult) Summarize() *Summary {
+	s := &Summary{Elapsed: r.Elapsed}
+	folders := make(map[string]*Count)
+	reasons := make(map[ActionReason]*Count)
+	unknown := make(map[string]*Count)
+
+	add := func(m map[string]*Count, name string, a FileAction) {
+		c, ok := m[name]
+		if !ok {
+			c = &Count{Name: name}
+			m[name] = c
+		}
+		c.Files++
+		c.Bytes += a.Size
+	}
+
+	for _, a := range r.Actions {
+		s.Files++
+		s.Bytes += a.Size
+
+		c, ok := reasons[a.Reason]
+		if !ok {
+			c = &Count{Name: a.Reason.String()}
+			reasons[a.Reason] = c
+		}
+		c.Files++
+		c.Bytes += a.Size
+
+		if a.Destination != "" && a.Reason != ReasonStale && a.Reason != ReasonVetoed {
+			add(folders, a.Destination, a)
+		}
+		if ext := unknownExtension(a); ext != "" {
+			add(unknown, ext, a)
+		}
+	}
+
+	for reason := ReasonOrganized; reason <= ReasonVetoed; reason++ {
+		if c, ok := reasons[reason]; ok {
+			s.Reasons = append(s.Reasons, *c)
+		}
+	}
+	s.Folders = sortedCounts(folders, 0)
+	s.UnknownExtensions = sortedCounts(unknown, SummaryTop)
+
+	s.Largest = slices.Clone(r.Actions)
+	slices.SortStableFunc(s.Largest, func(a, b FileAction) int {
+		return cmp.Compare(b.Size, a.Size)
+	})
+	s.Largest = s.Largest[:min(len(s.Largest), SummaryTop)]
+	s.Largest = slices.DeleteFunc(s.Largest, func(a FileAction) bool { return a.Size == 0 })
+
+	return s
+}
+
+// UnknownExtensions returns every extension of the result that has no rule,
+// without its dot and sorted, for creating rules for them at once.
+func (r *OrganizeResult) UnknownExtensions() []string {
+	var exts []string
+	for _, a := range r.Actions {
+		if ext := unknownExtension(a); ext != "" && !slices.Contains(exts, ext) {
+			exts = append(exts, ext)
+		}
+	}
+	slices.Sort(exts)
+	return exts
+}
+
+// unknownExtension returns the lowercased extension of a file that was left
+// alone for lack of a rule, or "" for other files and for names without an
+// extension such as dotfiles.
+func unknownExtension(a FileAction) string {
+	if a.Reason != ReasonUnknownExtension || strings.HasPrefix(a.FileName, ".") {
+		return ""
+	}
+	return strings.ToLower(strings.TrimPrefix(filepath.Ext(a.FileName), "."))
+}
+
+// summarize records the time elapsed since start and the totals.
+func (r *OrganizeResult) summarize(start time.Time) {
+	r.Elapsed = time.Since(start)
+	r.Summary = r.Summarize()
+}
+
+// sortedCounts returns the counts by decreasing number of files, then size
+// and name, keeping the first top if top is positive.
+func sortedCounts(m map[string]*Count, top int) []Count {
+	counts := make([]Count, 0, len(m))
+	for _, c := range m {
+		counts = append(counts, *c)
+	}
+	slices.SortFunc(counts, func(a, b Count) int {
+		return cmp.Or(
+			cmp.Compare(b.Files, a.Files),
+			cmp.Compare(b.Bytes, a.Bytes),
+			cmp.Compare(a.Name, b.Name),
+		)
+	})
+	if top > 0 && len(counts) > top {
+		counts = counts[:top]
+	}
+	return counts
+}
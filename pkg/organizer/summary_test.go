@@ -0,0 +1,147 @@
+package organizer_test
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/d6o/Gorganizer/internal/testutil"
+	"github.com/d6o/Gorganizer/pkg/organizer"
+)
+
+func TestSummarize(t *testing.T) {
+	t.Parallel()
+	result := &organizer.OrganizeResult{Actions: []organizer.FileAction{
+		{FileName: "a.mp3", Size: 10, Destination: "Music", Reason: organizer.ReasonOrganized},
+		{FileName: "b.mp3", Size: 30, Destination: "Music", Reason: organizer.ReasonOrganized},
+		{FileName: "c.pdf", Size: 50, Destination: "Documents", Reason: organizer.ReasonOrganized},
+		{FileName: "d.heic", Size: 5, Reason: organizer.ReasonUnknownExtension},
+		{FileName: "e.HEIC", Size: 7, Reason: organizer.ReasonUnknownExtension},
+		{FileName: "f.srt", Size: 100, Reason: organizer.ReasonUnknownExtension},
+		{FileName: "README", Reason: organizer.ReasonUnknownExtension},
+		{FileName: ".bashrc", Size: 1, Reason: organizer.ReasonHidden},
+	}}
+
+	s := result.Summarize()
+
+	if s.Files != 8 || s.Bytes != 203 {
+		t.Errorf("totals = %d files, %d bytes, want 8 files, 203 bytes", s.Files, s.Bytes)
+	}
+
+	tests := []struct {
+		name string
+		got  []organizer.Count
+		want []organizer.Count
+	}{
+		{"folders", s.Folders, []organizer.Count{
+			{Name: "Music", Files: 2, Bytes: 40},
+			{Name: "Documents", Files: 1, Bytes: 50},
+		}},
+		{"reasons", s.Reasons, []organizer.Count{
+			{Name: "organized", Files: 3, Bytes: 90},
+			{Name: "hidden", Files: 1, Bytes: 1},
+			{Name: "unknown", Files: 4, Bytes: 112},
+		}},
+		{"unknown extensions", s.UnknownExtensions, []organizer.Count{
+			{Name: "heic", Files: 2, Bytes: 12},
+			{Name: "srt", Files: 1, Bytes: 100},
+		}},
+	}
+	for _, tt := range tests {
+		if !slices.Equal(tt.got, tt.want) {
+			t.Errorf("%s = %+v, want %+v", tt.name, tt.got, tt.want)
+		}
+	}
+
+	var largest []string
+	for _, a := range s.Largest {
+		largest = append(largest, a.FileName)
+	}
+	if want := []string{"f.srt", "c.pdf", "b.mp3", "a.mp3", "e.HEIC", "d.heic", ".bashrc"}; !slices.Equal(largest, want) {
+		t.Errorf("largest = %v, want %v", largest, want)
+	}
+
+	if got, want := result.UnknownExtensions(), []string{"heic", "srt"}; !slices.Equal(got, want) {
+		t.Errorf("UnknownExtensions() = %v, want %v", got, want)
+	}
+}
+
+func TestSummarize_Limits(t *testing.T) {
+	t.Parallel()
+	result := &organizer.OrganizeResult{}
+	for i := range organizer.SummaryTop + 5 {
+		result.Actions = append(result.Actions, organizer.FileAction{
+			FileName: "file." + strings.Repeat("x", i+1),
+			Size:     int64(i + 1),
+			Reason:   organizer.ReasonUnknownExtension,
+		})
+	}
+
+	s := result.Summarize()
+	if len(s.UnknownExtensions) != organizer.SummaryTop || len(s.Largest) != organizer.SummaryTop {
+		t.Errorf("got %d extensions and %d largest files, want %d of each",
+			len(s.UnknownExtensions), len(s.Largest), organizer.SummaryTop)
+	}
+	if s.Largest[0].Size != int64(organizer.SummaryTop+5) {
+		t.Errorf("largest file is %d bytes, want %d", s.Largest[0].Size, organizer.SummaryTop+5)
+	}
+	if n := len(result.UnknownExtensions()); n != organizer.SummaryTop+5 {
+		t.Errorf("UnknownExtensions() returned %d extensions, want all %d", n, organizer.SummaryTop+5)
+	}
+}
+
+func TestRun_Summary(t *testing.T) {
+	t.Parallel()
+	in := t.TempDir()
+	out := t.TempDir()
+	testutil.WriteFile(t, in, "song.mp3", "music")
+	testutil.WriteFile(t, in, "notes.xyz", "?")
+
+	org := newTestOrganizer(t, organizer.Config{InputFolder: in, OutputFolder: out})
+	result, err := org.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := result.Summary
+	if s == nil {
+		t.Fatal("Run() result has no summary")
+	}
+	if s.Files != 2 || s.Bytes != 6 || s.Elapsed != result.Elapsed || result.Elapsed <= 0 {
+		t.Errorf("summary = %+v, want 2 files, 6 bytes and the elapsed time", s)
+	}
+	if want := []organizer.Count{{Name: "Music", Files: 1, Bytes: 5}}; !slices.Equal(s.Folders, want) {
+		t.Errorf("folders = %+v, want %+v", s.Folders, want)
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded struct {
+		Summary struct {
+			Files             int               `json:"files"`
+			UnknownExtensions []organizer.Count `json:"unknown_extensions"`
+			Elapsed           string            `json:"elapsed"`
+		} `json:"summary"`
+		Elapsed any `json:"elapsed"`
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Summary.Files != 2 || len(decoded.Summary.UnknownExtensions) != 1 || decoded.Summary.UnknownExtensions[0].Name != "xyz" {
+		t.Errorf("JSON summary = %+v, want 2 files and xyz unknown", decoded.Summary)
+	}
+	if decoded.Summary.Elapsed != s.Elapsed.String() || decoded.Elapsed != nil {
+		t.Errorf("JSON elapsed = %q in the summary and %v in the result, want %q only in the summary", decoded.Summary.Elapsed, decoded.Elapsed, s.Elapsed)
+	}
+
+	var back organizer.OrganizeResult
+	if err := json.Unmarshal(data, &back); err != nil {
+		t.Fatal(err)
+	}
+	if back.Summary.Elapsed != s.Elapsed || back.Summary.Files != s.Files {
+		t.Errorf("decoded summary = %+v, want %+v", back.Summary, s)
+	}
+}
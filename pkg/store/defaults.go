@@ -1,9 +1,45 @@
 package store
 
+import "strconv"
+
+// DefaultsVersion is the version of the built-in rules. It goes up whenever
+// a release adds default rules, so that SyncDefaults can offer the new ones
+// to existing rules files.
+const DefaultsVersion = 2
+
+// defaultsSince maps the default rules added after the first release to the
+// DefaultsVersion that added them. All other default rules are version 1.
+var defaultsSince = map[string]int{
+	"opus": 2,
+	"mov":  2,
+	"avif": 2,
+	"azw3": 2,
+}
+
 func (s *Store) populateDefaults(lang string) error {
 	s.emitEvent(EventDatabaseNotFound)
 	s.emitEvent(EventCreatingDefaults)
 
+	for _, r := range s.defaultRules(lang) {
+		if err := s.set(r.Extension, r.Folder); err != nil {
+			return err
+		}
+	}
+
+	if err := s.setDefaultsVersion(DefaultsVersion); err != nil {
+		return err
+	}
+	if err := s.repo.SetSetting(schemaSetting, strconv.Itoa(SchemaVersion)); err != nil {
+		return err
+	}
+
+	s.log.Info("created default rules", "language", lang, "rules", len(s.index))
+	s.emitEvent(EventDefaultsInitialized)
+	return nil
+}
+
+// defaultRules returns the built-in rules with folder names in lang.
+func (s *Store) defaultRules(lang string) []Rule {
 	translations := s.languageMap(lang)
 
 	rules := []struct {
@@ -20,6 +56,7 @@ func (s *Store) populateDefaults(lang string) error {
 		{"aiff", translations["music"]},
 		{"wav", translations["music"]},
 		{"amr", translations["music"]},
+		{"opus", translations["music"]},
 
 		// Videos
 		{"flv", translations["videos"]},
@@ -34,6 +71,7 @@ func (s *Store) populateDefaults(lang string) error {
 		{"webm", translations["videos"]},
 		{"vob", translations["videos"]},
 		{"wmv", translations["videos"]},
+		{"mov", translations["videos"]},
 
 		// Pictures
 		{"png", translations["pictures"]},
@@ -45,6 +83,7 @@ func (s *Store) populateDefaults(lang string) error {
 		{"webp", translations["pictures"]},
 		{"psd", translations["pictures"]},
 		{"tiff", translations["pictures"]},
+		{"avif", translations["pictures"]},
 
 		// Archives
 		{"rar", translations["archives"]},
@@ -82,6 +121,7 @@ func (s *Store) populateDefaults(lang string) error {
 		{"mobi", translations["books"]},
 		{"epub", translations["books"]},
 		{"chm", translations["books"]},
+		{"azw3", translations["books"]},
 
 		// DEB Packages
 		{"deb", translations["deb_packages"]},
@@ -94,14 +134,11 @@ func (s *Store) populateDefaults(lang string) error {
 		{"rpm", translations["rpm_packages"]},
 	}
 
-	for _, r := range rules {
-		if err := s.InsertRule(r.ext + ":" + r.folder); err != nil {
-			return err
-		}
+	defaults := make([]Rule, len(rules))
+	for i, r := range rules {
+		defaults[i] = Rule{Extension: r.ext, Folder: s.title(r.folder)}
 	}
-
-	s.emitEvent(EventDefaultsInitialized)
-	return nil
+	return defaults
 }
 
 func (s *Store) languageMap(lang string) map[string]string {
@@ -0,0 +1,261 @@
+package store
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/d6o/Gorganizer/internal/fsutil"
+)
+
+// Change describes how an edit changes the folder an extension resolves to
+// in the current profile.
+type Change struct {
+	Extension string `json:"extension"`
+	// From is the folder before the edit, or empty if there was no rule.
+	From string `json:"from,omitempty"`
+	// To is the folder after the edit, or empty if no rule is left.
+	To string `json:"to,omitempty"`
+}
+
+// The edit methods below return the changes they make. With preview set,
+// they only work the changes out and leave the rules untouched.
+
+// AddRules maps every extension in exts to folder in the current profile,
+// capitalizing folder like InsertRule. Extensions that already resolve to
+// folder are not reported as changes.
+func (s *Store) AddRules(exts []string, folder string, preview bool) ([]Change, error) {
+	if err := CheckFolder(folder); err != nil {
+		return nil, err
+	}
+	rules := make([]Rule, len(exts))
+	for i, ext := range exts {
+		rules[i] = Rule{Extension: ext, Folder: s.title(folder)}
+	}
+	return s.SetRules(rules, preview)
+}
+
+// SetRules maps the extension of every rule to the rule's folder in the
+// current profile, the first rule winning when an extension is repeated.
+// Folders are kept as given, so rules learned from existing folders match
+// them exactly. Extensions that already resolve to their folder are not
+// reported as changes.
+func (s *Store) SetRules(rules []Rule, preview bool) ([]Change, error) {
+	var changes []Change
+	var set []Rule
+	seen := make(map[string]bool)
+	for _, r := range rules {
+		if err := CheckFolder(r.Folder); err != nil {
+			return nil, err
+		}
+		folder := r.Folder
+		if IsRoot(folder) {
+			folder = filepath.Clean(folder)
+		}
+		ext := strings.ToLower(strings.TrimSpace(r.Extension))
+		if ext == "" {
+			return nil, ErrEmptyRuleComponent
+		}
+		if strings.ContainsAny(ext, ":,") {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidRuleFormat, ext)
+		}
+		if seen[ext] {
+			continue
+		}
+		seen[ext] = true
+		set = append(set, Rule{Extension: ext, Folder: folder})
+
+		if from := s.Lookup(ext); from != folder {
+			changes = append(changes, Change{Extension: ext, From: from, To: folder})
+		}
+	}
+
+	if preview {
+		return changes, nil
+	}
+	for _, r := range set {
+		if err := s.put(r); err != nil {
+			return nil, err
+		}
+	}
+	s.logChanges(changes)
+	return changes, nil
+}
+
+// MoveRule maps an extension that already has a rule to another folder.
+func (s *Store) MoveRule(ext, folder string, preview bool) ([]Change, error) {
+	if s.Lookup(ext) == "" {
+		return nil, fmt.Errorf("%w: %q", ErrRuleNotFound, strings.ToLower(ext))
+	}
+	return s.AddRules([]string{ext}, folder, preview)
+}
+
+// RemoveRule deletes the current profile's rule for ext. The extension falls
+// back to the rule the profile inherits for it, if any. Returns
+// ErrRuleInherited if the rule comes from a profile the current one extends,
+// and ErrRuleNotFound if there is no rule at all.
+func (s *Store) RemoveRule(ext string, preview bool) ([]Change, error) {
+	ext = strings.ToLower(ext)
+	var changes []Change
+	for _, r := range s.repo.Rules(s.profile) {
+		if r.Extension == ext {
+			changes = append(changes, Change{Extension: ext, From: r.Folder, To: s.inherited(ext)})
+		}
+	}
+	if len(changes) == 0 {
+		if s.Lookup(ext) != "" {
+			return nil, fmt.Errorf("%w: %q", ErrRuleInherited, ext)
+		}
+		return nil, fmt.Errorf("%w: %q", ErrRuleNotFound, ext)
+	}
+
+	if !preview {
+		s.remove(ext)
+		s.logChanges(changes)
+	}
+	return changes, nil
+}
+
+// RenameFolder maps every extension that resolves to from to the folder to
+// instead. Renaming to a folder that already has rules merges the two.
+// Inherited rules are overridden in the current profile. Folder names are
+// matched case-insensitively.
+func (s *Store) RenameFolder(from, to string, preview bool) ([]Change, error) {
+	exts := s.extensionsIn(from)
+	if len(exts) == 0 {
+		return nil, fmt.Errorf("%w: %q", ErrFolderNotFound, from)
+	}
+	return s.AddRules(exts, to, preview)
+}
+
+// RemoveFolder deletes the current profile's rules for folder. Extensions
+// the profile inherits a rule for fall back to that rule. Returns
+// ErrRuleInherited if all the folder's rules are inherited. Folder names are
+// matched case-insensitively.
+func (s *Store) RemoveFolder(folder string, preview bool) ([]Change, error) {
+	var changes []Change
+	for _, r := range s.repo.Rules(s.profile) {
+		if strings.EqualFold(r.Folder, folder) {
+			changes = append(changes, Change{Extension: r.Extension, From: r.Folder, To: s.inherited(r.Extension)})
+		}
+	}
+	if len(changes) == 0 {
+		if len(s.extensionsIn(folder)) > 0 {
+			return nil, fmt.Errorf("%w: %q", ErrRuleInherited, folder)
+		}
+		return nil, fmt.Errorf("%w: %q", ErrFolderNotFound, folder)
+	}
+
+	if !preview {
+		for _, c := range changes {
+			s.remove(c.Extension)
+		}
+		s.logChanges(changes)
+	}
+	return changes, nil
+}
+
+// ResetDefaults replaces the current profile's rules with the default rules
+// of lang. Rules it inherits are kept unless the defaults override them. The
+// profile is marked as synced with the current DefaultsVersion.
+func (s *Store) ResetDefaults(lang string, preview bool) ([]Change, error) {
+	defaults := s.defaultRules(lang)
+
+	after := make(map[string]string)
+	for _, r := range defaults {
+		after[r.Extension] = r.Folder
+	}
+	for _, p := range s.chain(s.profile)[1:] {
+		for _, r := range s.repo.Rules(p) {
+			if _, ok := after[r.Extension]; !ok {
+				after[r.Extension] = r.Folder
+			}
+		}
+	}
+
+	var changes []Change
+	before := make(map[string]bool)
+	for _, r := range s.Rules() {
+		before[r.Extension] = true
+		if to := after[r.Extension]; to != r.Folder {
+			changes = append(changes, Change{Extension: r.Extension, From: r.Folder, To: to})
+		}
+	}
+	for _, r := range defaults {
+		if !before[r.Extension] {
+			changes = append(changes, Change{Extension: r.Extension, To: r.Folder})
+		}
+	}
+
+	if preview {
+		return changes, nil
+	}
+	for _, r := range s.repo.Rules(s.profile) {
+		s.repo.Delete(s.profile, r.Extension)
+	}
+	for _, r := range defaults {
+		if err := s.repo.Insert(s.profile, r); err != nil {
+			return nil, err
+		}
+	}
+	s.reindex()
+	if err := s.setDefaultsVersion(DefaultsVersion); err != nil {
+		return nil, err
+	}
+	s.logChanges(changes)
+	return changes, nil
+}
+
+func (s *Store) logChanges(changes []Change) {
+	for _, c := range changes {
+		s.log.Debug("changed rule", "extension", c.Extension, "from", c.From, "to", c.To, "profile", s.profile)
+	}
+}
+
+// extensionsIn returns the extensions that resolve to folder, ignoring case.
+func (s *Store) extensionsIn(folder string) []string {
+	var exts []string
+	for _, r := range s.Rules() {
+		if strings.EqualFold(r.Folder, folder) {
+			exts = append(exts, r.Extension)
+		}
+	}
+	return exts
+}
+
+// inherited returns the folder ext resolves to in the profiles the current
+// one extends.
+func (s *Store) inherited(ext string) string {
+	for _, p := range s.chain(s.profile)[1:] {
+		for _, r := range s.repo.Rules(p) {
+			if r.Extension == ext {
+				return r.Folder
+			}
+		}
+	}
+	return ""
+}
+
+// CheckFolder returns an error wrapping ErrEmptyRuleComponent,
+// ErrInvalidRuleFormat or ErrInvalidDestination when folder cannot be the
+// folder of a rule.
+func CheckFolder(folder string) error {
+	if strings.TrimSpace(folder) == "" {
+		return ErrEmptyRuleComponent
+	}
+	vol := filepath.VolumeName(folder)
+	if strings.Contains(folder[len(vol):], ":") {
+		return fmt.Errorf("%w: %q", ErrInvalidRuleFormat, folder)
+	}
+	if (vol != "" || strings.HasPrefix(folder, "~")) && !IsRoot(folder) {
+		return fmt.Errorf("%w: %q", ErrInvalidDestination, folder)
+	}
+	return nil
+}
+
+// IsRoot reports whether a rule folder is a destination root of its own,
+// an absolute path or a path starting with ~/, rather than a folder in the
+// output folder.
+func IsRoot(folder string) bool {
+	return fsutil.HomeRelative(folder) || filepath.IsAbs(folder)
+}
@@ -0,0 +1,341 @@
+package store_test
+
+import (
+	"errors"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"testing"
+
+	"github.com/d6o/Gorganizer/pkg/store"
+)
+
+func TestInsertRule_Bulk(t *testing.T) {
+	t.Parallel()
+	s := newTestStore(t, "en")
+
+	if err := s.InsertRule("mp3,FLAC,xyz:audio"); err != nil {
+		t.Fatal(err)
+	}
+	for _, ext := range []string{"mp3", "flac", "xyz"} {
+		if got := s.Lookup(ext); got != "Audio" {
+			t.Errorf("Lookup(%s) = %q, want Audio", ext, got)
+		}
+	}
+
+	for _, rule := range []string{"mp3,,ogg:Music", ",:Music", "mp3,ogg:"} {
+		if err := s.InsertRule(rule); !errors.Is(err, store.ErrEmptyRuleComponent) {
+			t.Errorf("InsertRule(%q) = %v, want ErrEmptyRuleComponent", rule, err)
+		}
+	}
+}
+
+func TestAddRules_Preview(t *testing.T) {
+	t.Parallel()
+	s := newTestStore(t, "en")
+
+	changes, err := s.AddRules([]string{"mp3", "ogg", "xyz", "mp3"}, "music", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []store.Change{{Extension: "xyz", To: "Music"}}
+	if !slices.Equal(changes, want) {
+		t.Errorf("changes = %+v, want %+v", changes, want)
+	}
+	if got := s.Lookup("xyz"); got != "" {
+		t.Errorf("preview added a rule: Lookup(xyz) = %q", got)
+	}
+}
+
+func TestSetRules(t *testing.T) {
+	t.Parallel()
+	s := newTestStore(t, "en")
+
+	changes, err := s.SetRules([]store.Rule{
+		{Extension: "MP3", Folder: "Music"},
+		{Extension: "pdf", Folder: "papers"},
+		{Extension: "xyz", Folder: "Misc"},
+		{Extension: "xyz", Folder: "Other"},
+	}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []store.Change{
+		{Extension: "pdf", From: "Documents", To: "papers"},
+		{Extension: "xyz", To: "Misc"},
+	}
+	if !slices.Equal(changes, want) {
+		t.Errorf("changes = %+v, want %+v", changes, want)
+	}
+	for ext, folder := range map[string]string{"mp3": "Music", "pdf": "papers", "xyz": "Misc"} {
+		if got := s.Lookup(ext); got != folder {
+			t.Errorf("Lookup(%s) = %q, want %q", ext, got, folder)
+		}
+	}
+
+	if _, err := s.SetRules([]store.Rule{{Extension: "a", Folder: "A:B"}}, false); !errors.Is(err, store.ErrInvalidRuleFormat) {
+		t.Errorf("SetRules() error = %v, want ErrInvalidRuleFormat", err)
+	}
+}
+
+func TestMoveRule(t *testing.T) {
+	t.Parallel()
+	s := newTestStore(t, "en")
+
+	changes, err := s.MoveRule("MP3", "Audio", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []store.Change{{Extension: "mp3", From: "Music", To: "Audio"}}
+	if !slices.Equal(changes, want) {
+		t.Errorf("changes = %+v, want %+v", changes, want)
+	}
+	if got := s.Lookup("mp3"); got != "Audio" {
+		t.Errorf("Lookup(mp3) = %q, want Audio", got)
+	}
+
+	if _, err := s.MoveRule("xyz", "Audio", false); !errors.Is(err, store.ErrRuleNotFound) {
+		t.Errorf("MoveRule(xyz) = %v, want ErrRuleNotFound", err)
+	}
+}
+
+func TestRemoveRule(t *testing.T) {
+	t.Parallel()
+	s := newTestStore(t, "en")
+
+	if err := s.CreateProfile("photos", store.DefaultProfile); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.UseProfile("photos"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.InsertRule("jpg:Camera"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, preview := range []bool{true, false} {
+		if _, err := s.RemoveRule("mp3", preview); !errors.Is(err, store.ErrRuleInherited) {
+			t.Errorf("RemoveRule(mp3, %v) in photos = %v, want ErrRuleInherited", preview, err)
+		}
+		if _, err := s.RemoveRule("xyz", preview); !errors.Is(err, store.ErrRuleNotFound) {
+			t.Errorf("RemoveRule(xyz, %v) = %v, want ErrRuleNotFound", preview, err)
+		}
+	}
+
+	want := []store.Change{{Extension: "jpg", From: "Camera", To: "Pictures"}}
+	changes, err := s.RemoveRule("JPG", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(changes, want) {
+		t.Errorf("preview changes = %+v, want %+v", changes, want)
+	}
+	if got := s.Lookup("jpg"); got != "Camera" {
+		t.Errorf("preview removed the rule, Lookup(jpg) = %q", got)
+	}
+
+	changes, err = s.RemoveRule("jpg", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(changes, want) {
+		t.Errorf("changes = %+v, want %+v", changes, want)
+	}
+	if got := s.Lookup("jpg"); got != "Pictures" {
+		t.Errorf("Lookup(jpg) = %q, want the inherited Pictures", got)
+	}
+}
+
+func TestRenameFolder(t *testing.T) {
+	t.Parallel()
+	s := newTestStore(t, "en")
+	before := len(s.Rules())
+
+	preview, err := s.RenameFolder("debpackages", "Packages", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := s.Lookup("deb"); got != "DEBPackages" {
+		t.Errorf("preview renamed the folder: Lookup(deb) = %q", got)
+	}
+
+	changes, err := s.RenameFolder("debpackages", "Packages", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []store.Change{{Extension: "deb", From: "DEBPackages", To: "Packages"}}
+	if !slices.Equal(changes, want) || !slices.Equal(preview, want) {
+		t.Errorf("changes = %+v, preview = %+v, want %+v", changes, preview, want)
+	}
+	if got := s.Lookup("deb"); got != "Packages" {
+		t.Errorf("Lookup(deb) = %q, want Packages", got)
+	}
+	if got := len(s.Rules()); got != before {
+		t.Errorf("rename changed the number of rules from %d to %d", before, got)
+	}
+
+	if _, err := s.RenameFolder("Nothing", "Else", false); !errors.Is(err, store.ErrFolderNotFound) {
+		t.Errorf("RenameFolder(Nothing) = %v, want ErrFolderNotFound", err)
+	}
+}
+
+func TestRemoveFolder(t *testing.T) {
+	t.Parallel()
+	s := newTestStore(t, "en")
+
+	if err := s.CreateProfile("photos", store.DefaultProfile); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.UseProfile("photos"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.InsertRule("jpg,raw:Camera"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.RemoveFolder("Music", false); !errors.Is(err, store.ErrRuleInherited) {
+		t.Errorf("RemoveFolder(Music) in photos = %v, want ErrRuleInherited", err)
+	}
+
+	changes, err := s.RemoveFolder("camera", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []store.Change{
+		{Extension: "jpg", From: "Camera", To: "Pictures"},
+		{Extension: "raw", From: "Camera"},
+	}
+	if !slices.Equal(changes, want) {
+		t.Errorf("changes = %+v, want %+v", changes, want)
+	}
+	if got := s.Lookup("raw"); got != "" {
+		t.Errorf("Lookup(raw) = %q, want no rule", got)
+	}
+
+	if _, err := s.RemoveFolder("Camera", false); !errors.Is(err, store.ErrFolderNotFound) {
+		t.Errorf("second RemoveFolder(Camera) = %v, want ErrFolderNotFound", err)
+	}
+}
+
+func TestResetDefaults(t *testing.T) {
+	t.Parallel()
+	s := newTestStore(t, "en")
+
+	if err := s.InsertRule("xyz:Custom"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.MoveRule("mp3", "Audio", false); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.DeleteRule("pdf"); err != nil {
+		t.Fatal(err)
+	}
+
+	changes, err := s.ResetDefaults("en", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]store.Change{
+		"mp3": {Extension: "mp3", From: "Audio", To: "Music"},
+		"xyz": {Extension: "xyz", From: "Custom"},
+		"pdf": {Extension: "pdf", To: "Documents"},
+	}
+	if len(changes) != len(want) {
+		t.Fatalf("changes = %+v, want %d", changes, len(want))
+	}
+	for _, c := range changes {
+		if want[c.Extension] != c {
+			t.Errorf("change %+v, want %+v", c, want[c.Extension])
+		}
+	}
+
+	if _, err := s.ResetDefaults("en", false); err != nil {
+		t.Fatal(err)
+	}
+	for ext, folder := range map[string]string{"mp3": "Music", "pdf": "Documents", "xyz": ""} {
+		if got := s.Lookup(ext); got != folder {
+			t.Errorf("Lookup(%s) after reset = %q, want %q", ext, got, folder)
+		}
+	}
+	if changes, _ := s.ResetDefaults("en", true); len(changes) != 0 {
+		t.Errorf("reset left differences: %+v", changes)
+	}
+}
+
+func TestInsertRule_DestinationRoots(t *testing.T) {
+	t.Parallel()
+	s := newTestStore(t, "en")
+
+	root := filepath.Join(t.TempDir(), "media", "videos")
+	if err := s.InsertRule("mkv,mp4:" + root + string(filepath.Separator)); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.InsertRule("pdf:~/docs/"); err != nil {
+		t.Fatal(err)
+	}
+	tests := map[string]string{"mkv": root, "mp4": root, "pdf": "~/docs"}
+	for ext, want := range tests {
+		if got := s.Lookup(ext); got != want {
+			t.Errorf("Lookup(%s) = %q, want %q", ext, got, want)
+		}
+	}
+
+	for _, rule := range []string{"txt:~bob/docs", "txt:~docs"} {
+		if err := s.InsertRule(rule); !errors.Is(err, store.ErrInvalidDestination) {
+			t.Errorf("InsertRule(%q) = %v, want ErrInvalidDestination", rule, err)
+		}
+	}
+}
+
+func TestIsRoot(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		folder string
+		want   bool
+	}{
+		{"Videos", false},
+		{"Media/Videos", false},
+		{"~", true},
+		{"~/Documents", true},
+		{"~bob/Documents", false},
+		{filepath.Join(string(filepath.Separator), "mnt", "media"), runtime.GOOS != "windows"},
+	}
+	for _, tt := range tests {
+		if got := store.IsRoot(tt.folder); got != tt.want {
+			t.Errorf("IsRoot(%q) = %v, want %v", tt.folder, got, tt.want)
+		}
+	}
+}
+
+func TestSetRules_ReservedFolders(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	s, err := store.NewStore("en", store.WithConfigDir(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	learned := []store.Rule{{Extension: "nfo", Folder: "meta"}, {Extension: "json", Folder: "profiles"}, {Extension: "pct", Folder: "100%"}}
+	if _, err := s.SetRules(learned, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened, err := store.NewStore("en", store.WithConfigDir(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range learned {
+		if got := reopened.Lookup(r.Extension); got != r.Folder {
+			t.Errorf("Lookup(%s) = %q after reloading, want %q", r.Extension, got, r.Folder)
+		}
+	}
+	if profiles := reopened.Profiles(); len(profiles) != 1 {
+		t.Errorf("profiles = %+v, want only the default one", profiles)
+	}
+	if got := reopened.Lookup("mp3"); got != "Music" {
+		t.Errorf("Lookup(mp3) = %q, the other rules should survive", got)
+	}
+}
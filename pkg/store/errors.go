@@ -9,3 +9,49 @@ var ErrInvalidRuleFormat = errors.New("rule must have exactly one colon separato
 // ErrEmptyRuleComponent is returned when either the extension or folder
 // part of a rule is empty.
 var ErrEmptyRuleComponent = errors.New("rule extension and folder must not be empty")
+
+// ErrInvalidDestination is returned for rule folders that look like a path
+// but are neither absolute nor start with ~/, such as ~user/Videos.
+var ErrInvalidDestination = errors.New("destination must be a folder name, an absolute path or start with ~/")
+
+// ErrProfileNotFound is returned when a named profile does not exist.
+var ErrProfileNotFound = errors.New("profile not found")
+
+// ErrProfileExists is returned when creating a profile whose name is taken.
+var ErrProfileExists = errors.New("profile already exists")
+
+// ErrInvalidProfileName is returned for empty profile names or names
+// containing a colon, whitespace or INI syntax.
+var ErrInvalidProfileName = errors.New("invalid profile name")
+
+// ErrDeleteDefaultProfile is returned when trying to delete DefaultProfile.
+var ErrDeleteDefaultProfile = errors.New("the default profile cannot be deleted")
+
+// ErrProfileInUse is returned when deleting a profile other profiles extend.
+var ErrProfileInUse = errors.New("profile is extended by another profile")
+
+// ErrUnknownFormat is returned for rule file formats other than JSON, YAML,
+// CSV and INI.
+var ErrUnknownFormat = errors.New("unknown rules format")
+
+// ErrUnknownImportMode is returned for import modes other than merge,
+// replace and dry-run.
+var ErrUnknownImportMode = errors.New("unknown import mode")
+
+// ErrRuleNotFound is returned when editing an extension no rule maps.
+var ErrRuleNotFound = errors.New("no rule for extension")
+
+// ErrFolderNotFound is returned when editing a folder no rule maps to.
+var ErrFolderNotFound = errors.New("no rule for folder")
+
+// ErrRuleInherited is returned when deleting rules the current profile
+// inherits from the profile it extends. They can only be overridden.
+var ErrRuleInherited = errors.New("rule is inherited from another profile")
+
+// ErrNewerSchema is returned when a config file was written by a newer
+// version of Gorganizer, in a format this version cannot read safely.
+var ErrNewerSchema = errors.New("rules file was written by a newer version")
+
+// ErrInvalidSchema is returned when a config file's schema version is not
+// a number.
+var ErrInvalidSchema = errors.New("invalid rules file schema version")
@@ -0,0 +1,148 @@
+package store
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FileRepository stores rules in a JSON or YAML file, chosen by the file
+// extension:
+//
+//	meta:
+//	  default_profile: photos
+//	rules:
+//	  - extension: mp3
+//	    folder: Music
+//	profiles:
+//	  - name: photos
+//	    extends: default
+//	    rules:
+//	      - extension: jpg
+//	        folder: Camera
+//
+// The top level rules belong to DefaultProfile. The whole file is read on
+// open and rewritten on Save.
+type FileRepository struct {
+	*MemoryRepository
+	file   string
+	format Format
+}
+
+// fileDocument is the layout of a FileRepository file.
+type fileDocument struct {
+	Meta     map[string]string `json:"meta,omitempty" yaml:"meta,omitempty"`
+	Rules    []Rule            `json:"rules" yaml:"rules"`
+	Profiles []fileProfile     `json:"profiles,omitempty" yaml:"profiles,omitempty"`
+}
+
+type fileProfile struct {
+	Name    string `json:"name" yaml:"name"`
+	Extends string `json:"extends,omitempty" yaml:"extends,omitempty"`
+	Rules   []Rule `json:"rules" yaml:"rules"`
+}
+
+// NewFileRepository returns an empty repository that saves to file, which
+// must have a .json, .yaml or .yml extension.
+func NewFileRepository(file string) (*FileRepository, error) {
+	format, err := FormatFromPath(file)
+	if err != nil {
+		return nil, err
+	}
+	if format != FormatJSON && format != FormatYAML {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownFormat, format)
+	}
+
+	return &FileRepository{
+		MemoryRepository: NewMemoryRepository(),
+		file:             file,
+		format:           format,
+	}, nil
+}
+
+// OpenFileRepository loads the rules stored in file.
+func OpenFileRepository(file string) (*FileRepository, error) {
+	r, err := NewFileRepository(file)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc fileDocument
+	if r.format == FormatJSON {
+		err = json.Unmarshal(data, &doc)
+	} else {
+		err = yaml.Unmarshal(data, &doc)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+
+	for k, v := range doc.Meta {
+		if err := r.SetSetting(k, v); err != nil {
+			return nil, err
+		}
+	}
+	for _, rule := range doc.Rules {
+		if err := r.Insert(DefaultProfile, rule); err != nil {
+			return nil, err
+		}
+	}
+	for _, p := range doc.Profiles {
+		if err := r.CreateProfile(p.Name, p.Extends); err != nil {
+			return nil, err
+		}
+		for _, rule := range p.Rules {
+			if err := r.Insert(p.Name, rule); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return r, nil
+}
+
+// Save rewrites the file.
+func (r *FileRepository) Save() error {
+	doc := fileDocument{
+		Meta:  r.settings,
+		Rules: r.Rules(DefaultProfile),
+	}
+	if doc.Rules == nil {
+		doc.Rules = []Rule{}
+	}
+	for _, p := range r.Profiles() {
+		rules := r.Rules(p.Name)
+		if rules == nil {
+			rules = []Rule{}
+		}
+		doc.Profiles = append(doc.Profiles, fileProfile{Name: p.Name, Extends: p.Extends, Rules: rules})
+	}
+
+	var buf bytes.Buffer
+	if r.format == FormatJSON {
+		enc := json.NewEncoder(&buf)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(doc); err != nil {
+			return err
+		}
+	} else {
+		enc := yaml.NewEncoder(&buf)
+		enc.SetIndent(2)
+		if err := enc.Encode(doc); err != nil {
+			return err
+		}
+		if err := enc.Close(); err != nil {
+			return err
+		}
+	}
+
+	return os.WriteFile(r.file, buf.Bytes(), 0o644)
+}
@@ -0,0 +1,210 @@
+package store_test
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/d6o/Gorganizer/pkg/organizer"
+	"github.com/d6o/Gorganizer/pkg/store"
+)
+
+func TestLookup_IndexFollowsEdits(t *testing.T) {
+	t.Parallel()
+	s := newTestStore(t, "en")
+
+	if err := s.CreateProfile("photos", store.DefaultProfile); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.UseProfile("photos"); err != nil {
+		t.Fatal(err)
+	}
+
+	steps := []struct {
+		name string
+		edit func() error
+		ext  string
+		want string
+	}{
+		{"inherited", func() error { return nil }, "jpg", "Pictures"},
+		{"override", func() error { return s.InsertRule("jpg:Camera") }, "JPG", "Camera"},
+		{"delete override", func() error { return s.DeleteRule("JPG") }, "jpg", "Pictures"},
+		{"new", func() error { return s.InsertRule("raw:Camera") }, "raw", "Camera"},
+		{"delete new", func() error { return s.DeleteRule("raw") }, "raw", ""},
+		{"delete inherited fails", func() error {
+			if err := s.DeleteRule("png"); !errors.Is(err, store.ErrRuleInherited) {
+				return fmt.Errorf("DeleteRule(png) = %v, want ErrRuleInherited", err)
+			}
+			return nil
+		}, "png", "Pictures"},
+		{"import", func() error {
+			_, err := s.Import([]store.Rule{{Extension: "heic", Folder: "camera"}}, store.ImportMerge)
+			return err
+		}, "heic", "Camera"},
+		{"switch profile", func() error { return s.UseProfile(store.DefaultProfile) }, "heic", ""},
+	}
+
+	for _, step := range steps {
+		if err := step.edit(); err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		if got := s.Lookup(step.ext); got != step.want {
+			t.Errorf("%s: Lookup(%s) = %q, want %q", step.name, step.ext, got, step.want)
+		}
+	}
+}
+
+func TestLookup_IndexAfterDeletingCurrentProfile(t *testing.T) {
+	t.Parallel()
+	s := newTestStore(t, "en")
+
+	if err := s.CreateProfile("empty", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.UseProfile("empty"); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.Lookup("mp3"); got != "" {
+		t.Fatalf("Lookup(mp3) in empty profile = %q, want no rule", got)
+	}
+
+	if err := s.DeleteProfile("empty"); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.Lookup("mp3"); got != "Music" {
+		t.Errorf("Lookup(mp3) after deleting the profile = %q, want Music", got)
+	}
+}
+
+// largeRuleSet returns n rules spread over n/20 folders.
+func largeRuleSet(n int) []store.Rule {
+	rules := make([]store.Rule, n)
+	for i := range rules {
+		rules[i] = store.Rule{
+			Extension: fmt.Sprintf("x%d", i),
+			Folder:    fmt.Sprintf("Folder%d", i/20),
+		}
+	}
+	return rules
+}
+
+// scanResolver looks every extension up by walking the rules of an INI
+// repository, as the store did before it kept an index.
+type scanResolver struct {
+	repo store.RuleRepository
+}
+
+func (r scanResolver) Lookup(ext string) string {
+	ext = strings.ToLower(ext)
+	for _, rule := range r.repo.Rules(store.DefaultProfile) {
+		if rule.Extension == ext {
+			return rule.Folder
+		}
+	}
+	return ""
+}
+
+func newBenchRepository(b *testing.B, rules []store.Rule) store.RuleRepository {
+	b.Helper()
+	repo := store.NewINIRepository(filepath.Join(b.TempDir(), "rules.ini"))
+	for _, r := range rules {
+		if err := repo.Insert(store.DefaultProfile, r); err != nil {
+			b.Fatal(err)
+		}
+	}
+	return repo
+}
+
+func newBenchStore(b *testing.B, repo store.RuleRepository) *store.Store {
+	b.Helper()
+	s, err := store.NewStore("en", store.WithRepository(repo))
+	if err != nil {
+		b.Fatal(err)
+	}
+	return s
+}
+
+func BenchmarkLookup(b *testing.B) {
+	for _, n := range []int{100, 1000, 10000} {
+		rules := largeRuleSet(n)
+		// Half the lookups hit the last rule, half miss.
+		exts := []string{rules[n-1].Extension, "unknown"}
+
+		repo := newBenchRepository(b, rules)
+		resolvers := []struct {
+			name string
+			r    organizer.ExtensionResolver
+		}{
+			{"scan", scanResolver{repo: repo}},
+			{"index", newBenchStore(b, repo)},
+		}
+
+		for _, res := range resolvers {
+			b.Run(fmt.Sprintf("%s/rules=%d", res.name, n), func(b *testing.B) {
+				for i := 0; b.Loop(); i++ {
+					res.r.Lookup(exts[i%len(exts)])
+				}
+			})
+		}
+	}
+}
+
+func BenchmarkLookup_LargeDirectory(b *testing.B) {
+	const files = 10000
+	rules := largeRuleSet(500)
+
+	dir := b.TempDir()
+	for i := range files {
+		name := fmt.Sprintf("file%d.%s", i, rules[i%len(rules)].Extension)
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o644); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	repo := newBenchRepository(b, rules)
+	resolvers := []struct {
+		name string
+		r    organizer.ExtensionResolver
+	}{
+		{"scan", scanResolver{repo: repo}},
+		{"index", newBenchStore(b, repo)},
+	}
+
+	for _, res := range resolvers {
+		b.Run(fmt.Sprintf("%s/files=%d", res.name, files), func(b *testing.B) {
+			org := organizer.NewOrganizer(res.r, organizer.Config{
+				InputFolder:  dir,
+				OutputFolder: dir,
+				Preview:      true,
+			})
+			for b.Loop() {
+				if _, err := org.Run(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestExtensionRules(t *testing.T) {
+	t.Parallel()
+	r := organizer.ExtensionRules(newTestStore(t, "en"))
+
+	tests := []struct {
+		name string
+		want organizer.Resolution
+	}{
+		{"song.MP3", organizer.Resolution{Destination: "Music", Reason: "rule for mp3"}},
+		{"notes.xyz", organizer.Resolution{}},
+		{"README", organizer.Resolution{}},
+	}
+	for _, tt := range tests {
+		got, err := r.Resolve(&organizer.File{Name: tt.name})
+		if err != nil || got != tt.want {
+			t.Errorf("Resolve(%s) = %+v, %v, want %+v", tt.name, got, err, tt.want)
+		}
+	}
+}
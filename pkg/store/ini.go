@@ -0,0 +1,208 @@
+package store
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"gopkg.in/ini.v1"
+)
+
+// Sections with these names hold settings rather than rules. A folder of
+// the same name is written with its first letter escaped, see escapeFolder.
+const (
+	profilesSection = "profiles"
+	metaSection     = "meta"
+)
+
+// The rules of a named profile live in sections called "profile:Folder".
+// Profile names cannot hold ':', and escapeFolder encodes it in folders,
+// which may be volume-qualified roots such as D:\Videos.
+const profileSeparator = ":"
+
+// INIRepository stores rules in an INI file, one section per folder listing
+// its extensions. The rules of DefaultProfile are plain folder sections, so
+// files written before profiles existed load unchanged. It is the format of
+// the default config files.
+type INIRepository struct {
+	cfg  *ini.File
+	file string
+}
+
+// NewINIRepository returns an empty repository that saves to file.
+func NewINIRepository(file string) *INIRepository {
+	return &INIRepository{cfg: ini.Empty(), file: file}
+}
+
+// OpenINIRepository loads the rules stored in file.
+func OpenINIRepository(file string) (*INIRepository, error) {
+	cfg, err := ini.LoadSources(ini.LoadOptions{AllowBooleanKeys: true, Loose: false}, file)
+	if err != nil {
+		return nil, err
+	}
+	return &INIRepository{cfg: cfg, file: file}, nil
+}
+
+// Rules returns the profile's own rules, in file order.
+func (r *INIRepository) Rules(profile string) []Rule {
+	var rules []Rule
+	for _, sec := range r.sections(profile) {
+		for _, key := range r.cfg.Section(sec.name).KeyStrings() {
+			rules = append(rules, Rule{Extension: key, Folder: sec.folder})
+		}
+	}
+	return rules
+}
+
+// Insert adds the extension to the folder's section.
+func (r *INIRepository) Insert(profile string, rule Rule) error {
+	name := sectionName(profile, rule.Folder)
+	if r.cfg.HasSection(name) && r.cfg.Section(name).HasKey(rule.Extension) {
+		return nil
+	}
+
+	r.Delete(profile, rule.Extension)
+	_, err := r.cfg.Section(name).NewKey(rule.Extension, "")
+	return err
+}
+
+// Delete removes the extension from the profile's sections, dropping a
+// section it leaves empty.
+func (r *INIRepository) Delete(profile, ext string) bool {
+	for _, sec := range r.sections(profile) {
+		section := r.cfg.Section(sec.name)
+		if section.HasKey(ext) {
+			section.DeleteKey(ext)
+			if len(section.Keys()) == 0 {
+				r.cfg.DeleteSection(sec.name)
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// Profiles lists the [profiles] section, which maps each profile to the
+// one it extends.
+func (r *INIRepository) Profiles() []Profile {
+	if !r.cfg.HasSection(profilesSection) {
+		return nil
+	}
+
+	var profiles []Profile
+	for _, k := range r.cfg.Section(profilesSection).Keys() {
+		profiles = append(profiles, Profile{Name: k.Name(), Extends: k.Value()})
+	}
+	return profiles
+}
+
+// CreateProfile adds the profile to the [profiles] section.
+func (r *INIRepository) CreateProfile(name, extends string) error {
+	_, err := r.cfg.Section(profilesSection).NewKey(name, extends)
+	return err
+}
+
+// DeleteProfile removes the profile's sections and its [profiles] entry.
+func (r *INIRepository) DeleteProfile(name string) error {
+	for _, sec := range r.sections(name) {
+		r.cfg.DeleteSection(sec.name)
+	}
+	if r.cfg.HasSection(profilesSection) {
+		r.cfg.Section(profilesSection).DeleteKey(name)
+	}
+	return nil
+}
+
+// Setting reads a key of the [meta] section.
+func (r *INIRepository) Setting(key string) string {
+	if !r.cfg.HasSection(metaSection) || !r.cfg.Section(metaSection).HasKey(key) {
+		return ""
+	}
+	return r.cfg.Section(metaSection).Key(key).String()
+}
+
+// SetSetting writes a key of the [meta] section.
+func (r *INIRepository) SetSetting(key, value string) error {
+	if value == "" {
+		if r.cfg.HasSection(metaSection) {
+			r.cfg.Section(metaSection).DeleteKey(key)
+		}
+		return nil
+	}
+	r.cfg.Section(metaSection).Key(key).SetValue(value)
+	return nil
+}
+
+// Save writes the INI file.
+func (r *INIRepository) Save() error {
+	return r.cfg.SaveTo(r.file)
+}
+
+// folderSection is a config section holding the rules of one folder.
+type folderSection struct {
+	name   string
+	folder string
+}
+
+// sections returns the folder sections of a profile, in file order.
+func (r *INIRepository) sections(profile string) []folderSection {
+	profiles := make(map[string]bool)
+	for _, p := range r.Profiles() {
+		profiles[p.Name] = true
+	}
+
+	var sections []folderSection
+	for _, name := range r.cfg.SectionStrings() {
+		if reservedSection(name) {
+			continue
+		}
+
+		p, folder, named := strings.Cut(name, profileSeparator)
+		// Files written before ':' was escaped hold roots such as
+		// [D:\Videos] in the default profile.
+		if named && !profiles[p] {
+			named = false
+		}
+		switch {
+		case !named && profile == DefaultProfile:
+			sections = append(sections, folderSection{name: name, folder: unescapeFolder(name)})
+		case named && p == profile:
+			sections = append(sections, folderSection{name: name, folder: unescapeFolder(folder)})
+		}
+	}
+	return sections
+}
+
+// sectionName returns the config section holding a profile's rules for
+// folder.
+func sectionName(profile, folder string) string {
+	if profile == DefaultProfile {
+		return escapeFolder(folder)
+	}
+	return profile + profileSeparator + escapeFolder(folder)
+}
+
+func reservedSection(name string) bool {
+	return name == ini.DefaultSection || name == profilesSection || name == metaSection
+}
+
+// escapeFolder writes folder as it appears in a section name. '%' and ':'
+// are percent-encoded, and so is the first letter of a folder that would
+// read as a settings section, such as "meta".
+func escapeFolder(folder string) string {
+	folder = strings.ReplaceAll(folder, "%", "%25")
+	folder = strings.ReplaceAll(folder, profileSeparator, "%3A")
+	if reservedSection(folder) {
+		folder = fmt.Sprintf("%%%02X", folder[0]) + folder[1:]
+	}
+	return folder
+}
+
+// unescapeFolder reverses escapeFolder. Names that are not valid escapes,
+// as written before folders were escaped, are returned unchanged.
+func unescapeFolder(name string) string {
+	if folder, err := url.PathUnescape(name); err == nil {
+		return folder
+	}
+	return name
+}
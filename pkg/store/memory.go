@@ -0,0 +1,104 @@
+package store
+
+import (
+	"fmt"
+	"slices"
+)
+
+// MemoryRepository keeps rules in memory only. It is useful for library
+// users who manage rules themselves, and for tests.
+type MemoryRepository struct {
+	rules    map[string][]Rule
+	profiles []Profile
+	settings map[string]string
+}
+
+// NewMemoryRepository returns a repository holding the given rules in
+// DefaultProfile.
+func NewMemoryRepository(rules ...Rule) *MemoryRepository {
+	m := &MemoryRepository{
+		rules:    make(map[string][]Rule),
+		settings: make(map[string]string),
+	}
+	for _, r := range rules {
+		_ = m.Insert(DefaultProfile, r)
+	}
+	return m
+}
+
+// Rules returns a copy of the profile's rules.
+func (m *MemoryRepository) Rules(profile string) []Rule {
+	return slices.Clone(m.rules[profile])
+}
+
+// Insert places the rule after the last rule of the same folder, keeping
+// rules grouped by folder.
+func (m *MemoryRepository) Insert(profile string, rule Rule) error {
+	if slices.Contains(m.rules[profile], rule) {
+		return nil
+	}
+
+	m.Delete(profile, rule.Extension)
+	rules := m.rules[profile]
+
+	at := len(rules)
+	for i, r := range rules {
+		if r.Folder == rule.Folder {
+			at = i + 1
+		}
+	}
+	m.rules[profile] = slices.Insert(rules, at, rule)
+	return nil
+}
+
+// Delete removes the profile's rule for ext.
+func (m *MemoryRepository) Delete(profile, ext string) bool {
+	rules := m.rules[profile]
+	i := slices.IndexFunc(rules, func(r Rule) bool { return r.Extension == ext })
+	if i < 0 {
+		return false
+	}
+	m.rules[profile] = slices.Delete(rules, i, i+1)
+	return true
+}
+
+// Profiles returns a copy of the named profiles.
+func (m *MemoryRepository) Profiles() []Profile {
+	return slices.Clone(m.profiles)
+}
+
+// CreateProfile adds a profile.
+func (m *MemoryRepository) CreateProfile(name, extends string) error {
+	if name == DefaultProfile || slices.ContainsFunc(m.profiles, func(p Profile) bool { return p.Name == name }) {
+		return fmt.Errorf("%w: %q", ErrProfileExists, name)
+	}
+	m.profiles = append(m.profiles, Profile{Name: name, Extends: extends})
+	return nil
+}
+
+// DeleteProfile removes a profile and its rules.
+func (m *MemoryRepository) DeleteProfile(name string) error {
+	m.profiles = slices.DeleteFunc(m.profiles, func(p Profile) bool { return p.Name == name })
+	delete(m.rules, name)
+	return nil
+}
+
+// Setting returns a setting.
+func (m *MemoryRepository) Setting(key string) string {
+	return m.settings[key]
+}
+
+// SetSetting changes a setting.
+func (m *MemoryRepository) SetSetting(key, value string) error {
+	if value == "" {
+		delete(m.settings, key)
+		return nil
+	}
+	m.settings[key] = value
+	return nil
+}
+
+// Save does nothing.
+func (m *MemoryRepository) Save() error {
+	return nil
+}
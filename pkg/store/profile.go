@@ -0,0 +1,191 @@
+package store
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DefaultProfile is the profile every rules database has.
+const DefaultProfile = "default"
+
+// defaultProfileSetting is the repository setting naming the default profile.
+const defaultProfileSetting = "default_profile"
+
+// Profile describes a named rule set.
+type Profile struct {
+	Name string `json:"name"`
+	// Extends is the profile whose rules this one inherits and overrides,
+	// or empty.
+	Extends string `json:"extends,omitempty"`
+	// Default reports whether the profile is selected when none is given.
+	Default bool `json:"default"`
+}
+
+// WithProfile selects the profile whose rules the store reads and edits,
+// instead of the database's default profile.
+func WithProfile(name string) Option {
+	return func(s *Store) {
+		s.profile = name
+	}
+}
+
+// Profile returns the name of the profile the store is using.
+func (s *Store) Profile() string {
+	return s.profile
+}
+
+// UseProfile switches the store to another profile.
+func (s *Store) UseProfile(name string) error {
+	if !s.hasProfile(name) {
+		return fmt.Errorf("%w: %q", ErrProfileNotFound, name)
+	}
+	s.profile = name
+	s.reindex()
+	s.log.Debug("using rule profile", "profile", name, "rules", len(s.index))
+	return nil
+}
+
+// Profiles returns every profile, starting with DefaultProfile, then in the
+// order they were created.
+func (s *Store) Profiles() []Profile {
+	def := s.DefaultProfile()
+	profiles := []Profile{{Name: DefaultProfile, Default: def == DefaultProfile}}
+
+	for _, p := range s.repo.Profiles() {
+		p.Default = def == p.Name
+		profiles = append(profiles, p)
+	}
+	return profiles
+}
+
+// DefaultProfile returns the profile used when none is selected.
+func (s *Store) DefaultProfile() string {
+	if name := s.repo.Setting(defaultProfileSetting); s.hasProfile(name) {
+		return name
+	}
+	return DefaultProfile
+}
+
+// SetDefaultProfile makes name the profile used when none is selected.
+func (s *Store) SetDefaultProfile(name string) error {
+	if !s.hasProfile(name) {
+		return fmt.Errorf("%w: %q", ErrProfileNotFound, name)
+	}
+	if name == DefaultProfile {
+		name = ""
+	}
+	return s.repo.SetSetting(defaultProfileSetting, name)
+}
+
+// CreateProfile adds an empty profile. If extends is not empty, the new
+// profile inherits the rules of that profile and overrides them with its own.
+func (s *Store) CreateProfile(name, extends string) error {
+	if err := s.checkNewProfile(name); err != nil {
+		return err
+	}
+	if extends != "" && !s.hasProfile(extends) {
+		return fmt.Errorf("%w: %q", ErrProfileNotFound, extends)
+	}
+
+	return s.repo.CreateProfile(name, extends)
+}
+
+// CloneProfile creates dst with a copy of the rules of src and the same
+// parent. Later changes to either profile do not affect the other.
+func (s *Store) CloneProfile(src, dst string) error {
+	if !s.hasProfile(src) {
+		return fmt.Errorf("%w: %q", ErrProfileNotFound, src)
+	}
+	if err := s.CreateProfile(dst, s.parent(src)); err != nil {
+		return err
+	}
+
+	for _, r := range s.repo.Rules(src) {
+		if err := s.repo.Insert(dst, r); err != nil {
+			return err
+		}
+	}
+	return s.repo.SetSetting(defaultsVersionKey(dst), s.repo.Setting(defaultsVersionKey(src)))
+}
+
+// DeleteProfile removes a profile and its rules. DefaultProfile and profiles
+// extended by others cannot be deleted. If name was the default profile,
+// DefaultProfile becomes the default again, and a store using it switches
+// to the new default.
+func (s *Store) DeleteProfile(name string) error {
+	if name == DefaultProfile {
+		return ErrDeleteDefaultProfile
+	}
+	if !s.hasProfile(name) {
+		return fmt.Errorf("%w: %q", ErrProfileNotFound, name)
+	}
+	for _, p := range s.Profiles() {
+		if p.Extends == name {
+			return fmt.Errorf("%w: %q is extended by %q", ErrProfileInUse, name, p.Name)
+		}
+	}
+	wasDefault := s.DefaultProfile() == name
+
+	if err := s.repo.DeleteProfile(name); err != nil {
+		return err
+	}
+	if err := s.repo.SetSetting(defaultsVersionKey(name), ""); err != nil {
+		return err
+	}
+
+	if wasDefault {
+		if err := s.SetDefaultProfile(DefaultProfile); err != nil {
+			return err
+		}
+	}
+	if s.profile == name {
+		s.profile = s.DefaultProfile()
+		s.reindex()
+	}
+	return nil
+}
+
+func (s *Store) checkNewProfile(name string) error {
+	if name == "" || strings.ContainsAny(name, profileSeparator+"=[]; \t") {
+		return fmt.Errorf("%w: %q", ErrInvalidProfileName, name)
+	}
+	if s.hasProfile(name) {
+		return fmt.Errorf("%w: %q", ErrProfileExists, name)
+	}
+	return nil
+}
+
+func (s *Store) hasProfile(name string) bool {
+	_, ok := s.findProfile(name)
+	return ok
+}
+
+func (s *Store) parent(name string) string {
+	p, _ := s.findProfile(name)
+	return p.Extends
+}
+
+func (s *Store) findProfile(name string) (Profile, bool) {
+	if name == DefaultProfile {
+		return Profile{Name: DefaultProfile}, true
+	}
+	for _, p := range s.repo.Profiles() {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return Profile{}, false
+}
+
+// chain returns the profile followed by its ancestors. A cycle in a
+// hand-edited file ends the chain instead of looping.
+func (s *Store) chain(name string) []string {
+	var chain []string
+	seen := make(map[string]bool)
+	for name != "" && !seen[name] && s.hasProfile(name) {
+		seen[name] = true
+		chain = append(chain, name)
+		name = s.parent(name)
+	}
+	return chain
+}
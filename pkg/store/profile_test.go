@@ -0,0 +1,197 @@
+package store_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/d6o/Gorganizer/pkg/store"
+)
+
+func TestProfiles_Isolation(t *testing.T) {
+	t.Parallel()
+	s := newTestStore(t, "en")
+
+	if err := s.CreateProfile("work", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.UseProfile("work"); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(s.Rules()) != 0 {
+		t.Errorf("new profile without parent should have no rules, got %d", len(s.Rules()))
+	}
+	if err := s.InsertRule("pdf:Invoices"); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.Lookup("pdf"); got != "Invoices" {
+		t.Errorf("Lookup(pdf) in work = %q, want Invoices", got)
+	}
+	if got := s.Lookup("mp3"); got != "" {
+		t.Errorf("Lookup(mp3) in work = %q, want no rule", got)
+	}
+
+	if err := s.UseProfile(store.DefaultProfile); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.Lookup("pdf"); got != "Documents" {
+		t.Errorf("Lookup(pdf) in default = %q, want Documents", got)
+	}
+	for _, r := range s.Rules() {
+		if r.Folder == "Invoices" || r.Folder == "work:Invoices" {
+			t.Errorf("default profile lists rule of work: %+v", r)
+		}
+	}
+}
+
+func TestProfiles_Inheritance(t *testing.T) {
+	t.Parallel()
+	s := newTestStore(t, "en")
+
+	if err := s.CreateProfile("photos", store.DefaultProfile); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.UseProfile("photos"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.InsertRule("jpg:Camera"); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := s.Lookup("jpg"); got != "Camera" {
+		t.Errorf("Lookup(jpg) = %q, want the override", got)
+	}
+	if got := s.Lookup("mp3"); got != "Music" {
+		t.Errorf("Lookup(mp3) = %q, want the inherited rule", got)
+	}
+
+	jpg := 0
+	for _, r := range s.Rules() {
+		if r.Extension == "jpg" {
+			jpg++
+			if r.Folder != "Camera" {
+				t.Errorf("Rules lists jpg in %q, want Camera", r.Folder)
+			}
+		}
+	}
+	if jpg != 1 {
+		t.Errorf("Rules lists jpg %d times, want once", jpg)
+	}
+
+	// Deleting the override uncovers the inherited rule.
+	if err := s.DeleteRule("jpg"); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.Lookup("jpg"); got != "Pictures" {
+		t.Errorf("Lookup(jpg) after delete = %q, want Pictures", got)
+	}
+	// Inherited rules cannot be deleted from the child.
+	if err := s.DeleteRule("mp3"); !errors.Is(err, store.ErrRuleInherited) {
+		t.Errorf("DeleteRule(mp3) in photos = %v, want ErrRuleInherited", err)
+	}
+	if got := s.Lookup("mp3"); got != "Music" {
+		t.Errorf("Lookup(mp3) = %q, deleting from photos must not touch default", got)
+	}
+}
+
+func TestProfiles_Clone(t *testing.T) {
+	t.Parallel()
+	s := newTestStore(t, "en")
+
+	if err := s.CloneProfile(store.DefaultProfile, "copy"); err != nil {
+		t.Fatal(err)
+	}
+	want := len(s.Rules())
+
+	if err := s.UseProfile("copy"); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(s.Rules()); got != want {
+		t.Errorf("clone has %d rules, want %d", got, want)
+	}
+
+	if err := s.DeleteRule("mp3"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.UseProfile(store.DefaultProfile); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.Lookup("mp3"); got != "Music" {
+		t.Errorf("editing the clone changed the original: Lookup(mp3) = %q", got)
+	}
+}
+
+func TestProfiles_DefaultAndDelete(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+
+	s1, err := store.NewStore("en", store.WithConfigDir(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s1.CreateProfile("work", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := s1.SetDefaultProfile("work"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s1.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	s2 := newTestStoreIn(t, dir)
+	if s2.Profile() != "work" {
+		t.Errorf("Profile() = %q, want the saved default", s2.Profile())
+	}
+
+	profiles := s2.Profiles()
+	if len(profiles) != 2 || profiles[0].Name != store.DefaultProfile || !profiles[1].Default {
+		t.Errorf("Profiles() = %+v", profiles)
+	}
+
+	if err := s2.DeleteProfile("work"); err != nil {
+		t.Fatal(err)
+	}
+	if s2.Profile() != store.DefaultProfile || s2.DefaultProfile() != store.DefaultProfile {
+		t.Errorf("after deleting the default, Profile() = %q, DefaultProfile() = %q", s2.Profile(), s2.DefaultProfile())
+	}
+}
+
+func TestProfiles_Errors(t *testing.T) {
+	t.Parallel()
+	s := newTestStore(t, "en")
+
+	if err := s.CreateProfile("base", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.CreateProfile("child", "base"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{"duplicate", s.CreateProfile("base", ""), store.ErrProfileExists},
+		{"default taken", s.CreateProfile(store.DefaultProfile, ""), store.ErrProfileExists},
+		{"empty name", s.CreateProfile("", ""), store.ErrInvalidProfileName},
+		{"colon", s.CreateProfile("a:b", ""), store.ErrInvalidProfileName},
+		{"missing parent", s.CreateProfile("x", "nope"), store.ErrProfileNotFound},
+		{"use missing", s.UseProfile("nope"), store.ErrProfileNotFound},
+		{"clone missing", s.CloneProfile("nope", "y"), store.ErrProfileNotFound},
+		{"delete default", s.DeleteProfile(store.DefaultProfile), store.ErrDeleteDefaultProfile},
+		{"delete extended", s.DeleteProfile("base"), store.ErrProfileInUse},
+		{"default missing", s.SetDefaultProfile("nope"), store.ErrProfileNotFound},
+	}
+
+	for _, tt := range tests {
+		if !errors.Is(tt.err, tt.want) {
+			t.Errorf("%s: error = %v, want %v", tt.name, tt.err, tt.want)
+		}
+	}
+
+	if _, err := store.NewStore("en", store.WithConfigDir(t.TempDir()), store.WithProfile("nope")); !errors.Is(err, store.ErrProfileNotFound) {
+		t.Errorf("NewStore with unknown profile: error = %v", err)
+	}
+}
@@ -0,0 +1,64 @@
+package store
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// RuleRepository is where a Store keeps its rules. Rules are grouped by
+// profile; DefaultProfile always exists and is not listed by Profiles.
+// Extensions are passed in lower case and folder names already normalized;
+// validation and profile inheritance are left to the Store.
+type RuleRepository interface {
+	// Rules returns the profile's own rules, grouped by folder.
+	Rules(profile string) []Rule
+	// Insert maps rule.Extension to rule.Folder in the profile, replacing
+	// the profile's previous rule for the extension.
+	Insert(profile string, rule Rule) error
+	// Delete removes the profile's rule for ext and reports whether there
+	// was one.
+	Delete(profile, ext string) bool
+
+	// Profiles returns the named profiles in creation order.
+	Profiles() []Profile
+	// CreateProfile adds an empty profile extending another one, or none.
+	CreateProfile(name, extends string) error
+	// DeleteProfile removes a profile and its rules.
+	DeleteProfile(name string) error
+
+	// Setting returns a database setting, or "" if it is not set.
+	Setting(key string) string
+	// SetSetting changes a database setting. An empty value removes it.
+	SetSetting(key, value string) error
+
+	// Save persists the rules, if the repository is backed by storage.
+	Save() error
+}
+
+// isDataFile reports whether a config file is stored in one of the formats
+// of FileRepository rather than INI.
+func isDataFile(file string) bool {
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".json", ".yaml", ".yml":
+		return true
+	}
+	return false
+}
+
+// openRepository loads the rules stored in file, choosing the backend from
+// the file extension.
+func openRepository(file string) (RuleRepository, error) {
+	if isDataFile(file) {
+		return OpenFileRepository(file)
+	}
+	return OpenINIRepository(file)
+}
+
+// newRepository returns an empty repository that saves to file, choosing the
+// backend from the file extension.
+func newRepository(file string) (RuleRepository, error) {
+	if isDataFile(file) {
+		return NewFileRepository(file)
+	}
+	return NewINIRepository(file), nil
+}
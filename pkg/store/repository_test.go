@@ -0,0 +1,197 @@
+package store_test
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/d6o/Gorganizer/pkg/store"
+)
+
+func TestWithRepository_Memory(t *testing.T) {
+	t.Parallel()
+
+	repo := store.NewMemoryRepository(store.Rule{Extension: "mp3", Folder: "Music"})
+	s, err := store.NewStore("en", store.WithRepository(repo))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := len(s.Rules()); got != 1 {
+		t.Errorf("a repository with rules should not get the defaults, got %d rules", got)
+	}
+	if err := s.InsertRule("pdf:Docs"); err != nil {
+		t.Fatal(err)
+	}
+	if got := repo.Rules(store.DefaultProfile); len(got) != 2 {
+		t.Errorf("repository rules = %v, want the inserted rule too", got)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWithRepository_EmptyGetsDefaults(t *testing.T) {
+	t.Parallel()
+
+	s, err := store.NewStore("en", store.WithRepository(store.NewMemoryRepository()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := s.Lookup("mp3"); got != "Music" {
+		t.Errorf("Lookup(mp3) = %q, want the default rule", got)
+	}
+}
+
+func TestRepository_InsertReplaces(t *testing.T) {
+	t.Parallel()
+
+	repos := map[string]store.RuleRepository{
+		"memory": store.NewMemoryRepository(),
+		"ini":    store.NewINIRepository(filepath.Join(t.TempDir(), "rules.ini")),
+	}
+	for name, repo := range repos {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			for _, r := range []store.Rule{
+				{Extension: "mp3", Folder: "Music"},
+				{Extension: "ogg", Folder: "Music"},
+				{Extension: "mp3", Folder: "Audio"},
+			} {
+				if err := repo.Insert(store.DefaultProfile, r); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			want := map[string]string{"mp3": "Audio", "ogg": "Music"}
+			rules := repo.Rules(store.DefaultProfile)
+			if len(rules) != len(want) {
+				t.Fatalf("Rules() = %v, want one rule per extension", rules)
+			}
+			for _, r := range rules {
+				if want[r.Extension] != r.Folder {
+					t.Errorf("rule %+v, want folder %q", r, want[r.Extension])
+				}
+			}
+
+			if !repo.Delete(store.DefaultProfile, "mp3") {
+				t.Error("Delete(mp3) = false, want true")
+			}
+			if repo.Delete(store.DefaultProfile, "mp3") {
+				t.Error("second Delete(mp3) = true, want false")
+			}
+		})
+	}
+}
+
+func TestFileRepository_SaveAndReload(t *testing.T) {
+	t.Parallel()
+
+	for _, name := range []string{"rules.json", "rules.yaml", "rules.yml"} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			file := filepath.Join(t.TempDir(), name)
+
+			s1, err := store.NewStore("en", store.WithConfigFile(file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := s1.InsertRule("xyz:Custom"); err != nil {
+				t.Fatal(err)
+			}
+			if err := s1.CreateProfile("work", store.DefaultProfile); err != nil {
+				t.Fatal(err)
+			}
+			if err := s1.UseProfile("work"); err != nil {
+				t.Fatal(err)
+			}
+			if err := s1.InsertRule("pdf:Invoices"); err != nil {
+				t.Fatal(err)
+			}
+			if err := s1.SetDefaultProfile("work"); err != nil {
+				t.Fatal(err)
+			}
+			if err := s1.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			s2, err := store.NewStore("pt", store.WithConfigFile(file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := s2.Profile(); got != "work" {
+				t.Errorf("Profile() = %q, want the saved default", got)
+			}
+			if got := s2.Lookup("pdf"); got != "Invoices" {
+				t.Errorf("Lookup(pdf) = %q, want Invoices", got)
+			}
+			if got := s2.Lookup("xyz"); got != "Custom" {
+				t.Errorf("Lookup(xyz) = %q, want the inherited Custom", got)
+			}
+			if got := s2.Lookup("mp3"); got != "Music" {
+				t.Errorf("Lookup(mp3) = %q, want the defaults the file was created with", got)
+			}
+		})
+	}
+}
+
+func TestNewFileRepository_UnsupportedFormat(t *testing.T) {
+	t.Parallel()
+
+	if _, err := store.NewFileRepository("rules.csv"); err == nil {
+		t.Error("NewFileRepository(rules.csv) should fail")
+	}
+}
+
+func TestINIRepository_VolumeRoots(t *testing.T) {
+	t.Parallel()
+	file := filepath.Join(t.TempDir(), "rules.ini")
+
+	repo := store.NewINIRepository(file)
+	if err := repo.CreateProfile("work", store.DefaultProfile); err != nil {
+		t.Fatal(err)
+	}
+	for profile, r := range map[string]store.Rule{
+		store.DefaultProfile: {Extension: "mkv", Folder: `D:\Videos`},
+		"work":               {Extension: "pdf", Folder: `E:\Shared:Docs`},
+	} {
+		if err := repo.Insert(profile, r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := repo.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened, err := store.OpenINIRepository(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := reopened.Rules(store.DefaultProfile); !slices.Equal(got, []store.Rule{{Extension: "mkv", Folder: `D:\Videos`}}) {
+		t.Errorf("default rules = %+v, want the D: root", got)
+	}
+	if got := reopened.Rules("work"); !slices.Equal(got, []store.Rule{{Extension: "pdf", Folder: `E:\Shared:Docs`}}) {
+		t.Errorf("work rules = %+v, want the E: root", got)
+	}
+	if got := reopened.Rules("D"); len(got) != 0 {
+		t.Errorf("rules of profile D = %+v, want none", got)
+	}
+}
+
+func TestINIRepository_UnescapedVolumeRoots(t *testing.T) {
+	t.Parallel()
+	file := filepath.Join(t.TempDir(), "rules.ini")
+	if err := os.WriteFile(file, []byte("[D:\\Videos]\nmkv =\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	repo, err := store.OpenINIRepository(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := repo.Rules(store.DefaultProfile); !slices.Equal(got, []store.Rule{{Extension: "mkv", Folder: `D:\Videos`}}) {
+		t.Errorf("default rules = %+v, want the D: root written before escaping", got)
+	}
+}
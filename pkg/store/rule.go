@@ -2,6 +2,6 @@ package store
 
 // Rule represents a mapping from a file extension to a destination folder.
 type Rule struct {
-	Extension string
-	Folder    string
+	Extension string `json:"extension" yaml:"extension"`
+	Folder    string `json:"folder" yaml:"folder"`
 }
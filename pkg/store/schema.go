@@ -0,0 +1,127 @@
+package store
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// SchemaVersion is the version of the config file layout this version of
+// Gorganizer writes. It is stored in the file's settings, the [meta] section
+// of an INI file; files without it are version 1.
+const SchemaVersion = 2
+
+const schemaSetting = "schema_version"
+
+// migration upgrades a repository from schema version from to from+1.
+type migration struct {
+	from  int
+	apply func(RuleRepository) error
+}
+
+// migrations are run in order on files older than SchemaVersion. Add one,
+// and bump SchemaVersion, whenever the layout changes.
+var migrations = []migration{
+	{from: 1, apply: migrateV1},
+}
+
+// schemaVersion returns the schema version the repository was written with.
+func schemaVersion(repo RuleRepository) (int, error) {
+	value := repo.Setting(schemaSetting)
+	if value == "" {
+		return 1, nil
+	}
+	v, err := strconv.Atoi(value)
+	if err != nil || v < 1 {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidSchema, value)
+	}
+	return v, nil
+}
+
+// migrate upgrades the repository loaded from file to SchemaVersion. The
+// original file is first copied to file.v<version>.bak. Files written by a
+// newer version are rejected rather than risk losing what this version does
+// not understand.
+func (s *Store) migrate(file string) error {
+	name := file
+	if name == "" {
+		name = "repository"
+	}
+
+	v, err := schemaVersion(s.repo)
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	if v > SchemaVersion {
+		return fmt.Errorf("%w: %s has schema version %d, this version reads up to %d", ErrNewerSchema, name, v, SchemaVersion)
+	}
+	if v == SchemaVersion {
+		return nil
+	}
+
+	if file != "" {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(backupFile(file, v), data, 0o644); err != nil {
+			return err
+		}
+	}
+
+	for _, m := range migrations {
+		if m.from < v {
+			continue
+		}
+		if err := m.apply(s.repo); err != nil {
+			return fmt.Errorf("migrating %s from schema version %d: %w", name, m.from, err)
+		}
+	}
+	if err := s.repo.SetSetting(schemaSetting, strconv.Itoa(SchemaVersion)); err != nil {
+		return err
+	}
+	if err := s.repo.Save(); err != nil {
+		return err
+	}
+
+	s.log.Info("upgraded rules file", "file", name, "from", v, "to", SchemaVersion)
+	s.emitEvent(EventDatabaseMigrated)
+	return nil
+}
+
+func backupFile(file string, version int) string {
+	return file + ".v" + strconv.Itoa(version) + ".bak"
+}
+
+// migrateV1 lower cases extensions, which version 1 files could hold in any
+// case although lookups only ever matched lower case, and records that the
+// rules were created from version 1 of the default rules.
+func migrateV1(repo RuleRepository) error {
+	profiles := []string{DefaultProfile}
+	for _, p := range repo.Profiles() {
+		profiles = append(profiles, p.Name)
+	}
+
+	for _, p := range profiles {
+		for _, r := range repo.Rules(p) {
+			ext := strings.ToLower(r.Extension)
+			if ext == r.Extension {
+				continue
+			}
+			repo.Delete(p, r.Extension)
+			if slices.ContainsFunc(repo.Rules(p), func(r Rule) bool { return r.Extension == ext }) {
+				continue
+			}
+			if err := repo.Insert(p, Rule{Extension: ext, Folder: r.Folder}); err != nil {
+				return err
+			}
+		}
+	}
+
+	if repo.Setting(defaultsVersionSetting) == "" {
+		return repo.SetSetting(defaultsVersionSetting, "1")
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package store_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/d6o/Gorganizer/pkg/store"
+)
+
+// copyFixture copies a file from testdata into a temporary directory and
+// returns its path there.
+func copyFixture(t *testing.T, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(file, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func readFile(t *testing.T, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestMigrate(t *testing.T) {
+	t.Parallel()
+
+	for _, fixture := range []string{"v1.ini", "v1.yaml"} {
+		t.Run(fixture, func(t *testing.T) {
+			t.Parallel()
+			file := copyFixture(t, fixture)
+
+			var events []store.Event
+			s, err := store.NewStore("en", store.WithConfigFile(file), store.WithEventHandler(func(e store.Event) {
+				events = append(events, e)
+			}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(events) != 1 || events[0] != store.EventDatabaseMigrated {
+				t.Errorf("events = %v, want only EventDatabaseMigrated", events)
+			}
+
+			if got, want := readFile(t, file), readFile(t, filepath.Join("testdata", fixture+".golden")); got != want {
+				t.Errorf("migrated file:\n%s\nwant:\n%s", got, want)
+			}
+			if got, want := readFile(t, file+".v1.bak"), readFile(t, filepath.Join("testdata", fixture)); got != want {
+				t.Errorf("backup:\n%s\nwant the original file:\n%s", got, want)
+			}
+
+			if got := s.Lookup("mp3"); got != "Audio" {
+				t.Errorf("Lookup(mp3) = %q, want the upper case rule to match", got)
+			}
+			if got := s.DefaultsVersion(); got != 1 {
+				t.Errorf("DefaultsVersion() = %d, want 1", got)
+			}
+			if err := s.UseProfile("photos"); err != nil {
+				t.Fatal(err)
+			}
+			if got := s.Lookup("jpg"); got != "Camera" {
+				t.Errorf("Lookup(jpg) in photos = %q, want Camera", got)
+			}
+		})
+	}
+}
+
+func TestMigrate_CurrentFileUntouched(t *testing.T) {
+	t.Parallel()
+	file := copyFixture(t, "v2.ini")
+
+	if _, err := store.NewStore("en", store.WithConfigFile(file)); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := readFile(t, file), readFile(t, "testdata/v2.ini"); got != want {
+		t.Errorf("current file was rewritten:\n%s", got)
+	}
+	if _, err := os.Stat(file + ".v2.bak"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("current file was backed up: %v", err)
+	}
+}
+
+func TestMigrate_Rejects(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		fixture string
+		want    error
+	}{
+		{"newer.ini", store.ErrNewerSchema},
+		{"invalid.ini", store.ErrInvalidSchema},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			t.Parallel()
+			file := copyFixture(t, tt.fixture)
+
+			if _, err := store.NewStore("en", store.WithConfigFile(file)); !errors.Is(err, tt.want) {
+				t.Errorf("NewStore() = %v, want %v", err, tt.want)
+			}
+			if got, want := readFile(t, file), readFile(t, filepath.Join("testdata", tt.fixture)); got != want {
+				t.Errorf("rejected file was changed:\n%s", got)
+			}
+		})
+	}
+}
+
+func TestNewStore_WritesSchemaVersion(t *testing.T) {
+	t.Parallel()
+	file := filepath.Join(t.TempDir(), "rules.ini")
+
+	s, err := store.NewStore("en", store.WithConfigFile(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var events []store.Event
+	if _, err := store.NewStore("en", store.WithConfigFile(file), store.WithEventHandler(func(e store.Event) {
+		events = append(events, e)
+	})); err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 0 {
+		t.Errorf("reopening a new file emitted %v, want no migration", events)
+	}
+}
@@ -1,13 +1,16 @@
-// Package store manages file extension to folder mapping rules backed by an INI config file.
+// Package store manages file extension to folder mapping rules. Rules are kept
+// in a RuleRepository: an INI config file by default, a JSON or YAML file, or
+// memory.
 package store
 
 import (
+	"errors"
+	"io/fs"
+	"log/slog"
 	"os/user"
 	"path/filepath"
 	"strings"
 	"unicode"
-
-	"gopkg.in/ini.v1"
 )
 
 const configFile = ".gorganizer-{lang}.ini"
@@ -22,6 +25,10 @@ const (
 	EventCreatingDefaults
 	// EventDefaultsInitialized is emitted after default rules have been written.
 	EventDefaultsInitialized
+	// EventDatabaseMigrated is emitted after a config file written in an
+	// older format has been upgraded. A backup of the old file is kept next
+	// to it.
+	EventDatabaseMigrated
 )
 
 // Option configures a Store during construction.
@@ -44,69 +51,137 @@ func WithConfigDir(dir string) Option {
 	}
 }
 
-// Store manages file extension to folder mapping rules backed by an INI config file.
+// WithConfigFile sets the exact config file to use, ignoring the language
+// file name and search path. The file is created with default rules if it
+// does not exist. Files ending in .json, .yaml or .yml are stored with a
+// FileRepository, anything else as INI.
+func WithConfigFile(file string) Option {
+	return func(s *Store) {
+		s.configFile = file
+	}
+}
+
+// WithRepository makes the store use repo instead of a config file. If repo
+// holds no rules and no profiles, it is filled with the default rules. The
+// store indexes the rules when it is created, so repo must not be changed
+// other than through the store afterwards.
+func WithRepository(repo RuleRepository) Option {
+	return func(s *Store) {
+		s.repo = repo
+	}
+}
+
+// WithLogger sets the logger the store reports to: which file it loaded
+// and how it changed the rules at debug level, files it could not read at
+// warn level. By default nothing is logged.
+func WithLogger(logger *slog.Logger) Option {
+	return func(s *Store) {
+		if logger != nil {
+			s.log = logger
+		}
+	}
+}
+
+// Store manages file extension to folder mapping rules.
 type Store struct {
-	cfg       *ini.File
-	cfgFile   string
-	onEvent   func(Event)
-	configDir string
+	repo       RuleRepository
+	onEvent    func(Event)
+	configDir  string
+	configFile string
+	profile    string
+	log        *slog.Logger
+
+	// index maps every extension the current profile resolves, including
+	// inherited rules, to its folder.
+	index map[string]string
 }
 
 // NewStore creates a Store for the given language. It searches for a config file
 // in the current directory and the user's home directory. If none is found, it
-// creates a new config with default rules for 60+ file types.
+// creates a new config with default rules for 60+ file types. The store uses
+// the database's default profile unless WithProfile selects another one.
 func NewStore(lang string, opts ...Option) (*Store, error) {
 	if lang == "" {
 		lang = "en"
 	}
 
-	s := &Store{}
+	s := &Store{
+		index: make(map[string]string),
+		log:   slog.New(slog.DiscardHandler),
+	}
 	for _, opt := range opts {
 		opt(s)
 	}
 
+	profile := s.profile
+	s.profile = DefaultProfile
+	if err := s.load(lang); err != nil {
+		return nil, err
+	}
+
+	if profile == "" {
+		profile = s.DefaultProfile()
+	}
+	if err := s.UseProfile(profile); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// load opens the repository, or creates it with the defaults for lang.
+func (s *Store) load(lang string) error {
 	cfgFileName := strings.Replace(configFile, "{lang}", lang, 1)
 
-	if s.configDir != "" {
-		file := filepath.Join(s.configDir, cfgFileName)
-		if s.tryLoad(file) {
-			return s, nil
+	var file string
+	switch {
+	case s.repo != nil:
+		if len(s.repo.Rules(DefaultProfile)) > 0 || len(s.repo.Profiles()) > 0 {
+			return s.migrate("")
 		}
-		s.cfg = ini.Empty()
-		s.cfgFile = file
-	} else {
+		return s.populateDefaults(lang)
+
+	case s.configFile != "":
+		file = s.configFile
+
+	case s.configDir != "":
+		file = filepath.Join(s.configDir, cfgFileName)
+
+	default:
 		if s.tryLoad(cfgFileName) {
-			return s, nil
+			return s.migrate(cfgFileName)
 		}
 
 		currentUser, err := user.Current()
 		if err != nil {
-			return nil, err
-		}
-
-		homeFile := filepath.Join(currentUser.HomeDir, cfgFileName)
-		if s.tryLoad(homeFile) {
-			return s, nil
+			return err
 		}
+		file = filepath.Join(currentUser.HomeDir, cfgFileName)
+	}
 
-		s.cfg = ini.Empty()
-		s.cfgFile = homeFile
+	if s.tryLoad(file) {
+		return s.migrate(file)
 	}
 
-	if err := s.populateDefaults(lang); err != nil {
-		return nil, err
+	repo, err := newRepository(file)
+	if err != nil {
+		return err
 	}
+	s.repo = repo
 
-	return s, nil
+	return s.populateDefaults(lang)
 }
 
 func (s *Store) tryLoad(file string) bool {
-	cfg, err := ini.LoadSources(ini.LoadOptions{AllowBooleanKeys: true, Loose: false}, file)
+	repo, err := openRepository(file)
 	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			s.log.Warn("cannot read rules file", "file", file, "error", err)
+		}
 		return false
 	}
-	s.cfg = cfg
-	s.cfgFile = file
+	s.log.Debug("loaded rules", "file", file)
+	s.repo = repo
 	return true
 }
 
@@ -116,63 +191,116 @@ func (s *Store) emitEvent(evt Event) {
 	}
 }
 
+// Save writes the current rules to the config file on disk.
+func (s *Store) Save() error {
+	return s.repo.Save()
+}
+
 // Close saves the current rules to the config file on disk.
 func (s *Store) Close() error {
-	return s.cfg.SaveTo(s.cfgFile)
+	return s.Save()
 }
 
 // Lookup returns the destination folder name for the given file extension,
-// or an empty string if no rule matches. The lookup is case-insensitive.
+// or an empty string if no rule matches. The lookup is case-insensitive and
+// falls back to the profiles the current one extends. It is served from an
+// index, so its cost does not grow with the number of rules.
 func (s *Store) Lookup(ext string) string {
-	ext = strings.ToLower(ext)
-	sections := s.cfg.SectionStrings()
+	if folder, ok := s.index[ext]; ok {
+		return folder
+	}
+	return s.index[strings.ToLower(ext)]
+}
+
+// reindex rebuilds the index of the current profile.
+func (s *Store) reindex() {
+	s.index = make(map[string]string)
+	for _, r := range s.Rules() {
+		s.index[r.Extension] = r.Folder
+	}
+}
 
-	for _, section := range sections[1:] {
-		if s.cfg.Section(section).HasKey(ext) {
-			return section
+// resolve finds the folder of ext by walking the profile chain, without
+// the index.
+func (s *Store) resolve(ext string) string {
+	for _, profile := range s.chain(s.profile) {
+		for _, r := range s.repo.Rules(profile) {
+			if r.Extension == ext {
+				return r.Folder
+			}
 		}
 	}
 
 	return ""
 }
 
-// InsertRule adds a new extension-to-folder mapping. The rule must be in
-// "ext:folder" format (e.g., "mp3:Music"). Returns ErrInvalidRuleFormat
-// or ErrEmptyRuleComponent on invalid input.
+// InsertRule adds a new extension-to-folder mapping to the current profile,
+// replacing its previous rule for the extension. The rule must be in
+// "ext:folder" format (e.g., "mp3:Music"); several extensions can be mapped
+// to the same folder at once with "mp3,flac,ogg:Music". Returns
+// ErrInvalidRuleFormat or ErrEmptyRuleComponent on invalid input.
 func (s *Store) InsertRule(rule string) error {
-	parts := strings.Split(rule, ":")
-	if len(parts) != 2 {
-		return ErrInvalidRuleFormat
+	exts, folder, err := ParseRule(rule)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.AddRules(exts, folder, false)
+	return err
+}
+
+// ParseRule splits an "ext[,ext...]:folder" rule. The folder can be a
+// destination root such as /mnt/media/Videos, ~/Documents or, on Windows,
+// D:\Videos.
+func ParseRule(rule string) ([]string, string, error) {
+	exts, folder, ok := strings.Cut(rule, ":")
+	if !ok || strings.Contains(folder[len(filepath.VolumeName(folder)):], ":") {
+		return nil, "", ErrInvalidRuleFormat
 	}
-	if parts[0] == "" || parts[1] == "" {
-		return ErrEmptyRuleComponent
+	if exts == "" || folder == "" {
+		return nil, "", ErrEmptyRuleComponent
 	}
 
-	return s.set(parts[0], parts[1])
+	return strings.Split(exts, ","), folder, nil
 }
 
-// DeleteRule removes the rule for the given file extension. If no rule
-// exists for the extension, it is a no-op.
-func (s *Store) DeleteRule(ext string) {
-	sections := s.cfg.SectionStrings()
+// DeleteRule removes the current profile's rule for the given file
+// extension. Returns ErrRuleInherited if the rule comes from a profile the
+// current one extends, and ErrRuleNotFound if there is no rule at all.
+func (s *Store) DeleteRule(ext string) error {
+	_, err := s.RemoveRule(ext, false)
+	return err
+}
 
-	for _, section := range sections {
-		if s.cfg.Section(section).HasKey(ext) {
-			s.cfg.Section(section).DeleteKey(ext)
-			return
-		}
+// remove deletes the current profile's rule for ext, if it has one.
+func (s *Store) remove(ext string) bool {
+	if !s.repo.Delete(s.profile, ext) {
+		return false
+	}
+
+	if folder := s.resolve(ext); folder != "" {
+		s.index[ext] = folder
+	} else {
+		delete(s.index, ext)
 	}
+	return true
 }
 
-// Rules returns all configured rules ordered by folder, then by extension.
+// Rules returns the rules of the current profile ordered by folder, then by
+// extension, followed by the inherited rules it does not override.
 func (s *Store) Rules() []Rule {
 	var rules []Rule
-	sections := s.cfg.SectionStrings()
-
-	for _, section := range sections[1:] {
-		keys := s.cfg.Section(section).KeyStrings()
-		for _, key := range keys {
-			rules = append(rules, Rule{Extension: key, Folder: section})
+	seen := make(map[string]bool)
+
+	for _, profile := range s.chain(s.profile) {
+		own := s.repo.Rules(profile)
+		for _, r := range own {
+			if !seen[r.Extension] {
+				rules = append(rules, r)
+			}
+		}
+		for _, r := range own {
+			seen[r.Extension] = true
 		}
 	}
 
@@ -180,18 +308,33 @@ func (s *Store) Rules() []Rule {
 }
 
 func (s *Store) set(key, value string) error {
+	return s.put(Rule{Extension: strings.ToLower(key), Folder: s.title(value)})
+}
+
+// put adds r to the current profile as it is.
+func (s *Store) put(r Rule) error {
+	if err := s.repo.Insert(s.profile, r); err != nil {
+		return err
+	}
+	s.index[r.Extension] = r.Folder
+	return nil
+}
+
+// title capitalizes the first letter of every word of a folder name.
+// Destination roots are paths and are only cleaned.
+func (s *Store) title(folder string) string {
+	if IsRoot(folder) {
+		return filepath.Clean(folder)
+	}
 	prev := rune(' ')
-	runes := []rune(value)
+	runes := []rune(folder)
 	for i, r := range runes {
 		if s.isTitleSeparator(prev) {
 			runes[i] = unicode.ToTitle(r)
 		}
 		prev = r
 	}
-
-	key = strings.ToLower(key)
-	_, err := s.cfg.Section(string(runes)).NewKey(key, "")
-	return err
+	return string(runes)
 }
 
 func (s *Store) isTitleSeparator(r rune) bool {
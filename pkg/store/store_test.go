@@ -1,6 +1,12 @@
 package store_test
 
 import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/d6o/Gorganizer/pkg/store"
@@ -21,6 +27,20 @@ func newTestStore(t *testing.T, lang string) *store.Store {
 	return s
 }
 
+func newTestStoreIn(t *testing.T, dir string) *store.Store {
+	t.Helper()
+	s, err := store.NewStore("en", store.WithConfigDir(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := s.Close(); err != nil {
+			t.Fatal(err)
+		}
+	})
+	return s
+}
+
 func TestNewStore(t *testing.T) {
 	t.Parallel()
 
@@ -188,11 +208,16 @@ func TestDeleteRule(t *testing.T) {
 		t.Fatal("mp3 should exist before deletion")
 	}
 
-	s.DeleteRule("mp3")
+	if err := s.DeleteRule("mp3"); err != nil {
+		t.Fatal(err)
+	}
 
 	if folder := s.Lookup("mp3"); folder != "" {
 		t.Errorf("Lookup(mp3) after delete = %q, want empty", folder)
 	}
+	if err := s.DeleteRule("mp3"); !errors.Is(err, store.ErrRuleNotFound) {
+		t.Errorf("second DeleteRule(mp3) = %v, want ErrRuleNotFound", err)
+	}
 }
 
 func TestRules(t *testing.T) {
@@ -254,3 +279,81 @@ func TestTurkishTranslations(t *testing.T) {
 		t.Errorf("Lookup(pdf) with tr = %q, want %q", folder, "Dokümanlar")
 	}
 }
+
+func TestSave(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+
+	s1, err := store.NewStore("en", store.WithConfigDir(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s1.InsertRule("xyz:Saved"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s1.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	s2 := newTestStoreIn(t, dir)
+	if folder := s2.Lookup("xyz"); folder != "Saved" {
+		t.Errorf("Lookup(xyz) after Save = %q, want %q", folder, "Saved")
+	}
+}
+
+func TestWithConfigFile(t *testing.T) {
+	t.Parallel()
+	file := filepath.Join(t.TempDir(), "rules.ini")
+
+	s1, err := store.NewStore("pt", store.WithConfigFile(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s1.InsertRule("xyz:Custom"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s1.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	s2, err := store.NewStore("en", store.WithConfigFile(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if folder := s2.Lookup("xyz"); folder != "Custom" {
+		t.Errorf("Lookup(xyz) = %q, want %q", folder, "Custom")
+	}
+	if folder := s2.Lookup("mp3"); folder != "Musicas" {
+		t.Errorf("Lookup(mp3) = %q, want the defaults the file was created with", folder)
+	}
+}
+
+func TestNewStore_Logs(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	file := filepath.Join(dir, "rules.ini")
+	if err := os.WriteFile(file, []byte("[Music\nmp3 =\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	s, err := store.NewStore("en", store.WithConfigFile(file), store.WithLogger(logger))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.MoveRule("mp3", "Audio", false); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{
+		`level=WARN msg="cannot read rules file" file=` + file,
+		`level=INFO msg="created default rules" language=en`,
+		`level=DEBUG msg="changed rule" extension=mp3 from=Music to=Audio`,
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("log lacks %q:\n%s", want, buf.String())
+		}
+	}
+}
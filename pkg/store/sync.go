@@ -0,0 +1,105 @@
+package store
+
+import "strconv"
+
+// defaultsVersionSetting is the repository setting recording the
+// DefaultsVersion the rules of DefaultProfile were last synced with. Other
+// profiles append ".name".
+const defaultsVersionSetting = "defaults_version"
+
+// Move is an extension a profile maps to another folder than the defaults.
+type Move struct {
+	Extension string `json:"extension"`
+	Default   string `json:"default"`
+	Current   string `json:"current"`
+}
+
+// DefaultsDiff describes how the rules of a profile differ from the
+// built-in rules.
+type DefaultsDiff struct {
+	// Added are rules for extensions the defaults have no rule for.
+	Added []Rule `json:"added"`
+	// Removed are default rules for extensions the profile has no rule for.
+	Removed []Rule `json:"removed"`
+	// Moved are extensions mapped to another folder than the default one.
+	Moved []Move `json:"moved"`
+	// Unchanged counts the default rules the profile has as they are.
+	Unchanged int `json:"unchanged"`
+}
+
+// DiffDefaults compares the rules of the current profile, including the
+// ones it inherits, with the default rules of lang.
+func (s *Store) DiffDefaults(lang string) *DefaultsDiff {
+	diff := &DefaultsDiff{}
+
+	defaults := make(map[string]bool)
+	for _, r := range s.defaultRules(lang) {
+		defaults[r.Extension] = true
+		switch current := s.Lookup(r.Extension); current {
+		case "":
+			diff.Removed = append(diff.Removed, r)
+		case r.Folder:
+			diff.Unchanged++
+		default:
+			diff.Moved = append(diff.Moved, Move{Extension: r.Extension, Default: r.Folder, Current: current})
+		}
+	}
+
+	for _, r := range s.Rules() {
+		if !defaults[r.Extension] {
+			diff.Added = append(diff.Added, r)
+		}
+	}
+	return diff
+}
+
+// DefaultsVersion returns the DefaultsVersion the current profile was last
+// synced with. Rules files that predate the marker are at version 1.
+func (s *Store) DefaultsVersion() int {
+	v, err := strconv.Atoi(s.repo.Setting(defaultsVersionKey(s.profile)))
+	if err != nil || v < 1 {
+		return 1
+	}
+	return v
+}
+
+// SyncDefaults adds the default rules of lang introduced since the
+// profile's DefaultsVersion, unless the profile already has a rule for the
+// extension, and then marks the profile as synced. Default rules the user
+// moved or deleted stay that way.
+func (s *Store) SyncDefaults(lang string, preview bool) ([]Change, error) {
+	since := s.DefaultsVersion()
+
+	var changes []Change
+	for _, r := range s.defaultRules(lang) {
+		if defaultsSince[r.Extension] <= since || s.Lookup(r.Extension) != "" {
+			continue
+		}
+		changes = append(changes, Change{Extension: r.Extension, To: r.Folder})
+	}
+
+	if preview {
+		return changes, nil
+	}
+	for _, c := range changes {
+		if err := s.set(c.Extension, c.To); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.setDefaultsVersion(DefaultsVersion); err != nil {
+		return nil, err
+	}
+	s.logChanges(changes)
+	return changes, nil
+}
+
+func (s *Store) setDefaultsVersion(v int) error {
+	return s.repo.SetSetting(defaultsVersionKey(s.profile), strconv.Itoa(v))
+}
+
+func defaultsVersionKey(profile string) string {
+	if profile == DefaultProfile {
+		return defaultsVersionSetting
+	}
+	return defaultsVersionSetting + "." + profile
+}
@@ -0,0 +1,128 @@
+package store_test
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/d6o/Gorganizer/pkg/store"
+)
+
+// legacyRules is a customized rules file written before default rules were
+// versioned: mp3 moved to Audio, mov mapped by the user, xyz added and
+// every other default deleted.
+const legacyRules = `[Music]
+ogg =
+
+[Audio]
+mp3 =
+
+[Movies]
+mov =
+
+[Custom]
+xyz =
+`
+
+func newLegacyStore(t *testing.T) *store.Store {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), "rules.ini")
+	if err := os.WriteFile(file, []byte(legacyRules), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	s, err := store.NewStore("en", store.WithConfigFile(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestDiffDefaults(t *testing.T) {
+	t.Parallel()
+	s := newLegacyStore(t)
+
+	diff := s.DiffDefaults("en")
+
+	if diff.Unchanged != 1 {
+		t.Errorf("Unchanged = %d, want 1 (ogg)", diff.Unchanged)
+	}
+	wantAdded := []store.Rule{{Extension: "xyz", Folder: "Custom"}}
+	if !slices.Equal(diff.Added, wantAdded) {
+		t.Errorf("Added = %+v, want %+v", diff.Added, wantAdded)
+	}
+	wantMoved := []store.Move{
+		{Extension: "mp3", Default: "Music", Current: "Audio"},
+		{Extension: "mov", Default: "Videos", Current: "Movies"},
+	}
+	if !slices.Equal(diff.Moved, wantMoved) {
+		t.Errorf("Moved = %+v, want %+v", diff.Moved, wantMoved)
+	}
+	if !slices.Contains(diff.Removed, store.Rule{Extension: "wav", Folder: "Music"}) {
+		t.Errorf("Removed = %+v, want wav among them", diff.Removed)
+	}
+
+	fresh := newTestStore(t, "en")
+	if d := fresh.DiffDefaults("en"); len(d.Added)+len(d.Removed)+len(d.Moved) != 0 {
+		t.Errorf("fresh store differs from the defaults: %+v", d)
+	}
+}
+
+func TestSyncDefaults(t *testing.T) {
+	t.Parallel()
+	s := newLegacyStore(t)
+
+	if got := s.DefaultsVersion(); got != 1 {
+		t.Errorf("DefaultsVersion() of a legacy file = %d, want 1", got)
+	}
+
+	preview, err := s.SyncDefaults("en", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.DefaultsVersion() != 1 || s.Lookup("opus") != "" {
+		t.Error("preview changed the store")
+	}
+
+	changes, err := s.SyncDefaults("en", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []store.Change{
+		{Extension: "opus", To: "Music"},
+		{Extension: "avif", To: "Pictures"},
+		{Extension: "azw3", To: "Books"},
+	}
+	if !slices.Equal(changes, want) || !slices.Equal(preview, want) {
+		t.Errorf("changes = %+v, preview = %+v, want %+v", changes, preview, want)
+	}
+
+	// User changes survive: deleted defaults stay deleted, moved ones moved.
+	for ext, folder := range map[string]string{"wav": "", "mp3": "Audio", "mov": "Movies", "opus": "Music"} {
+		if got := s.Lookup(ext); got != folder {
+			t.Errorf("Lookup(%s) after sync = %q, want %q", ext, got, folder)
+		}
+	}
+
+	if got := s.DefaultsVersion(); got != store.DefaultsVersion {
+		t.Errorf("DefaultsVersion() after sync = %d, want %d", got, store.DefaultsVersion)
+	}
+	if changes, _ := s.SyncDefaults("en", false); len(changes) != 0 {
+		t.Errorf("second sync changed %+v", changes)
+	}
+}
+
+func TestSyncDefaults_NewStoreIsCurrent(t *testing.T) {
+	t.Parallel()
+	s := newTestStore(t, "en")
+
+	if got := s.DefaultsVersion(); got != store.DefaultsVersion {
+		t.Errorf("DefaultsVersion() = %d, want %d", got, store.DefaultsVersion)
+	}
+	if err := s.DeleteRule("opus"); err != nil {
+		t.Fatal(err)
+	}
+	if changes, _ := s.SyncDefaults("en", false); len(changes) != 0 {
+		t.Errorf("sync re-added deleted defaults: %+v", changes)
+	}
+}
@@ -0,0 +1,293 @@
+package store
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/ini.v1"
+	"gopkg.in/yaml.v3"
+)
+
+// Format is a file format rules can be exported to and imported from.
+type Format int
+
+const (
+	// FormatJSON is a JSON array of {"extension": ..., "folder": ...}.
+	FormatJSON Format = iota
+	// FormatYAML is a YAML list of extension/folder mappings.
+	FormatYAML
+	// FormatCSV has an "extension,folder" header and one rule per row.
+	FormatCSV
+	// FormatINI is the store's own format: one section per folder listing
+	// its extensions.
+	FormatINI
+)
+
+var formatNames = map[Format]string{
+	FormatJSON: "json",
+	FormatYAML: "yaml",
+	FormatCSV:  "csv",
+	FormatINI:  "ini",
+}
+
+// String returns the name of the format.
+func (f Format) String() string {
+	return formatNames[f]
+}
+
+// ParseFormat returns the format with the given name: json, yaml (or yml),
+// csv or ini.
+func ParseFormat(name string) (Format, error) {
+	name = strings.ToLower(name)
+	if name == "yml" {
+		name = "yaml"
+	}
+	for f, n := range formatNames {
+		if n == name {
+			return f, nil
+		}
+	}
+	return 0, fmt.Errorf("%w: %q", ErrUnknownFormat, name)
+}
+
+// FormatFromPath returns the format matching the extension of path.
+func FormatFromPath(path string) (Format, error) {
+	return ParseFormat(strings.TrimPrefix(filepath.Ext(path), "."))
+}
+
+// ExportRules writes rules to w in the given format.
+func ExportRules(w io.Writer, rules []Rule, format Format) error {
+	if rules == nil {
+		rules = []Rule{}
+	}
+
+	switch format {
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(rules)
+
+	case FormatYAML:
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(rules); err != nil {
+			return err
+		}
+		return enc.Close()
+
+	case FormatCSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write([]string{"extension", "folder"}); err != nil {
+			return err
+		}
+		for _, r := range rules {
+			if err := cw.Write([]string{r.Extension, r.Folder}); err != nil {
+				return err
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+
+	case FormatINI:
+		cfg := ini.Empty()
+		for _, r := range rules {
+			if _, err := cfg.Section(r.Folder).NewKey(r.Extension, ""); err != nil {
+				return err
+			}
+		}
+		_, err := cfg.WriteTo(w)
+		return err
+	}
+
+	return fmt.Errorf("%w: %d", ErrUnknownFormat, int(format))
+}
+
+// ReadRules decodes rules written by ExportRules. For INI, only the rules of
+// the default profile are read.
+func ReadRules(r io.Reader, format Format) ([]Rule, error) {
+	var rules []Rule
+
+	switch format {
+	case FormatJSON:
+		if err := json.NewDecoder(r).Decode(&rules); err != nil {
+			return nil, err
+		}
+
+	case FormatYAML:
+		if err := yaml.NewDecoder(r).Decode(&rules); err != nil && err != io.EOF {
+			return nil, err
+		}
+
+	case FormatCSV:
+		records, err := csv.NewReader(r).ReadAll()
+		if err != nil {
+			return nil, err
+		}
+		for i, rec := range records {
+			if len(rec) != 2 {
+				return nil, fmt.Errorf("%w: line %d", ErrInvalidRuleFormat, i+1)
+			}
+			if i == 0 && strings.EqualFold(rec[0], "extension") {
+				continue
+			}
+			rules = append(rules, Rule{Extension: rec[0], Folder: rec[1]})
+		}
+
+	case FormatINI:
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+		cfg, err := ini.LoadSources(ini.LoadOptions{AllowBooleanKeys: true}, data)
+		if err != nil {
+			return nil, err
+		}
+		rules = (&INIRepository{cfg: cfg}).Rules(DefaultProfile)
+
+	default:
+		return nil, fmt.Errorf("%w: %d", ErrUnknownFormat, int(format))
+	}
+
+	for i, rule := range rules {
+		if rule.Extension == "" || rule.Folder == "" {
+			return nil, fmt.Errorf("%w: rule %d", ErrEmptyRuleComponent, i+1)
+		}
+		if strings.Contains(rule.Extension, ":") {
+			return nil, fmt.Errorf("%w: rule %d", ErrInvalidRuleFormat, i+1)
+		}
+		if err := CheckFolder(rule.Folder); err != nil {
+			return nil, fmt.Errorf("rule %d: %w", i+1, err)
+		}
+	}
+	return rules, nil
+}
+
+// ImportMode decides how Import combines incoming rules with the store's.
+type ImportMode int
+
+const (
+	// ImportMerge adds incoming rules for new extensions and keeps the
+	// current folder of extensions that conflict.
+	ImportMerge ImportMode = iota
+	// ImportReplace makes the profile's own rules exactly the incoming
+	// ones. Rules inherited from another profile are overridden but not
+	// removed.
+	ImportReplace
+	// ImportDryRun changes nothing and only reports the differences.
+	ImportDryRun
+)
+
+// ParseImportMode returns the mode with the given name: merge, replace or
+// dry-run.
+func ParseImportMode(name string) (ImportMode, error) {
+	switch name {
+	case "", "merge":
+		return ImportMerge, nil
+	case "replace":
+		return ImportReplace, nil
+	case "dry-run":
+		return ImportDryRun, nil
+	}
+	return 0, fmt.Errorf("%w: %q", ErrUnknownImportMode, name)
+}
+
+// Conflict is an incoming rule for an extension the store already maps to
+// another folder.
+type Conflict struct {
+	Extension string `json:"extension"`
+	Current   string `json:"current"`
+	Incoming  string `json:"incoming"`
+}
+
+// ImportReport describes how incoming rules differ from the store's.
+type ImportReport struct {
+	// Added are incoming rules for extensions the store has no rule for.
+	Added []Rule `json:"added"`
+	// Conflicts are incoming rules that map an extension elsewhere.
+	Conflicts []Conflict `json:"conflicts"`
+	// Missing are the current profile's own rules for extensions not in
+	// the import. Replace removes them.
+	Missing []Rule `json:"missing"`
+	// Inherited are rules from the profiles the current one extends for
+	// extensions not in the import. Replace keeps them, since they belong
+	// to another profile.
+	Inherited []Rule `json:"inherited,omitempty"`
+	// Unchanged counts incoming rules the store already has.
+	Unchanged int `json:"unchanged"`
+}
+
+// Import compares rules with the current profile and applies them according
+// to mode. Folder names are normalized the same way InsertRule does before
+// they are compared.
+func (s *Store) Import(rules []Rule, mode ImportMode) (*ImportReport, error) {
+	report := &ImportReport{}
+
+	incoming := make(map[string]bool)
+	for _, r := range rules {
+		ext := strings.ToLower(r.Extension)
+		folder := s.title(r.Folder)
+		if incoming[ext] {
+			continue
+		}
+		incoming[ext] = true
+
+		switch current := s.Lookup(ext); current {
+		case "":
+			report.Added = append(report.Added, Rule{Extension: ext, Folder: folder})
+		case folder:
+			report.Unchanged++
+		default:
+			report.Conflicts = append(report.Conflicts, Conflict{Extension: ext, Current: current, Incoming: folder})
+		}
+	}
+
+	own := make(map[string]bool)
+	for _, r := range s.repo.Rules(s.profile) {
+		own[r.Extension] = true
+	}
+	for _, r := range s.Rules() {
+		switch {
+		case incoming[r.Extension]:
+		case own[r.Extension]:
+			report.Missing = append(report.Missing, r)
+		default:
+			report.Inherited = append(report.Inherited, r)
+		}
+	}
+
+	switch mode {
+	case ImportMerge:
+		for _, r := range report.Added {
+			if err := s.set(r.Extension, r.Folder); err != nil {
+				return nil, err
+			}
+		}
+
+	case ImportReplace:
+		for _, r := range report.Missing {
+			s.remove(r.Extension)
+		}
+		for _, c := range report.Conflicts {
+			if err := s.set(c.Extension, c.Incoming); err != nil {
+				return nil, err
+			}
+		}
+		for _, r := range report.Added {
+			if err := s.set(r.Extension, r.Folder); err != nil {
+				return nil, err
+			}
+		}
+
+	case ImportDryRun:
+		// Report only.
+
+	default:
+		return nil, fmt.Errorf("%w: %d", ErrUnknownImportMode, int(mode))
+	}
+
+	return report, nil
+}
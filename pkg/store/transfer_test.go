@@ -0,0 +1,190 @@
+package store_test
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/d6o/Gorganizer/pkg/store"
+)
+
+func TestExportReadRules_RoundTrip(t *testing.T) {
+	t.Parallel()
+
+	rules := []store.Rule{
+		{Extension: "mp3", Folder: "Music"},
+		{Extension: "flac", Folder: "Music"},
+		{Extension: "pdf", Folder: "Documents, Misc"},
+	}
+
+	for _, format := range []store.Format{store.FormatJSON, store.FormatYAML, store.FormatCSV, store.FormatINI} {
+		t.Run(format.String(), func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+			if err := store.ExportRules(&buf, rules, format); err != nil {
+				t.Fatal(err)
+			}
+			got, err := store.ReadRules(&buf, format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, rules) {
+				t.Errorf("round trip = %+v, want %+v", got, rules)
+			}
+		})
+	}
+}
+
+func TestReadRules_Invalid(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		input  string
+		format store.Format
+		want   error
+	}{
+		{"empty folder", `[{"extension": "mp3", "folder": ""}]`, store.FormatJSON, store.ErrEmptyRuleComponent},
+		{"colon", "- extension: mp3\n  folder: a:b\n", store.FormatYAML, store.ErrInvalidRuleFormat},
+		{"user home", "extension,folder\nmp3,~bob/Music\n", store.FormatCSV, store.ErrInvalidDestination},
+		{"csv columns", "extension,folder\nmp3\n", store.FormatCSV, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			_, err := store.ReadRules(strings.NewReader(tt.input), tt.format)
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if tt.want != nil && !errors.Is(err, tt.want) {
+				t.Errorf("error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	t.Parallel()
+
+	if f, err := store.FormatFromPath("team/rules.yml"); err != nil || f != store.FormatYAML {
+		t.Errorf("FormatFromPath(rules.yml) = %v, %v", f, err)
+	}
+	if _, err := store.ParseFormat("xml"); !errors.Is(err, store.ErrUnknownFormat) {
+		t.Errorf("ParseFormat(xml) error = %v", err)
+	}
+	if _, err := store.ParseImportMode("overwrite"); !errors.Is(err, store.ErrUnknownImportMode) {
+		t.Errorf("ParseImportMode(overwrite) error = %v", err)
+	}
+}
+
+func importRules() []store.Rule {
+	return []store.Rule{
+		{Extension: "MP3", Folder: "music"},
+		{Extension: "pdf", Folder: "papers"},
+		{Extension: "heic", Folder: "photos"},
+	}
+}
+
+func TestImport_Report(t *testing.T) {
+	t.Parallel()
+	s := newTestStore(t, "en")
+	before := len(s.Rules())
+
+	report, err := s.Import(importRules(), store.ImportDryRun)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if report.Unchanged != 1 {
+		t.Errorf("Unchanged = %d, want 1 (mp3 after normalization)", report.Unchanged)
+	}
+	if want := []store.Rule{{Extension: "heic", Folder: "Photos"}}; !reflect.DeepEqual(report.Added, want) {
+		t.Errorf("Added = %+v, want %+v", report.Added, want)
+	}
+	if want := []store.Conflict{{Extension: "pdf", Current: "Documents", Incoming: "Papers"}}; !reflect.DeepEqual(report.Conflicts, want) {
+		t.Errorf("Conflicts = %+v, want %+v", report.Conflicts, want)
+	}
+	if len(report.Missing) != before-2 {
+		t.Errorf("Missing = %d rules, want %d", len(report.Missing), before-2)
+	}
+
+	if len(s.Rules()) != before || s.Lookup("heic") != "" {
+		t.Error("dry run must not change the store")
+	}
+}
+
+func TestImport_Merge(t *testing.T) {
+	t.Parallel()
+	s := newTestStore(t, "en")
+	before := len(s.Rules())
+
+	if _, err := s.Import(importRules(), store.ImportMerge); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := s.Lookup("heic"); got != "Photos" {
+		t.Errorf("Lookup(heic) = %q, want Photos", got)
+	}
+	if got := s.Lookup("pdf"); got != "Documents" {
+		t.Errorf("Lookup(pdf) = %q, merge must keep the current folder", got)
+	}
+	if got := len(s.Rules()); got != before+1 {
+		t.Errorf("rules = %d, want %d", got, before+1)
+	}
+}
+
+func TestImport_Replace(t *testing.T) {
+	t.Parallel()
+	s := newTestStore(t, "en")
+
+	if _, err := s.Import(importRules(), store.ImportReplace); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{"mp3": "Music", "pdf": "Papers", "heic": "Photos"}
+	rules := s.Rules()
+	if len(rules) != len(want) {
+		t.Fatalf("rules = %+v, want exactly the imported ones", rules)
+	}
+	for _, r := range rules {
+		if want[r.Extension] != r.Folder {
+			t.Errorf("rule %s -> %s, want %s", r.Extension, r.Folder, want[r.Extension])
+		}
+	}
+}
+
+func TestImport_ReplaceKeepsInherited(t *testing.T) {
+	t.Parallel()
+	s := newTestStore(t, "en")
+	if err := s.CreateProfile("photos", store.DefaultProfile); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.UseProfile("photos"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.InsertRule("jpg,raw:Camera"); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := s.Import([]store.Rule{{Extension: "jpg", Folder: "Camera"}}, store.ImportReplace)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []store.Rule{{Extension: "raw", Folder: "Camera"}}; !reflect.DeepEqual(report.Missing, want) {
+		t.Errorf("Missing = %+v, want only the profile's own rules %+v", report.Missing, want)
+	}
+	if !slices.Contains(report.Inherited, store.Rule{Extension: "mp3", Folder: "Music"}) {
+		t.Errorf("Inherited = %+v, want the default profile's rules", report.Inherited)
+	}
+	if got := s.Lookup("raw"); got != "" {
+		t.Errorf("Lookup(raw) = %q, want the rule removed", got)
+	}
+	if got := s.Lookup("mp3"); got != "Music" {
+		t.Errorf("Lookup(mp3) = %q, want the inherited rule kept", got)
+	}
+}
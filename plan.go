@@ -0,0 +1,136 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/disiqueira/gotree"
+
+	"github.com/d6o/Gorganizer/pkg/organizer"
+)
+
+func writePlan(org *organizer.Organizer, file string) (err error) {
+	plan, err := org.Plan()
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := f.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}()
+
+	if err := organizer.WritePlan(f, plan); err != nil {
+		return err
+	}
+
+	printPlanTree(plan)
+	say(fmt.Sprintf("Plan with %d operations written to %s", len(plan.Entries), file))
+	return nil
+}
+
+func runApply(args []string) error {
+	flags := flag.NewFlagSet("apply", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: %s apply [options] plan.json\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	strict := flags.Bool("strict", false, "Refuse to apply the plan if any file changed since it was made")
+	permanent := flags.Bool("permanent", false, "Delete files permanently instead of moving them to the trash")
+	var audit auditFlags
+	audit.register(flags)
+	var hooks hookFlags
+	hooks.register(flags)
+	var logs logFlags
+	logs.register(flags)
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if err := logs.setup(); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return errPlanFileRequired
+	}
+
+	plan, err := readPlan(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	if stale := plan.Stale(); len(stale) > 0 && *strict {
+		for _, e := range stale {
+			logger.Error("changed since planned", "path", e.Source)
+		}
+		return errStalePlan
+	}
+
+	auditLog, err := audit.open()
+	if err != nil {
+		return err
+	}
+	if auditLog != nil {
+		defer logClose("audit log", auditLog)
+	}
+
+	org := organizer.NewOrganizer(nil, organizer.Config{
+		InputFolder:  plan.Input,
+		OutputFolder: plan.Output,
+		Permanent:    *permanent,
+		Audit:        auditLog,
+		Hooks:        hooks.list(),
+		Logger:       logger,
+	})
+
+	say("GOrganizing your Files")
+
+	result, err := org.Apply(plan)
+	if err != nil {
+		return err
+	}
+
+	printResultTree(result)
+	if auditLog != nil {
+		say("Run", result.RunID, "recorded in", audit.file)
+	}
+
+	say("All files have been GOrganized!")
+	return nil
+}
+
+func readPlan(file string) (plan *organizer.Plan, err error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if cerr := f.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}()
+
+	return organizer.ReadPlan(f)
+}
+
+func printPlanTree(plan *organizer.Plan) {
+	tree := gotree.New("Plan")
+
+	for _, e := range plan.Entries {
+		label := string(e.Op)
+		if e.Target != "" {
+			label += " to " + filepath.Dir(e.Target)
+		}
+		addToTree(tree, label, e.Source)
+	}
+
+	fmt.Println(tree.Print())
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/d6o/Gorganizer/internal/fsutil"
+	"github.com/d6o/Gorganizer/pkg/organizer"
+	"github.com/d6o/Gorganizer/pkg/store"
+)
+
+// preset is a named set of settings an input directory can be bound to
+// with -directory path=name. Unset fields fall back to the command line
+// flags. Unlike rule profiles, which live in the rules database, presets
+// live in their own file and describe inputs.
+type preset struct {
+	Output   string `json:"output"`
+	Language string `json:"language"`
+	Rules    string `json:"rules"`
+	// RuleProfile selects a named profile within the rules database.
+	RuleProfile string   `json:"rule_profile"`
+	Recursive   *bool    `json:"recursive"`
+	Hidden      *bool    `json:"hidden"`
+	Exclude     []string `json:"exclude"`
+}
+
+// inputSpec is one -directory flag.
+type inputSpec struct {
+	dir    string
+	preset string
+}
+
+// inputsFlag collects repeated -directory flags, each a directory optionally
+// followed by =preset. An = within the directory is written ==.
+type inputsFlag []inputSpec
+
+func (f *inputsFlag) String() string {
+	if f == nil {
+		return ""
+	}
+	var specs []string
+	for _, in := range *f {
+		spec := strings.ReplaceAll(in.dir, "=", "==")
+		if in.preset != "" {
+			spec += "=" + in.preset
+		}
+		specs = append(specs, spec)
+	}
+	return strings.Join(specs, ",")
+}
+
+func (f *inputsFlag) Set(value string) error {
+	var in inputSpec
+	in.dir, in.preset = splitInput(value)
+	if in.dir == "" {
+		return errEmptyDirectory
+	}
+	*f = append(*f, in)
+	return nil
+}
+
+// splitInput splits a -directory value at its first single =, turning
+// every == before it into =, so that dir==1=scans is the directory dir=1
+// bound to the preset scans.
+func splitInput(value string) (dir, preset string) {
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] != '=' {
+			b.WriteByte(value[i])
+			continue
+		}
+		if i+1 < len(value) && value[i+1] == '=' {
+			b.WriteByte('=')
+			i++
+			continue
+		}
+		return b.String(), value[i+1:]
+	}
+	return b.String(), ""
+}
+
+func defaultPresetsFile() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ".gorganizer-presets.json"
+	}
+	return filepath.Join(home, ".gorganizer-presets.json")
+}
+
+// readPresets reads the presets file. A missing file holds no presets.
+func readPresets(path string) (map[string]preset, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return map[string]preset{}, nil
+		}
+		return nil, err
+	}
+
+	var presets map[string]preset
+	if err := json.Unmarshal(data, &presets); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return presets, nil
+}
+
+// inputDefaults are the command line settings inputs fall back to.
+type inputDefaults struct {
+	output      string
+	language    string
+	ruleProfile string
+	recursive   bool
+	hidden      bool
+	exclude     organizer.ExcludeList
+}
+
+// buildInputs resolves each input's preset into organizer settings. Inputs
+// whose preset selects other rules get their own store, which the caller
+// must close.
+func buildInputs(specs []inputSpec, presets map[string]preset, def inputDefaults) ([]organizer.Input, []*store.Store, error) {
+	var inputs []organizer.Input
+	var stores []*store.Store
+
+	for _, spec := range specs {
+		in := organizer.Input{
+			Folder:            spec.dir,
+			OutputFolder:      def.output,
+			Recursive:         def.recursive,
+			IgnoreHiddenFiles: def.hidden,
+			ExcludeList:       def.exclude,
+		}
+
+		if spec.preset != "" {
+			p, ok := presets[spec.preset]
+			if !ok {
+				return nil, stores, fmt.Errorf("%w: %q", errUnknownPreset, spec.preset)
+			}
+
+			if p.Output != "" {
+				output, err := fsutil.ExpandHome(p.Output)
+				if err != nil {
+					return nil, stores, err
+				}
+				in.OutputFolder = output
+			}
+			if p.Recursive != nil {
+				in.Recursive = *p.Recursive
+			}
+			if p.Hidden != nil {
+				in.IgnoreHiddenFiles = *p.Hidden
+			}
+			if p.Exclude != nil {
+				in.ExcludeList = organizer.ExcludeList(p.Exclude)
+			}
+
+			if p.Rules != "" || (p.Language != "" && p.Language != def.language) ||
+				(p.RuleProfile != "" && p.RuleProfile != def.ruleProfile) {
+				lang := p.Language
+				if lang == "" {
+					lang = def.language
+				}
+				ruleProfile := p.RuleProfile
+				if ruleProfile == "" && p.Rules == "" {
+					ruleProfile = def.ruleProfile
+				}
+				opts := []store.Option{store.WithProfile(ruleProfile), store.WithLogger(logger)}
+				if p.Rules != "" {
+					rules, err := fsutil.ExpandHome(p.Rules)
+					if err != nil {
+						return nil, stores, err
+					}
+					opts = append(opts, store.WithConfigFile(rules))
+				}
+				s, err := store.NewStore(lang, opts...)
+				if err != nil {
+					return nil, stores, err
+				}
+				stores = append(stores, s)
+				in.Resolver = s
+			}
+		}
+
+		inputs = append(inputs, in)
+	}
+
+	return inputs, stores, nil
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/disiqueira/gotree"
+
+	"github.com/d6o/Gorganizer/pkg/store"
+)
+
+func runProfile(args []string) (err error) {
+	flags := flag.NewFlagSet("profile", flag.ExitOnError)
+	flags.Usage = func() {
+		out := flags.Output()
+		fmt.Fprintf(out, "Usage: %s profile [options] list\n", os.Args[0])
+		fmt.Fprintf(out, "       %s profile [options] create [-extends parent] name\n", os.Args[0])
+		fmt.Fprintf(out, "       %s profile [options] clone source name\n", os.Args[0])
+		fmt.Fprintf(out, "       %s profile [options] delete name\n", os.Args[0])
+		fmt.Fprintf(out, "       %s profile [options] default name\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	lang := flags.String("language", "en", "Specify language: en|tr|pt")
+	extends := flags.String("extends", "", "Profile the new profile inherits rules from")
+	var logs logFlags
+	logs.register(flags)
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	// Allow options after the action, as in "create -extends default photos".
+	action := flags.Arg(0)
+	if err := flags.Parse(flags.Args()[min(1, flags.NArg()):]); err != nil {
+		return err
+	}
+	operands := flags.Args()
+	if err := logs.setup(); err != nil {
+		return err
+	}
+
+	want := map[string]int{"list": 0, "create": 1, "clone": 2, "delete": 1, "default": 1}
+	if n, ok := want[action]; !ok || len(operands) != n {
+		flags.Usage()
+		return errProfileUsage
+	}
+
+	s, err := openStore(*lang, store.DefaultProfile)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := s.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}()
+
+	switch action {
+	case "create":
+		err = s.CreateProfile(operands[0], *extends)
+	case "clone":
+		err = s.CloneProfile(operands[0], operands[1])
+	case "delete":
+		err = s.DeleteProfile(operands[0])
+	case "default":
+		err = s.SetDefaultProfile(operands[0])
+	}
+	if err != nil {
+		return err
+	}
+
+	printProfilesTree(s)
+	return nil
+}
+
+func printProfilesTree(s *store.Store) {
+	tree := gotree.New("Profiles")
+
+	for _, p := range s.Profiles() {
+		label := p.Name
+		if p.Extends != "" {
+			label += " (extends " + p.Extends + ")"
+		}
+		if p.Default {
+			label += " *"
+		}
+		tree.Add(label)
+	}
+
+	fmt.Println(tree.Print())
+}
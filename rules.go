@@ -0,0 +1,337 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/disiqueira/gotree"
+
+	"github.com/d6o/Gorganizer/pkg/organizer"
+	"github.com/d6o/Gorganizer/pkg/store"
+)
+
+// rulesArgs is the number of arguments each rules command takes, or -1 for
+// an optional file.
+var rulesArgs = map[string]int{
+	"export":        -1,
+	"import":        -1,
+	"add":           1,
+	"delete":        1,
+	"move":          2,
+	"rename":        2,
+	"remove-folder": 1,
+	"reset":         0,
+	"diff-defaults": 0,
+	"sync-defaults": 0,
+}
+
+func runRules(args []string) (err error) {
+	flags := flag.NewFlagSet("rules", flag.ExitOnError)
+	flags.Usage = func() {
+		out := flags.Output()
+		fmt.Fprintf(out, "Usage: %s rules export [options] [file]\n", os.Args[0])
+		fmt.Fprintf(out, "       %s rules import [options] [file]\n", os.Args[0])
+		fmt.Fprintf(out, "       %s rules add [options] ext[,ext...]:folder\n", os.Args[0])
+		fmt.Fprintf(out, "       %s rules delete [options] ext\n", os.Args[0])
+		fmt.Fprintf(out, "       %s rules move [options] ext folder\n", os.Args[0])
+		fmt.Fprintf(out, "       %s rules rename [options] folder new-name\n", os.Args[0])
+		fmt.Fprintf(out, "       %s rules remove-folder [options] folder\n", os.Args[0])
+		fmt.Fprintf(out, "       %s rules reset [options]\n", os.Args[0])
+		fmt.Fprintf(out, "       %s rules diff-defaults [options]\n", os.Args[0])
+		fmt.Fprintf(out, "       %s rules sync-defaults [options]\n", os.Args[0])
+		fmt.Fprintln(out, "Without a file, rules are written to stdout or read from stdin.")
+		flags.PrintDefaults()
+	}
+	lang := flags.String("language", "en", "Specify language: en|tr|pt")
+	profile := flags.String("profile", "", "Rule profile to use instead of the default one")
+	format := flags.String("format", "", "File format: json|yaml|csv|ini (default from the file extension, else json)")
+	mode := flags.String("mode", "merge", "How to import: merge|replace|dry-run")
+	preview := flags.Bool("preview", false, "Only show what an edit would change")
+	dir := flags.String("dir", "", "With rename, also rename the folder inside this output directory")
+	var logs logFlags
+	logs.register(flags)
+
+	if len(args) == 0 {
+		flags.Usage()
+		return errRulesUsage
+	}
+	action := args[0]
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+	if err := logs.setup(); err != nil {
+		return err
+	}
+	n, ok := rulesArgs[action]
+	if !ok || (n < 0 && flags.NArg() > 1) || (n >= 0 && flags.NArg() != n) {
+		flags.Usage()
+		return errRulesUsage
+	}
+
+	var f store.Format
+	if action == "export" || action == "import" {
+		if f, err = rulesFormat(*format, flags.Arg(0)); err != nil {
+			return err
+		}
+	}
+
+	s, err := openStore(*lang, *profile)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := s.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}()
+
+	var changes []store.Change
+	switch action {
+	case "export":
+		return exportRules(s, flags.Arg(0), f)
+
+	case "import":
+		m, err := store.ParseImportMode(*mode)
+		if err != nil {
+			return err
+		}
+		return importRules(s, flags.Arg(0), f, m)
+
+	case "add":
+		exts, folder, err := store.ParseRule(flags.Arg(0))
+		if err != nil {
+			return err
+		}
+		changes, err = s.AddRules(exts, folder, *preview)
+		if err != nil {
+			return err
+		}
+
+	case "delete":
+		changes, err = s.RemoveRule(flags.Arg(0), *preview)
+		if err != nil {
+			return err
+		}
+
+	case "move":
+		changes, err = s.MoveRule(flags.Arg(0), flags.Arg(1), *preview)
+		if err != nil {
+			return err
+		}
+
+	case "rename":
+		changes, err = renameFolder(s, flags.Arg(0), flags.Arg(1), *dir, *preview)
+		if err != nil {
+			return err
+		}
+
+	case "remove-folder":
+		changes, err = s.RemoveFolder(flags.Arg(0), *preview)
+		if err != nil {
+			return err
+		}
+
+	case "reset":
+		changes, err = s.ResetDefaults(*lang, *preview)
+		if err != nil {
+			return err
+		}
+
+	case "diff-defaults":
+		printDefaultsDiff(s.DiffDefaults(*lang))
+		return nil
+
+	case "sync-defaults":
+		from := s.DefaultsVersion()
+		changes, err = s.SyncDefaults(*lang, *preview)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Default rules version %d → %d\n", from, store.DefaultsVersion)
+	}
+
+	printChanges(changes, *preview)
+	return nil
+}
+
+// renameFolder renames a rules folder and, if dir is set, the folder of
+// that name inside dir. The folder on disk is renamed first and only if the
+// new name is not taken, so the rules are never left pointing elsewhere
+// than the files.
+func renameFolder(s *store.Store, from, to, dir string, preview bool) ([]store.Change, error) {
+	changes, err := s.RenameFolder(from, to, true)
+	if err != nil || len(changes) == 0 {
+		return changes, err
+	}
+
+	if dir != "" {
+		src := organizer.DestinationDir(dir, changes[0].From)
+		dst := organizer.DestinationDir(dir, changes[0].To)
+		if _, err := os.Stat(src); err == nil {
+			if _, err := os.Stat(dst); err == nil {
+				return nil, fmt.Errorf("%w: %s", errFolderExists, dst)
+			}
+			if preview {
+				fmt.Printf("Would rename %s to %s\n", src, dst)
+			} else {
+				if err := os.Rename(src, dst); err != nil {
+					return nil, err
+				}
+				fmt.Printf("Renamed %s to %s\n", src, dst)
+			}
+		}
+	}
+
+	if preview {
+		return changes, nil
+	}
+	return s.RenameFolder(from, to, false)
+}
+
+func rulesFormat(name, file string) (store.Format, error) {
+	if name != "" {
+		return store.ParseFormat(name)
+	}
+	if file == "" || file == "-" {
+		return store.FormatJSON, nil
+	}
+	return store.FormatFromPath(file)
+}
+
+func exportRules(s *store.Store, file string, format store.Format) (err error) {
+	var w io.Writer = os.Stdout
+	if file != "" && file != "-" {
+		f, err := os.Create(file)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if cerr := f.Close(); cerr != nil && err == nil {
+				err = cerr
+			}
+		}()
+		w = f
+	}
+
+	return store.ExportRules(w, s.Rules(), format)
+}
+
+func importRules(s *store.Store, file string, format store.Format, mode store.ImportMode) error {
+	var r io.Reader = os.Stdin
+	if file != "" && file != "-" {
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			_ = f.Close()
+		}()
+		r = f
+	}
+
+	rules, err := store.ReadRules(r, format)
+	if err != nil {
+		return err
+	}
+
+	report, err := s.Import(rules, mode)
+	if err != nil {
+		return err
+	}
+
+	printImportReport(report, mode)
+	return nil
+}
+
+func printChanges(changes []store.Change, preview bool) {
+	tree := gotree.New(fmt.Sprintf("Rule changes (%d)", len(changes)))
+	for _, c := range changes {
+		switch {
+		case c.From == "":
+			tree.Add(c.Extension + " → " + c.To + " (new)")
+		case c.To == "":
+			tree.Add(c.Extension + ": " + c.From + " (removed)")
+		default:
+			tree.Add(c.Extension + ": " + c.From + " → " + c.To)
+		}
+	}
+
+	fmt.Println(tree.Print())
+	if preview {
+		fmt.Println("Preview, nothing was changed")
+	}
+}
+
+func printDefaultsDiff(diff *store.DefaultsDiff) {
+	tree := gotree.New(fmt.Sprintf("Differences from the default rules (%d unchanged)", diff.Unchanged))
+
+	if len(diff.Added) > 0 {
+		added := tree.Add("Added")
+		for _, r := range diff.Added {
+			added.Add(r.Extension + " → " + r.Folder)
+		}
+	}
+	if len(diff.Removed) > 0 {
+		removed := tree.Add("Removed")
+		for _, r := range diff.Removed {
+			removed.Add(r.Extension + " → " + r.Folder)
+		}
+	}
+	if len(diff.Moved) > 0 {
+		moved := tree.Add("Moved")
+		for _, m := range diff.Moved {
+			moved.Add(m.Extension + ": " + m.Default + " → " + m.Current)
+		}
+	}
+
+	fmt.Println(tree.Print())
+}
+
+func printImportReport(report *store.ImportReport, mode store.ImportMode) {
+	tree := gotree.New(fmt.Sprintf("Import (%d rules unchanged)", report.Unchanged))
+
+	if len(report.Added) > 0 {
+		added := tree.Add("Added")
+		for _, r := range report.Added {
+			added.Add(r.Extension + " → " + r.Folder)
+		}
+	}
+
+	if len(report.Conflicts) > 0 {
+		label := "Conflicts (current folder kept)"
+		if mode == store.ImportReplace {
+			label = "Conflicts (replaced)"
+		} else if mode == store.ImportDryRun {
+			label = "Conflicts"
+		}
+		conflicts := tree.Add(label)
+		for _, c := range report.Conflicts {
+			conflicts.Add(c.Extension + ": " + c.Current + " → " + c.Incoming)
+		}
+	}
+
+	if len(report.Missing) > 0 && mode != store.ImportMerge {
+		label := "Not in import"
+		if mode == store.ImportReplace {
+			label = "Removed"
+		}
+		missing := tree.Add(label)
+		for _, r := range report.Missing {
+			missing.Add(r.Extension + " → " + r.Folder)
+		}
+	}
+
+	if len(report.Inherited) > 0 && mode != store.ImportMerge {
+		inherited := tree.Add("Not in import, inherited (kept)")
+		for _, r := range report.Inherited {
+			inherited.Add(r.Extension + " → " + r.Folder)
+		}
+	}
+
+	fmt.Println(tree.Print())
+	if mode == store.ImportDryRun {
+		fmt.Println("Dry run, nothing was changed")
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/d6o/Gorganizer/internal/server"
+)
+
+func runServe(args []string) error {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := flags.String("addr", "127.0.0.1:7890", "Loopback address to listen on")
+	socket := flags.String("socket", "", "Listen on a Unix socket instead of a TCP address")
+	token := flags.String("token", os.Getenv("GORGANIZER_TOKEN"), "Token clients must present (default $GORGANIZER_TOKEN, or a random one)")
+	lang := flags.String("language", "en", "Specify language: en|tr|pt")
+	profile := flags.String("profile", "", "Rule profile to serve instead of the default one")
+	var logs logFlags
+	logs.register(flags)
+	var audit auditFlags
+	audit.register(flags)
+	var hooks hookFlags
+	hooks.register(flags)
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if err := logs.setup(); err != nil {
+		return err
+	}
+
+	if *token == "" {
+		t, err := server.NewToken()
+		if err != nil {
+			return err
+		}
+		*token = t
+		fmt.Println("Token:", t)
+	}
+
+	s, err := openStore(*lang, *profile)
+	if err != nil {
+		return err
+	}
+	defer logClose("rules", s)
+
+	auditLog, err := audit.open()
+	if err != nil {
+		return err
+	}
+	if auditLog != nil {
+		defer logClose("audit log", auditLog)
+	}
+
+	l, err := server.Listen(*addr, *socket)
+	if err != nil {
+		return err
+	}
+
+	api := server.New(s, *token, server.WithAudit(auditLog), server.WithHooks(hooks.list()), server.WithLogger(logger))
+	srv := &http.Server{
+		Handler:           api,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// The rules and the audit log are closed when serve returns, so it first
+	// waits for the runs that requests started.
+	stopped := make(chan error, 1)
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(shutdown)
+		if err := api.Wait(shutdown); err != nil {
+			stopped <- fmt.Errorf("waiting for organize runs: %w", err)
+			return
+		}
+		stopped <- nil
+	}()
+
+	fmt.Println("Listening on", l.Addr())
+	if err := srv.Serve(l); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return <-stopped
+}